@@ -11,6 +11,7 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	_ "google.golang.org/protobuf/types/gofeaturespb"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	unsafe "unsafe"
@@ -23,21 +24,149 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Change to the backend agents of the mux agent
+type BackendAction int32
+
+const (
+	BackendAction_BACKEND_ACTION_UNSPECIFIED BackendAction = 0
+	BackendAction_BACKEND_ACTION_LIST        BackendAction = 1
+	BackendAction_BACKEND_ACTION_ADD         BackendAction = 2
+	BackendAction_BACKEND_ACTION_REMOVE      BackendAction = 3
+)
+
+// Enum value maps for BackendAction.
+var (
+	BackendAction_name = map[int32]string{
+		0: "BACKEND_ACTION_UNSPECIFIED",
+		1: "BACKEND_ACTION_LIST",
+		2: "BACKEND_ACTION_ADD",
+		3: "BACKEND_ACTION_REMOVE",
+	}
+	BackendAction_value = map[string]int32{
+		"BACKEND_ACTION_UNSPECIFIED": 0,
+		"BACKEND_ACTION_LIST":        1,
+		"BACKEND_ACTION_ADD":         2,
+		"BACKEND_ACTION_REMOVE":      3,
+	}
+)
+
+func (x BackendAction) Enum() *BackendAction {
+	p := new(BackendAction)
+	*p = x
+	return p
+}
+
+func (x BackendAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BackendAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_enumTypes[0].Descriptor()
+}
+
+func (BackendAction) Type() protoreflect.EnumType {
+	return &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_enumTypes[0]
+}
+
+func (x BackendAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Kind of event emitted by the mux agent
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED      EventType = 0
+	EventType_EVENT_TYPE_KEY_ADDED        EventType = 1
+	EventType_EVENT_TYPE_KEY_REMOVED      EventType = 2
+	EventType_EVENT_TYPE_BACKEND_ADDED    EventType = 3
+	EventType_EVENT_TYPE_BACKEND_REMOVED  EventType = 4
+	EventType_EVENT_TYPE_KEY_EXPIRED      EventType = 5
+	EventType_EVENT_TYPE_SIGN_SERVED      EventType = 6
+	EventType_EVENT_TYPE_SIGN_DENIED      EventType = 7
+	EventType_EVENT_TYPE_BACKEND_UP       EventType = 8
+	EventType_EVENT_TYPE_BACKEND_DOWN     EventType = 9
+	EventType_EVENT_TYPE_CONFIG_RELOADED  EventType = 10
+	EventType_EVENT_TYPE_SIGNING_LOCKED   EventType = 11
+	EventType_EVENT_TYPE_SIGNING_UNLOCKED EventType = 12
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0:  "EVENT_TYPE_UNSPECIFIED",
+		1:  "EVENT_TYPE_KEY_ADDED",
+		2:  "EVENT_TYPE_KEY_REMOVED",
+		3:  "EVENT_TYPE_BACKEND_ADDED",
+		4:  "EVENT_TYPE_BACKEND_REMOVED",
+		5:  "EVENT_TYPE_KEY_EXPIRED",
+		6:  "EVENT_TYPE_SIGN_SERVED",
+		7:  "EVENT_TYPE_SIGN_DENIED",
+		8:  "EVENT_TYPE_BACKEND_UP",
+		9:  "EVENT_TYPE_BACKEND_DOWN",
+		10: "EVENT_TYPE_CONFIG_RELOADED",
+		11: "EVENT_TYPE_SIGNING_LOCKED",
+		12: "EVENT_TYPE_SIGNING_UNLOCKED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED":      0,
+		"EVENT_TYPE_KEY_ADDED":        1,
+		"EVENT_TYPE_KEY_REMOVED":      2,
+		"EVENT_TYPE_BACKEND_ADDED":    3,
+		"EVENT_TYPE_BACKEND_REMOVED":  4,
+		"EVENT_TYPE_KEY_EXPIRED":      5,
+		"EVENT_TYPE_SIGN_SERVED":      6,
+		"EVENT_TYPE_SIGN_DENIED":      7,
+		"EVENT_TYPE_BACKEND_UP":       8,
+		"EVENT_TYPE_BACKEND_DOWN":     9,
+		"EVENT_TYPE_CONFIG_RELOADED":  10,
+		"EVENT_TYPE_SIGNING_LOCKED":   11,
+		"EVENT_TYPE_SIGNING_UNLOCKED": 12,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_enumTypes[1].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_enumTypes[1]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
 // Defines commands for communicating with ssh-agent-mux daemon
 type Config struct {
-	state                        protoimpl.MessageState     `protogen:"opaque.v1"`
-	xxx_hidden_Id                *string                    `protobuf:"bytes,1,opt,name=id"`
-	xxx_hidden_Ts                *timestamppb.Timestamp     `protobuf:"bytes,2,opt,name=ts"`
-	xxx_hidden_SocketPath        *string                    `protobuf:"bytes,10,opt,name=socket_path,json=socketPath"`
-	xxx_hidden_BackendSocketPath []string                   `protobuf:"bytes,11,rep,name=backend_socket_path,json=backendSocketPath"`
-	xxx_hidden_Pid               int64                      `protobuf:"varint,12,opt,name=pid"`
-	xxx_hidden_StartTime         *timestamppb.Timestamp     `protobuf:"bytes,13,opt,name=start_time,json=startTime"`
-	xxx_hidden_Version           *string                    `protobuf:"bytes,100,opt,name=version"`
-	xxx_hidden_VersionInfo       *go_cliversion.VersionInfo `protobuf:"bytes,101,opt,name=version_info,json=versionInfo"`
-	XXX_raceDetectHookData       protoimpl.RaceDetectHookData
-	XXX_presence                 [1]uint32
-	unknownFields                protoimpl.UnknownFields
-	sizeCache                    protoimpl.SizeCache
+	state                               protoimpl.MessageState     `protogen:"opaque.v1"`
+	xxx_hidden_Id                       *string                    `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts                       *timestamppb.Timestamp     `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_SocketPath               *string                    `protobuf:"bytes,10,opt,name=socket_path,json=socketPath"`
+	xxx_hidden_BackendSocketPath        []string                   `protobuf:"bytes,11,rep,name=backend_socket_path,json=backendSocketPath"`
+	xxx_hidden_Pid                      int64                      `protobuf:"varint,12,opt,name=pid"`
+	xxx_hidden_StartTime                *timestamppb.Timestamp     `protobuf:"bytes,13,opt,name=start_time,json=startTime"`
+	xxx_hidden_ShutdownTimeout          *durationpb.Duration       `protobuf:"bytes,14,opt,name=shutdown_timeout,json=shutdownTimeout"`
+	xxx_hidden_Profile                  *string                    `protobuf:"bytes,15,opt,name=profile"`
+	xxx_hidden_Listeners                *[]*Listener               `protobuf:"bytes,16,rep,name=listeners"`
+	xxx_hidden_PrimaryBackendSocketPath *string                    `protobuf:"bytes,17,opt,name=primary_backend_socket_path,json=primaryBackendSocketPath"`
+	xxx_hidden_ControlSocketPath        *string                    `protobuf:"bytes,18,opt,name=control_socket_path,json=controlSocketPath"`
+	xxx_hidden_Version                  *string                    `protobuf:"bytes,100,opt,name=version"`
+	xxx_hidden_VersionInfo              *go_cliversion.VersionInfo `protobuf:"bytes,101,opt,name=version_info,json=versionInfo"`
+	XXX_raceDetectHookData              protoimpl.RaceDetectHookData
+	XXX_presence                        [1]uint32
+	unknownFields                       protoimpl.UnknownFields
+	sizeCache                           protoimpl.SizeCache
 }
 
 func (x *Config) Reset() {
@@ -113,6 +242,52 @@ func (x *Config) GetStartTime() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *Config) GetShutdownTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.xxx_hidden_ShutdownTimeout
+	}
+	return nil
+}
+
+func (x *Config) GetProfile() string {
+	if x != nil {
+		if x.xxx_hidden_Profile != nil {
+			return *x.xxx_hidden_Profile
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Config) GetListeners() []*Listener {
+	if x != nil {
+		if x.xxx_hidden_Listeners != nil {
+			return *x.xxx_hidden_Listeners
+		}
+	}
+	return nil
+}
+
+func (x *Config) GetPrimaryBackendSocketPath() string {
+	if x != nil {
+		if x.xxx_hidden_PrimaryBackendSocketPath != nil {
+			return *x.xxx_hidden_PrimaryBackendSocketPath
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Config) GetControlSocketPath() string {
+	if x != nil {
+		if x.xxx_hidden_ControlSocketPath != nil {
+			return *x.xxx_hidden_ControlSocketPath
+		}
+		return ""
+	}
+	return ""
+}
+
 func (x *Config) GetVersion() string {
 	if x != nil {
 		if x.xxx_hidden_Version != nil {
@@ -132,7 +307,7 @@ func (x *Config) GetVersionInfo() *go_cliversion.VersionInfo {
 
 func (x *Config) SetId(v string) {
 	x.xxx_hidden_Id = &v
-	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 8)
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 13)
 }
 
 func (x *Config) SetTs(v *timestamppb.Timestamp) {
@@ -141,7 +316,7 @@ func (x *Config) SetTs(v *timestamppb.Timestamp) {
 
 func (x *Config) SetSocketPath(v string) {
 	x.xxx_hidden_SocketPath = &v
-	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 8)
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 13)
 }
 
 func (x *Config) SetBackendSocketPath(v []string) {
@@ -150,16 +325,39 @@ func (x *Config) SetBackendSocketPath(v []string) {
 
 func (x *Config) SetPid(v int64) {
 	x.xxx_hidden_Pid = v
-	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 4, 8)
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 4, 13)
 }
 
 func (x *Config) SetStartTime(v *timestamppb.Timestamp) {
 	x.xxx_hidden_StartTime = v
 }
 
+func (x *Config) SetShutdownTimeout(v *durationpb.Duration) {
+	x.xxx_hidden_ShutdownTimeout = v
+}
+
+func (x *Config) SetProfile(v string) {
+	x.xxx_hidden_Profile = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 7, 13)
+}
+
+func (x *Config) SetListeners(v []*Listener) {
+	x.xxx_hidden_Listeners = &v
+}
+
+func (x *Config) SetPrimaryBackendSocketPath(v string) {
+	x.xxx_hidden_PrimaryBackendSocketPath = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 9, 13)
+}
+
+func (x *Config) SetControlSocketPath(v string) {
+	x.xxx_hidden_ControlSocketPath = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 10, 13)
+}
+
 func (x *Config) SetVersion(v string) {
 	x.xxx_hidden_Version = &v
-	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 6, 8)
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 11, 13)
 }
 
 func (x *Config) SetVersionInfo(v *go_cliversion.VersionInfo) {
@@ -201,11 +399,39 @@ func (x *Config) HasStartTime() bool {
 	return x.xxx_hidden_StartTime != nil
 }
 
+func (x *Config) HasShutdownTimeout() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_ShutdownTimeout != nil
+}
+
+func (x *Config) HasProfile() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 7)
+}
+
+func (x *Config) HasPrimaryBackendSocketPath() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 9)
+}
+
+func (x *Config) HasControlSocketPath() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 10)
+}
+
 func (x *Config) HasVersion() bool {
 	if x == nil {
 		return false
 	}
-	return protoimpl.X.Present(&(x.XXX_presence[0]), 6)
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 11)
 }
 
 func (x *Config) HasVersionInfo() bool {
@@ -238,8 +464,27 @@ func (x *Config) ClearStartTime() {
 	x.xxx_hidden_StartTime = nil
 }
 
+func (x *Config) ClearShutdownTimeout() {
+	x.xxx_hidden_ShutdownTimeout = nil
+}
+
+func (x *Config) ClearProfile() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 7)
+	x.xxx_hidden_Profile = nil
+}
+
+func (x *Config) ClearPrimaryBackendSocketPath() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 9)
+	x.xxx_hidden_PrimaryBackendSocketPath = nil
+}
+
+func (x *Config) ClearControlSocketPath() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 10)
+	x.xxx_hidden_ControlSocketPath = nil
+}
+
 func (x *Config) ClearVersion() {
-	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 6)
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 11)
 	x.xxx_hidden_Version = nil
 }
 
@@ -250,14 +495,19 @@ func (x *Config) ClearVersionInfo() {
 type Config_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
-	Id                *string
-	Ts                *timestamppb.Timestamp
-	SocketPath        *string
-	BackendSocketPath []string
-	Pid               *int64
-	StartTime         *timestamppb.Timestamp
-	Version           *string
-	VersionInfo       *go_cliversion.VersionInfo
+	Id                       *string
+	Ts                       *timestamppb.Timestamp
+	SocketPath               *string
+	BackendSocketPath        []string
+	Pid                      *int64
+	StartTime                *timestamppb.Timestamp
+	ShutdownTimeout          *durationpb.Duration
+	Profile                  *string
+	Listeners                []*Listener
+	PrimaryBackendSocketPath *string
+	ControlSocketPath        *string
+	Version                  *string
+	VersionInfo              *go_cliversion.VersionInfo
 }
 
 func (b0 Config_builder) Build() *Config {
@@ -265,28 +515,247 @@ func (b0 Config_builder) Build() *Config {
 	b, x := &b0, m0
 	_, _ = b, x
 	if b.Id != nil {
-		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 8)
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 13)
 		x.xxx_hidden_Id = b.Id
 	}
 	x.xxx_hidden_Ts = b.Ts
 	if b.SocketPath != nil {
-		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 8)
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 13)
 		x.xxx_hidden_SocketPath = b.SocketPath
 	}
 	x.xxx_hidden_BackendSocketPath = b.BackendSocketPath
 	if b.Pid != nil {
-		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 4, 8)
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 4, 13)
 		x.xxx_hidden_Pid = *b.Pid
 	}
 	x.xxx_hidden_StartTime = b.StartTime
+	x.xxx_hidden_ShutdownTimeout = b.ShutdownTimeout
+	if b.Profile != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 7, 13)
+		x.xxx_hidden_Profile = b.Profile
+	}
+	x.xxx_hidden_Listeners = &b.Listeners
+	if b.PrimaryBackendSocketPath != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 9, 13)
+		x.xxx_hidden_PrimaryBackendSocketPath = b.PrimaryBackendSocketPath
+	}
+	if b.ControlSocketPath != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 10, 13)
+		x.xxx_hidden_ControlSocketPath = b.ControlSocketPath
+	}
 	if b.Version != nil {
-		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 6, 8)
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 11, 13)
 		x.xxx_hidden_Version = b.Version
 	}
 	x.xxx_hidden_VersionInfo = b.VersionInfo
 	return m0
 }
 
+// Additional listener serving the agent
+type Listener struct {
+	state                   protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Name         *string                `protobuf:"bytes,1,opt,name=name"`
+	xxx_hidden_Type         *string                `protobuf:"bytes,2,opt,name=type"`
+	xxx_hidden_Address      *string                `protobuf:"bytes,3,opt,name=address"`
+	xxx_hidden_ReadOnly     bool                   `protobuf:"varint,4,opt,name=read_only,json=readOnly"`
+	xxx_hidden_AllowControl bool                   `protobuf:"varint,5,opt,name=allow_control,json=allowControl"`
+	XXX_raceDetectHookData  protoimpl.RaceDetectHookData
+	XXX_presence            [1]uint32
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *Listener) Reset() {
+	*x = Listener{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Listener) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Listener) ProtoMessage() {}
+
+func (x *Listener) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *Listener) GetName() string {
+	if x != nil {
+		if x.xxx_hidden_Name != nil {
+			return *x.xxx_hidden_Name
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Listener) GetType() string {
+	if x != nil {
+		if x.xxx_hidden_Type != nil {
+			return *x.xxx_hidden_Type
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Listener) GetAddress() string {
+	if x != nil {
+		if x.xxx_hidden_Address != nil {
+			return *x.xxx_hidden_Address
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Listener) GetReadOnly() bool {
+	if x != nil {
+		return x.xxx_hidden_ReadOnly
+	}
+	return false
+}
+
+func (x *Listener) GetAllowControl() bool {
+	if x != nil {
+		return x.xxx_hidden_AllowControl
+	}
+	return false
+}
+
+func (x *Listener) SetName(v string) {
+	x.xxx_hidden_Name = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 5)
+}
+
+func (x *Listener) SetType(v string) {
+	x.xxx_hidden_Type = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 1, 5)
+}
+
+func (x *Listener) SetAddress(v string) {
+	x.xxx_hidden_Address = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 5)
+}
+
+func (x *Listener) SetReadOnly(v bool) {
+	x.xxx_hidden_ReadOnly = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 5)
+}
+
+func (x *Listener) SetAllowControl(v bool) {
+	x.xxx_hidden_AllowControl = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 4, 5)
+}
+
+func (x *Listener) HasName() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *Listener) HasType() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 1)
+}
+
+func (x *Listener) HasAddress() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *Listener) HasReadOnly() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
+}
+
+func (x *Listener) HasAllowControl() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 4)
+}
+
+func (x *Listener) ClearName() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Name = nil
+}
+
+func (x *Listener) ClearType() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 1)
+	x.xxx_hidden_Type = nil
+}
+
+func (x *Listener) ClearAddress() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_Address = nil
+}
+
+func (x *Listener) ClearReadOnly() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 3)
+	x.xxx_hidden_ReadOnly = false
+}
+
+func (x *Listener) ClearAllowControl() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 4)
+	x.xxx_hidden_AllowControl = false
+}
+
+type Listener_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Name         *string
+	Type         *string
+	Address      *string
+	ReadOnly     *bool
+	AllowControl *bool
+}
+
+func (b0 Listener_builder) Build() *Listener {
+	m0 := &Listener{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Name != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 5)
+		x.xxx_hidden_Name = b.Name
+	}
+	if b.Type != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 1, 5)
+		x.xxx_hidden_Type = b.Type
+	}
+	if b.Address != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 5)
+		x.xxx_hidden_Address = b.Address
+	}
+	if b.ReadOnly != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 5)
+		x.xxx_hidden_ReadOnly = *b.ReadOnly
+	}
+	if b.AllowControl != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 4, 5)
+		x.xxx_hidden_AllowControl = *b.AllowControl
+	}
+	return m0
+}
+
 // Ping/Pong commands for health checking
 type Ping struct {
 	state                  protoimpl.MessageState `protogen:"opaque.v1"`
@@ -300,7 +769,7 @@ type Ping struct {
 
 func (x *Ping) Reset() {
 	*x = Ping{}
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[1]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -312,7 +781,7 @@ func (x *Ping) String() string {
 func (*Ping) ProtoMessage() {}
 
 func (x *Ping) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[1]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -408,7 +877,7 @@ type Pong struct {
 
 func (x *Pong) Reset() {
 	*x = Pong{}
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[2]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -420,7 +889,7 @@ func (x *Pong) String() string {
 func (*Pong) ProtoMessage() {}
 
 func (x *Pong) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[2]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -622,7 +1091,7 @@ type ShutdownRequest struct {
 
 func (x *ShutdownRequest) Reset() {
 	*x = ShutdownRequest{}
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[3]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -634,7 +1103,7 @@ func (x *ShutdownRequest) String() string {
 func (*ShutdownRequest) ProtoMessage() {}
 
 func (x *ShutdownRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[3]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -744,20 +1213,21 @@ func (b0 ShutdownRequest_builder) Build() *ShutdownRequest {
 
 // Response to commands
 type CommandResponse struct {
-	state                  protoimpl.MessageState `protogen:"opaque.v1"`
-	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
-	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
-	xxx_hidden_Success     bool                   `protobuf:"varint,10,opt,name=success"`
-	xxx_hidden_Message     *string                `protobuf:"bytes,11,opt,name=message"`
-	XXX_raceDetectHookData protoimpl.RaceDetectHookData
-	XXX_presence           [1]uint32
-	unknownFields          protoimpl.UnknownFields
-	sizeCache              protoimpl.SizeCache
+	state                            protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id                    *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts                    *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_Success               bool                   `protobuf:"varint,10,opt,name=success"`
+	xxx_hidden_Message               *string                `protobuf:"bytes,11,opt,name=message"`
+	xxx_hidden_ConnectionsTerminated int64                  `protobuf:"varint,12,opt,name=connections_terminated,json=connectionsTerminated"`
+	XXX_raceDetectHookData           protoimpl.RaceDetectHookData
+	XXX_presence                     [1]uint32
+	unknownFields                    protoimpl.UnknownFields
+	sizeCache                        protoimpl.SizeCache
 }
 
 func (x *CommandResponse) Reset() {
 	*x = CommandResponse{}
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[4]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -769,7 +1239,7 @@ func (x *CommandResponse) String() string {
 func (*CommandResponse) ProtoMessage() {}
 
 func (x *CommandResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[4]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -814,9 +1284,16 @@ func (x *CommandResponse) GetMessage() string {
 	return ""
 }
 
+func (x *CommandResponse) GetConnectionsTerminated() int64 {
+	if x != nil {
+		return x.xxx_hidden_ConnectionsTerminated
+	}
+	return 0
+}
+
 func (x *CommandResponse) SetId(v string) {
 	x.xxx_hidden_Id = &v
-	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 4)
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 5)
 }
 
 func (x *CommandResponse) SetTs(v *timestamppb.Timestamp) {
@@ -825,12 +1302,17 @@ func (x *CommandResponse) SetTs(v *timestamppb.Timestamp) {
 
 func (x *CommandResponse) SetSuccess(v bool) {
 	x.xxx_hidden_Success = v
-	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 4)
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 5)
 }
 
 func (x *CommandResponse) SetMessage(v string) {
 	x.xxx_hidden_Message = &v
-	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 4)
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 5)
+}
+
+func (x *CommandResponse) SetConnectionsTerminated(v int64) {
+	x.xxx_hidden_ConnectionsTerminated = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 4, 5)
 }
 
 func (x *CommandResponse) HasId() bool {
@@ -861,6 +1343,13 @@ func (x *CommandResponse) HasMessage() bool {
 	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
 }
 
+func (x *CommandResponse) HasConnectionsTerminated() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 4)
+}
+
 func (x *CommandResponse) ClearId() {
 	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
 	x.xxx_hidden_Id = nil
@@ -880,13 +1369,19 @@ func (x *CommandResponse) ClearMessage() {
 	x.xxx_hidden_Message = nil
 }
 
+func (x *CommandResponse) ClearConnectionsTerminated() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 4)
+	x.xxx_hidden_ConnectionsTerminated = 0
+}
+
 type CommandResponse_builder struct {
 	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
 
-	Id      *string
-	Ts      *timestamppb.Timestamp
-	Success *bool
-	Message *string
+	Id                    *string
+	Ts                    *timestamppb.Timestamp
+	Success               *bool
+	Message               *string
+	ConnectionsTerminated *int64
 }
 
 func (b0 CommandResponse_builder) Build() *CommandResponse {
@@ -894,18 +1389,22 @@ func (b0 CommandResponse_builder) Build() *CommandResponse {
 	b, x := &b0, m0
 	_, _ = b, x
 	if b.Id != nil {
-		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 4)
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 5)
 		x.xxx_hidden_Id = b.Id
 	}
 	x.xxx_hidden_Ts = b.Ts
 	if b.Success != nil {
-		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 4)
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 5)
 		x.xxx_hidden_Success = *b.Success
 	}
 	if b.Message != nil {
-		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 4)
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 5)
 		x.xxx_hidden_Message = b.Message
 	}
+	if b.ConnectionsTerminated != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 4, 5)
+		x.xxx_hidden_ConnectionsTerminated = *b.ConnectionsTerminated
+	}
 	return m0
 }
 
@@ -922,7 +1421,7 @@ type ConfigRequest struct {
 
 func (x *ConfigRequest) Reset() {
 	*x = ConfigRequest{}
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[5]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -934,7 +1433,7 @@ func (x *ConfigRequest) String() string {
 func (*ConfigRequest) ProtoMessage() {}
 
 func (x *ConfigRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[5]
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1013,81 +1512,3234 @@ func (b0 ConfigRequest_builder) Build() *ConfigRequest {
 	return m0
 }
 
-var File_github_com_na4ma4_ssh_agent_mux_api_commands_proto protoreflect.FileDescriptor
+// Request to generate a key inside the mux agent
+type KeygenRequest struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_Type        *string                `protobuf:"bytes,10,opt,name=type"`
+	xxx_hidden_Bits        int32                  `protobuf:"varint,11,opt,name=bits"`
+	xxx_hidden_Lifetime    *durationpb.Duration   `protobuf:"bytes,12,opt,name=lifetime"`
+	xxx_hidden_Comment     *string                `protobuf:"bytes,13,opt,name=comment"`
+	xxx_hidden_Tags        []string               `protobuf:"bytes,14,rep,name=tags"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
 
-const file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_rawDesc = "" +
-	"\n" +
-	"2github.com/na4ma4/ssh-agent-mux/api/commands.proto\x12\x0fsshagentmux.api\x1a!google/protobuf/go_features.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a.github.com/dosquad/go-cliversion/version.proto\"\xcc\x02\n" +
-	"\x06Config\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
-	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x1f\n" +
-	"\vsocket_path\x18\n" +
-	" \x01(\tR\n" +
-	"socketPath\x12.\n" +
-	"\x13backend_socket_path\x18\v \x03(\tR\x11backendSocketPath\x12\x10\n" +
-	"\x03pid\x18\f \x01(\x03R\x03pid\x129\n" +
-	"\n" +
-	"start_time\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x12\x18\n" +
-	"\aversion\x18d \x01(\tR\aversion\x12B\n" +
-	"\fversion_info\x18e \x01(\v2\x1f.dosquad.cliversion.VersionInfoR\vversionInfoJ\x04\b\x03\x10\n" +
-	"J\x04\b\x0e\x10d\"B\n" +
-	"\x04Ping\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
-	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\"\xe4\x01\n" +
-	"\x04Pong\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
-	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x123\n" +
-	"\aping_ts\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x06pingTs\x129\n" +
-	"\n" +
-	"start_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x12\x10\n" +
-	"\x03pid\x18\n" +
-	" \x01(\x03R\x03pid\x12\x18\n" +
-	"\aversion\x18\v \x01(\tR\aversionJ\x04\b\x05\x10\n" +
-	"\"i\n" +
-	"\x0fShutdownRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
-	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x14\n" +
-	"\x05force\x18\n" +
-	" \x01(\bR\x05forceJ\x04\b\x03\x10\n" +
-	"\"\x87\x01\n" +
-	"\x0fCommandResponse\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
-	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x18\n" +
-	"\asuccess\x18\n" +
-	" \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\v \x01(\tR\amessageJ\x04\b\x03\x10\n" +
-	"\"K\n" +
-	"\rConfigRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
-	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02tsB-Z#github.com/na4ma4/ssh-agent-mux/api\x92\x03\x05\xd2>\x02\x10\x03b\beditionsp\xe9\a"
+func (x *KeygenRequest) Reset() {
+	*x = KeygenRequest{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeygenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeygenRequest) ProtoMessage() {}
+
+func (x *KeygenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *KeygenRequest) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *KeygenRequest) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *KeygenRequest) GetType() string {
+	if x != nil {
+		if x.xxx_hidden_Type != nil {
+			return *x.xxx_hidden_Type
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *KeygenRequest) GetBits() int32 {
+	if x != nil {
+		return x.xxx_hidden_Bits
+	}
+	return 0
+}
+
+func (x *KeygenRequest) GetLifetime() *durationpb.Duration {
+	if x != nil {
+		return x.xxx_hidden_Lifetime
+	}
+	return nil
+}
+
+func (x *KeygenRequest) GetComment() string {
+	if x != nil {
+		if x.xxx_hidden_Comment != nil {
+			return *x.xxx_hidden_Comment
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *KeygenRequest) GetTags() []string {
+	if x != nil {
+		return x.xxx_hidden_Tags
+	}
+	return nil
+}
+
+func (x *KeygenRequest) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 7)
+}
+
+func (x *KeygenRequest) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *KeygenRequest) SetType(v string) {
+	x.xxx_hidden_Type = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 7)
+}
+
+func (x *KeygenRequest) SetBits(v int32) {
+	x.xxx_hidden_Bits = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 7)
+}
+
+func (x *KeygenRequest) SetLifetime(v *durationpb.Duration) {
+	x.xxx_hidden_Lifetime = v
+}
+
+func (x *KeygenRequest) SetComment(v string) {
+	x.xxx_hidden_Comment = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 5, 7)
+}
+
+func (x *KeygenRequest) SetTags(v []string) {
+	x.xxx_hidden_Tags = v
+}
+
+func (x *KeygenRequest) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *KeygenRequest) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *KeygenRequest) HasType() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *KeygenRequest) HasBits() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
+}
+
+func (x *KeygenRequest) HasLifetime() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Lifetime != nil
+}
 
-var file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+func (x *KeygenRequest) HasComment() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 5)
+}
+
+func (x *KeygenRequest) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *KeygenRequest) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+func (x *KeygenRequest) ClearType() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_Type = nil
+}
+
+func (x *KeygenRequest) ClearBits() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 3)
+	x.xxx_hidden_Bits = 0
+}
+
+func (x *KeygenRequest) ClearLifetime() {
+	x.xxx_hidden_Lifetime = nil
+}
+
+func (x *KeygenRequest) ClearComment() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 5)
+	x.xxx_hidden_Comment = nil
+}
+
+type KeygenRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id       *string
+	Ts       *timestamppb.Timestamp
+	Type     *string
+	Bits     *int32
+	Lifetime *durationpb.Duration
+	Comment  *string
+	Tags     []string
+}
+
+func (b0 KeygenRequest_builder) Build() *KeygenRequest {
+	m0 := &KeygenRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 7)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	if b.Type != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 7)
+		x.xxx_hidden_Type = b.Type
+	}
+	if b.Bits != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 7)
+		x.xxx_hidden_Bits = *b.Bits
+	}
+	x.xxx_hidden_Lifetime = b.Lifetime
+	if b.Comment != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 5, 7)
+		x.xxx_hidden_Comment = b.Comment
+	}
+	x.xxx_hidden_Tags = b.Tags
+	return m0
+}
+
+// Public half of a key generated inside the mux agent
+type KeygenResponse struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_PublicKey   *string                `protobuf:"bytes,10,opt,name=public_key,json=publicKey"`
+	xxx_hidden_Fingerprint *string                `protobuf:"bytes,11,opt,name=fingerprint"`
+	xxx_hidden_Expires     *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=expires"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *KeygenResponse) Reset() {
+	*x = KeygenResponse{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeygenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeygenResponse) ProtoMessage() {}
+
+func (x *KeygenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *KeygenResponse) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *KeygenResponse) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *KeygenResponse) GetPublicKey() string {
+	if x != nil {
+		if x.xxx_hidden_PublicKey != nil {
+			return *x.xxx_hidden_PublicKey
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *KeygenResponse) GetFingerprint() string {
+	if x != nil {
+		if x.xxx_hidden_Fingerprint != nil {
+			return *x.xxx_hidden_Fingerprint
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *KeygenResponse) GetExpires() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Expires
+	}
+	return nil
+}
+
+func (x *KeygenResponse) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 5)
+}
+
+func (x *KeygenResponse) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *KeygenResponse) SetPublicKey(v string) {
+	x.xxx_hidden_PublicKey = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 5)
+}
+
+func (x *KeygenResponse) SetFingerprint(v string) {
+	x.xxx_hidden_Fingerprint = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 5)
+}
+
+func (x *KeygenResponse) SetExpires(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Expires = v
+}
+
+func (x *KeygenResponse) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *KeygenResponse) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *KeygenResponse) HasPublicKey() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *KeygenResponse) HasFingerprint() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
+}
+
+func (x *KeygenResponse) HasExpires() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Expires != nil
+}
+
+func (x *KeygenResponse) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *KeygenResponse) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+func (x *KeygenResponse) ClearPublicKey() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_PublicKey = nil
+}
+
+func (x *KeygenResponse) ClearFingerprint() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 3)
+	x.xxx_hidden_Fingerprint = nil
+}
+
+func (x *KeygenResponse) ClearExpires() {
+	x.xxx_hidden_Expires = nil
+}
+
+type KeygenResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id          *string
+	Ts          *timestamppb.Timestamp
+	PublicKey   *string
+	Fingerprint *string
+	Expires     *timestamppb.Timestamp
+}
+
+func (b0 KeygenResponse_builder) Build() *KeygenResponse {
+	m0 := &KeygenResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 5)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	if b.PublicKey != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 5)
+		x.xxx_hidden_PublicKey = b.PublicKey
+	}
+	if b.Fingerprint != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 5)
+		x.xxx_hidden_Fingerprint = b.Fingerprint
+	}
+	x.xxx_hidden_Expires = b.Expires
+	return m0
+}
+
+// Request to issue a certificate for a local key with the built-in certificate authority
+type CertIssueRequest struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_Key         *string                `protobuf:"bytes,10,opt,name=key"`
+	xxx_hidden_Principals  []string               `protobuf:"bytes,11,rep,name=principals"`
+	xxx_hidden_Validity    *durationpb.Duration   `protobuf:"bytes,12,opt,name=validity"`
+	xxx_hidden_KeyId       *string                `protobuf:"bytes,13,opt,name=key_id,json=keyId"`
+	xxx_hidden_Host        bool                   `protobuf:"varint,14,opt,name=host"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *CertIssueRequest) Reset() {
+	*x = CertIssueRequest{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CertIssueRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CertIssueRequest) ProtoMessage() {}
+
+func (x *CertIssueRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *CertIssueRequest) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *CertIssueRequest) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *CertIssueRequest) GetKey() string {
+	if x != nil {
+		if x.xxx_hidden_Key != nil {
+			return *x.xxx_hidden_Key
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *CertIssueRequest) GetPrincipals() []string {
+	if x != nil {
+		return x.xxx_hidden_Principals
+	}
+	return nil
+}
+
+func (x *CertIssueRequest) GetValidity() *durationpb.Duration {
+	if x != nil {
+		return x.xxx_hidden_Validity
+	}
+	return nil
+}
+
+func (x *CertIssueRequest) GetKeyId() string {
+	if x != nil {
+		if x.xxx_hidden_KeyId != nil {
+			return *x.xxx_hidden_KeyId
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *CertIssueRequest) GetHost() bool {
+	if x != nil {
+		return x.xxx_hidden_Host
+	}
+	return false
+}
+
+func (x *CertIssueRequest) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 7)
+}
+
+func (x *CertIssueRequest) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *CertIssueRequest) SetKey(v string) {
+	x.xxx_hidden_Key = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 7)
+}
+
+func (x *CertIssueRequest) SetPrincipals(v []string) {
+	x.xxx_hidden_Principals = v
+}
+
+func (x *CertIssueRequest) SetValidity(v *durationpb.Duration) {
+	x.xxx_hidden_Validity = v
+}
+
+func (x *CertIssueRequest) SetKeyId(v string) {
+	x.xxx_hidden_KeyId = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 5, 7)
+}
+
+func (x *CertIssueRequest) SetHost(v bool) {
+	x.xxx_hidden_Host = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 6, 7)
+}
+
+func (x *CertIssueRequest) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *CertIssueRequest) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *CertIssueRequest) HasKey() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *CertIssueRequest) HasValidity() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Validity != nil
+}
+
+func (x *CertIssueRequest) HasKeyId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 5)
+}
+
+func (x *CertIssueRequest) HasHost() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 6)
+}
+
+func (x *CertIssueRequest) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *CertIssueRequest) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+func (x *CertIssueRequest) ClearKey() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_Key = nil
+}
+
+func (x *CertIssueRequest) ClearValidity() {
+	x.xxx_hidden_Validity = nil
+}
+
+func (x *CertIssueRequest) ClearKeyId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 5)
+	x.xxx_hidden_KeyId = nil
+}
+
+func (x *CertIssueRequest) ClearHost() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 6)
+	x.xxx_hidden_Host = false
+}
+
+type CertIssueRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id         *string
+	Ts         *timestamppb.Timestamp
+	Key        *string
+	Principals []string
+	Validity   *durationpb.Duration
+	KeyId      *string
+	Host       *bool
+}
+
+func (b0 CertIssueRequest_builder) Build() *CertIssueRequest {
+	m0 := &CertIssueRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 7)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	if b.Key != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 7)
+		x.xxx_hidden_Key = b.Key
+	}
+	x.xxx_hidden_Principals = b.Principals
+	x.xxx_hidden_Validity = b.Validity
+	if b.KeyId != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 5, 7)
+		x.xxx_hidden_KeyId = b.KeyId
+	}
+	if b.Host != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 6, 7)
+		x.xxx_hidden_Host = *b.Host
+	}
+	return m0
+}
+
+// Certificate issued by the built-in certificate authority
+type CertIssueResponse struct {
+	state                     protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id             *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts             *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_Certificate    *string                `protobuf:"bytes,10,opt,name=certificate"`
+	xxx_hidden_Serial         uint64                 `protobuf:"varint,11,opt,name=serial"`
+	xxx_hidden_ValidBefore    *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=valid_before,json=validBefore"`
+	xxx_hidden_KeyFingerprint *string                `protobuf:"bytes,13,opt,name=key_fingerprint,json=keyFingerprint"`
+	xxx_hidden_CaFingerprint  *string                `protobuf:"bytes,14,opt,name=ca_fingerprint,json=caFingerprint"`
+	XXX_raceDetectHookData    protoimpl.RaceDetectHookData
+	XXX_presence              [1]uint32
+	unknownFields             protoimpl.UnknownFields
+	sizeCache                 protoimpl.SizeCache
+}
+
+func (x *CertIssueResponse) Reset() {
+	*x = CertIssueResponse{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CertIssueResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CertIssueResponse) ProtoMessage() {}
+
+func (x *CertIssueResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *CertIssueResponse) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *CertIssueResponse) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *CertIssueResponse) GetCertificate() string {
+	if x != nil {
+		if x.xxx_hidden_Certificate != nil {
+			return *x.xxx_hidden_Certificate
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *CertIssueResponse) GetSerial() uint64 {
+	if x != nil {
+		return x.xxx_hidden_Serial
+	}
+	return 0
+}
+
+func (x *CertIssueResponse) GetValidBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_ValidBefore
+	}
+	return nil
+}
+
+func (x *CertIssueResponse) GetKeyFingerprint() string {
+	if x != nil {
+		if x.xxx_hidden_KeyFingerprint != nil {
+			return *x.xxx_hidden_KeyFingerprint
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *CertIssueResponse) GetCaFingerprint() string {
+	if x != nil {
+		if x.xxx_hidden_CaFingerprint != nil {
+			return *x.xxx_hidden_CaFingerprint
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *CertIssueResponse) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 7)
+}
+
+func (x *CertIssueResponse) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *CertIssueResponse) SetCertificate(v string) {
+	x.xxx_hidden_Certificate = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 7)
+}
+
+func (x *CertIssueResponse) SetSerial(v uint64) {
+	x.xxx_hidden_Serial = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 7)
+}
+
+func (x *CertIssueResponse) SetValidBefore(v *timestamppb.Timestamp) {
+	x.xxx_hidden_ValidBefore = v
+}
+
+func (x *CertIssueResponse) SetKeyFingerprint(v string) {
+	x.xxx_hidden_KeyFingerprint = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 5, 7)
+}
+
+func (x *CertIssueResponse) SetCaFingerprint(v string) {
+	x.xxx_hidden_CaFingerprint = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 6, 7)
+}
+
+func (x *CertIssueResponse) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *CertIssueResponse) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *CertIssueResponse) HasCertificate() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *CertIssueResponse) HasSerial() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
+}
+
+func (x *CertIssueResponse) HasValidBefore() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_ValidBefore != nil
+}
+
+func (x *CertIssueResponse) HasKeyFingerprint() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 5)
+}
+
+func (x *CertIssueResponse) HasCaFingerprint() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 6)
+}
+
+func (x *CertIssueResponse) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *CertIssueResponse) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+func (x *CertIssueResponse) ClearCertificate() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_Certificate = nil
+}
+
+func (x *CertIssueResponse) ClearSerial() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 3)
+	x.xxx_hidden_Serial = 0
+}
+
+func (x *CertIssueResponse) ClearValidBefore() {
+	x.xxx_hidden_ValidBefore = nil
+}
+
+func (x *CertIssueResponse) ClearKeyFingerprint() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 5)
+	x.xxx_hidden_KeyFingerprint = nil
+}
+
+func (x *CertIssueResponse) ClearCaFingerprint() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 6)
+	x.xxx_hidden_CaFingerprint = nil
+}
+
+type CertIssueResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id             *string
+	Ts             *timestamppb.Timestamp
+	Certificate    *string
+	Serial         *uint64
+	ValidBefore    *timestamppb.Timestamp
+	KeyFingerprint *string
+	CaFingerprint  *string
+}
+
+func (b0 CertIssueResponse_builder) Build() *CertIssueResponse {
+	m0 := &CertIssueResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 7)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	if b.Certificate != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 7)
+		x.xxx_hidden_Certificate = b.Certificate
+	}
+	if b.Serial != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 7)
+		x.xxx_hidden_Serial = *b.Serial
+	}
+	x.xxx_hidden_ValidBefore = b.ValidBefore
+	if b.KeyFingerprint != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 5, 7)
+		x.xxx_hidden_KeyFingerprint = b.KeyFingerprint
+	}
+	if b.CaFingerprint != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 6, 7)
+		x.xxx_hidden_CaFingerprint = b.CaFingerprint
+	}
+	return m0
+}
+
+// Request to list the keys known to the mux agent
+type ListKeysRequest struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *ListKeysRequest) Reset() {
+	*x = ListKeysRequest{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListKeysRequest) ProtoMessage() {}
+
+func (x *ListKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *ListKeysRequest) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *ListKeysRequest) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *ListKeysRequest) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 2)
+}
+
+func (x *ListKeysRequest) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *ListKeysRequest) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *ListKeysRequest) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *ListKeysRequest) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *ListKeysRequest) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+type ListKeysRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id *string
+	Ts *timestamppb.Timestamp
+}
+
+func (b0 ListKeysRequest_builder) Build() *ListKeysRequest {
+	m0 := &ListKeysRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 2)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	return m0
+}
+
+// Key known to the mux agent
+type Key struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_PublicKey   *string                `protobuf:"bytes,1,opt,name=public_key,json=publicKey"`
+	xxx_hidden_Fingerprint *string                `protobuf:"bytes,2,opt,name=fingerprint"`
+	xxx_hidden_Comment     *string                `protobuf:"bytes,3,opt,name=comment"`
+	xxx_hidden_Type        *string                `protobuf:"bytes,4,opt,name=type"`
+	xxx_hidden_Source      *string                `protobuf:"bytes,5,opt,name=source"`
+	xxx_hidden_Tags        []string               `protobuf:"bytes,6,rep,name=tags"`
+	xxx_hidden_Expires     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=expires"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *Key) Reset() {
+	*x = Key{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Key) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Key) ProtoMessage() {}
+
+func (x *Key) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *Key) GetPublicKey() string {
+	if x != nil {
+		if x.xxx_hidden_PublicKey != nil {
+			return *x.xxx_hidden_PublicKey
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Key) GetFingerprint() string {
+	if x != nil {
+		if x.xxx_hidden_Fingerprint != nil {
+			return *x.xxx_hidden_Fingerprint
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Key) GetComment() string {
+	if x != nil {
+		if x.xxx_hidden_Comment != nil {
+			return *x.xxx_hidden_Comment
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Key) GetType() string {
+	if x != nil {
+		if x.xxx_hidden_Type != nil {
+			return *x.xxx_hidden_Type
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Key) GetSource() string {
+	if x != nil {
+		if x.xxx_hidden_Source != nil {
+			return *x.xxx_hidden_Source
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Key) GetTags() []string {
+	if x != nil {
+		return x.xxx_hidden_Tags
+	}
+	return nil
+}
+
+func (x *Key) GetExpires() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Expires
+	}
+	return nil
+}
+
+func (x *Key) SetPublicKey(v string) {
+	x.xxx_hidden_PublicKey = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 7)
+}
+
+func (x *Key) SetFingerprint(v string) {
+	x.xxx_hidden_Fingerprint = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 1, 7)
+}
+
+func (x *Key) SetComment(v string) {
+	x.xxx_hidden_Comment = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 7)
+}
+
+func (x *Key) SetType(v string) {
+	x.xxx_hidden_Type = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 7)
+}
+
+func (x *Key) SetSource(v string) {
+	x.xxx_hidden_Source = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 4, 7)
+}
+
+func (x *Key) SetTags(v []string) {
+	x.xxx_hidden_Tags = v
+}
+
+func (x *Key) SetExpires(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Expires = v
+}
+
+func (x *Key) HasPublicKey() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *Key) HasFingerprint() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 1)
+}
+
+func (x *Key) HasComment() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *Key) HasType() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
+}
+
+func (x *Key) HasSource() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 4)
+}
+
+func (x *Key) HasExpires() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Expires != nil
+}
+
+func (x *Key) ClearPublicKey() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_PublicKey = nil
+}
+
+func (x *Key) ClearFingerprint() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 1)
+	x.xxx_hidden_Fingerprint = nil
+}
+
+func (x *Key) ClearComment() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_Comment = nil
+}
+
+func (x *Key) ClearType() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 3)
+	x.xxx_hidden_Type = nil
+}
+
+func (x *Key) ClearSource() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 4)
+	x.xxx_hidden_Source = nil
+}
+
+func (x *Key) ClearExpires() {
+	x.xxx_hidden_Expires = nil
+}
+
+type Key_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	PublicKey   *string
+	Fingerprint *string
+	Comment     *string
+	Type        *string
+	// Source is "local", the backend socket path or the PKCS#11 module path.
+	Source  *string
+	Tags    []string
+	Expires *timestamppb.Timestamp
+}
+
+func (b0 Key_builder) Build() *Key {
+	m0 := &Key{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.PublicKey != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 7)
+		x.xxx_hidden_PublicKey = b.PublicKey
+	}
+	if b.Fingerprint != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 1, 7)
+		x.xxx_hidden_Fingerprint = b.Fingerprint
+	}
+	if b.Comment != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 7)
+		x.xxx_hidden_Comment = b.Comment
+	}
+	if b.Type != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 7)
+		x.xxx_hidden_Type = b.Type
+	}
+	if b.Source != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 4, 7)
+		x.xxx_hidden_Source = b.Source
+	}
+	x.xxx_hidden_Tags = b.Tags
+	x.xxx_hidden_Expires = b.Expires
+	return m0
+}
+
+// Keys known to the mux agent
+type ListKeysResponse struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_Keys        *[]*Key                `protobuf:"bytes,10,rep,name=keys"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *ListKeysResponse) Reset() {
+	*x = ListKeysResponse{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListKeysResponse) ProtoMessage() {}
+
+func (x *ListKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *ListKeysResponse) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *ListKeysResponse) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *ListKeysResponse) GetKeys() []*Key {
+	if x != nil {
+		if x.xxx_hidden_Keys != nil {
+			return *x.xxx_hidden_Keys
+		}
+	}
+	return nil
+}
+
+func (x *ListKeysResponse) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 3)
+}
+
+func (x *ListKeysResponse) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *ListKeysResponse) SetKeys(v []*Key) {
+	x.xxx_hidden_Keys = &v
+}
+
+func (x *ListKeysResponse) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *ListKeysResponse) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *ListKeysResponse) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *ListKeysResponse) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+type ListKeysResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id   *string
+	Ts   *timestamppb.Timestamp
+	Keys []*Key
+}
+
+func (b0 ListKeysResponse_builder) Build() *ListKeysResponse {
+	m0 := &ListKeysResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 3)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	x.xxx_hidden_Keys = &b.Keys
+	return m0
+}
+
+// Request to list, add or remove backend agents
+type ManageBackendsRequest struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_Action      BackendAction          `protobuf:"varint,10,opt,name=action,enum=sshagentmux.api.BackendAction"`
+	xxx_hidden_SocketPath  *string                `protobuf:"bytes,11,opt,name=socket_path,json=socketPath"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *ManageBackendsRequest) Reset() {
+	*x = ManageBackendsRequest{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ManageBackendsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManageBackendsRequest) ProtoMessage() {}
+
+func (x *ManageBackendsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *ManageBackendsRequest) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *ManageBackendsRequest) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *ManageBackendsRequest) GetAction() BackendAction {
+	if x != nil {
+		if protoimpl.X.Present(&(x.XXX_presence[0]), 2) {
+			return x.xxx_hidden_Action
+		}
+	}
+	return BackendAction_BACKEND_ACTION_UNSPECIFIED
+}
+
+func (x *ManageBackendsRequest) GetSocketPath() string {
+	if x != nil {
+		if x.xxx_hidden_SocketPath != nil {
+			return *x.xxx_hidden_SocketPath
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *ManageBackendsRequest) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 4)
+}
+
+func (x *ManageBackendsRequest) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *ManageBackendsRequest) SetAction(v BackendAction) {
+	x.xxx_hidden_Action = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 4)
+}
+
+func (x *ManageBackendsRequest) SetSocketPath(v string) {
+	x.xxx_hidden_SocketPath = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 4)
+}
+
+func (x *ManageBackendsRequest) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *ManageBackendsRequest) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *ManageBackendsRequest) HasAction() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *ManageBackendsRequest) HasSocketPath() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
+}
+
+func (x *ManageBackendsRequest) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *ManageBackendsRequest) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+func (x *ManageBackendsRequest) ClearAction() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_Action = BackendAction_BACKEND_ACTION_UNSPECIFIED
+}
+
+func (x *ManageBackendsRequest) ClearSocketPath() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 3)
+	x.xxx_hidden_SocketPath = nil
+}
+
+type ManageBackendsRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id         *string
+	Ts         *timestamppb.Timestamp
+	Action     *BackendAction
+	SocketPath *string
+}
+
+func (b0 ManageBackendsRequest_builder) Build() *ManageBackendsRequest {
+	m0 := &ManageBackendsRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 4)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	if b.Action != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 4)
+		x.xxx_hidden_Action = *b.Action
+	}
+	if b.SocketPath != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 4)
+		x.xxx_hidden_SocketPath = b.SocketPath
+	}
+	return m0
+}
+
+// Backend agents after a ManageBackendsRequest
+type ManageBackendsResponse struct {
+	state                        protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id                *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts                *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_BackendSocketPath []string               `protobuf:"bytes,10,rep,name=backend_socket_path,json=backendSocketPath"`
+	xxx_hidden_Changed           bool                   `protobuf:"varint,11,opt,name=changed"`
+	XXX_raceDetectHookData       protoimpl.RaceDetectHookData
+	XXX_presence                 [1]uint32
+	unknownFields                protoimpl.UnknownFields
+	sizeCache                    protoimpl.SizeCache
+}
+
+func (x *ManageBackendsResponse) Reset() {
+	*x = ManageBackendsResponse{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ManageBackendsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManageBackendsResponse) ProtoMessage() {}
+
+func (x *ManageBackendsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *ManageBackendsResponse) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *ManageBackendsResponse) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *ManageBackendsResponse) GetBackendSocketPath() []string {
+	if x != nil {
+		return x.xxx_hidden_BackendSocketPath
+	}
+	return nil
+}
+
+func (x *ManageBackendsResponse) GetChanged() bool {
+	if x != nil {
+		return x.xxx_hidden_Changed
+	}
+	return false
+}
+
+func (x *ManageBackendsResponse) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 4)
+}
+
+func (x *ManageBackendsResponse) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *ManageBackendsResponse) SetBackendSocketPath(v []string) {
+	x.xxx_hidden_BackendSocketPath = v
+}
+
+func (x *ManageBackendsResponse) SetChanged(v bool) {
+	x.xxx_hidden_Changed = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 4)
+}
+
+func (x *ManageBackendsResponse) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *ManageBackendsResponse) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *ManageBackendsResponse) HasChanged() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
+}
+
+func (x *ManageBackendsResponse) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *ManageBackendsResponse) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+func (x *ManageBackendsResponse) ClearChanged() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 3)
+	x.xxx_hidden_Changed = false
+}
+
+type ManageBackendsResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id                *string
+	Ts                *timestamppb.Timestamp
+	BackendSocketPath []string
+	Changed           *bool
+}
+
+func (b0 ManageBackendsResponse_builder) Build() *ManageBackendsResponse {
+	m0 := &ManageBackendsResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 4)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	x.xxx_hidden_BackendSocketPath = b.BackendSocketPath
+	if b.Changed != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 4)
+		x.xxx_hidden_Changed = *b.Changed
+	}
+	return m0
+}
+
+// Request to stream the events of the mux agent
+type StreamEventsRequest struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_Types       []EventType            `protobuf:"varint,10,rep,packed,name=types,enum=sshagentmux.api.EventType"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *StreamEventsRequest) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *StreamEventsRequest) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *StreamEventsRequest) GetTypes() []EventType {
+	if x != nil {
+		return x.xxx_hidden_Types
+	}
+	return nil
+}
+
+func (x *StreamEventsRequest) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 3)
+}
+
+func (x *StreamEventsRequest) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *StreamEventsRequest) SetTypes(v []EventType) {
+	x.xxx_hidden_Types = v
+}
+
+func (x *StreamEventsRequest) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *StreamEventsRequest) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *StreamEventsRequest) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *StreamEventsRequest) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+type StreamEventsRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id *string
+	Ts *timestamppb.Timestamp
+	// Types limits the stream to these event types, every event is sent when it is empty.
+	Types []EventType
+}
+
+func (b0 StreamEventsRequest_builder) Build() *StreamEventsRequest {
+	m0 := &StreamEventsRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 3)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	x.xxx_hidden_Types = b.Types
+	return m0
+}
+
+// Event emitted by the mux agent
+type Event struct {
+	state                        protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id                *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts                *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_Type              EventType              `protobuf:"varint,10,opt,name=type,enum=sshagentmux.api.EventType"`
+	xxx_hidden_KeyFingerprint    *string                `protobuf:"bytes,11,opt,name=key_fingerprint,json=keyFingerprint"`
+	xxx_hidden_KeyComment        *string                `protobuf:"bytes,12,opt,name=key_comment,json=keyComment"`
+	xxx_hidden_KeyType           *string                `protobuf:"bytes,13,opt,name=key_type,json=keyType"`
+	xxx_hidden_BackendSocketPath *string                `protobuf:"bytes,14,opt,name=backend_socket_path,json=backendSocketPath"`
+	xxx_hidden_Reason            *string                `protobuf:"bytes,15,opt,name=reason"`
+	xxx_hidden_Source            *string                `protobuf:"bytes,16,opt,name=source"`
+	xxx_hidden_Frontend          *string                `protobuf:"bytes,17,opt,name=frontend"`
+	XXX_raceDetectHookData       protoimpl.RaceDetectHookData
+	XXX_presence                 [1]uint32
+	unknownFields                protoimpl.UnknownFields
+	sizeCache                    protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *Event) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Event) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *Event) GetType() EventType {
+	if x != nil {
+		if protoimpl.X.Present(&(x.XXX_presence[0]), 2) {
+			return x.xxx_hidden_Type
+		}
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *Event) GetKeyFingerprint() string {
+	if x != nil {
+		if x.xxx_hidden_KeyFingerprint != nil {
+			return *x.xxx_hidden_KeyFingerprint
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Event) GetKeyComment() string {
+	if x != nil {
+		if x.xxx_hidden_KeyComment != nil {
+			return *x.xxx_hidden_KeyComment
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Event) GetKeyType() string {
+	if x != nil {
+		if x.xxx_hidden_KeyType != nil {
+			return *x.xxx_hidden_KeyType
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Event) GetBackendSocketPath() string {
+	if x != nil {
+		if x.xxx_hidden_BackendSocketPath != nil {
+			return *x.xxx_hidden_BackendSocketPath
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Event) GetReason() string {
+	if x != nil {
+		if x.xxx_hidden_Reason != nil {
+			return *x.xxx_hidden_Reason
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Event) GetSource() string {
+	if x != nil {
+		if x.xxx_hidden_Source != nil {
+			return *x.xxx_hidden_Source
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Event) GetFrontend() string {
+	if x != nil {
+		if x.xxx_hidden_Frontend != nil {
+			return *x.xxx_hidden_Frontend
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *Event) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 10)
+}
+
+func (x *Event) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *Event) SetType(v EventType) {
+	x.xxx_hidden_Type = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 10)
+}
+
+func (x *Event) SetKeyFingerprint(v string) {
+	x.xxx_hidden_KeyFingerprint = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 10)
+}
+
+func (x *Event) SetKeyComment(v string) {
+	x.xxx_hidden_KeyComment = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 4, 10)
+}
+
+func (x *Event) SetKeyType(v string) {
+	x.xxx_hidden_KeyType = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 5, 10)
+}
+
+func (x *Event) SetBackendSocketPath(v string) {
+	x.xxx_hidden_BackendSocketPath = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 6, 10)
+}
+
+func (x *Event) SetReason(v string) {
+	x.xxx_hidden_Reason = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 7, 10)
+}
+
+func (x *Event) SetSource(v string) {
+	x.xxx_hidden_Source = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 8, 10)
+}
+
+func (x *Event) SetFrontend(v string) {
+	x.xxx_hidden_Frontend = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 9, 10)
+}
+
+func (x *Event) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *Event) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *Event) HasType() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *Event) HasKeyFingerprint() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
+}
+
+func (x *Event) HasKeyComment() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 4)
+}
+
+func (x *Event) HasKeyType() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 5)
+}
+
+func (x *Event) HasBackendSocketPath() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 6)
+}
+
+func (x *Event) HasReason() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 7)
+}
+
+func (x *Event) HasSource() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 8)
+}
+
+func (x *Event) HasFrontend() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 9)
+}
+
+func (x *Event) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *Event) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+func (x *Event) ClearType() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_Type = EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *Event) ClearKeyFingerprint() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 3)
+	x.xxx_hidden_KeyFingerprint = nil
+}
+
+func (x *Event) ClearKeyComment() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 4)
+	x.xxx_hidden_KeyComment = nil
+}
+
+func (x *Event) ClearKeyType() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 5)
+	x.xxx_hidden_KeyType = nil
+}
+
+func (x *Event) ClearBackendSocketPath() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 6)
+	x.xxx_hidden_BackendSocketPath = nil
+}
+
+func (x *Event) ClearReason() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 7)
+	x.xxx_hidden_Reason = nil
+}
+
+func (x *Event) ClearSource() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 8)
+	x.xxx_hidden_Source = nil
+}
+
+func (x *Event) ClearFrontend() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 9)
+	x.xxx_hidden_Frontend = nil
+}
+
+type Event_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id                *string
+	Ts                *timestamppb.Timestamp
+	Type              *EventType
+	KeyFingerprint    *string
+	KeyComment        *string
+	KeyType           *string
+	BackendSocketPath *string
+	// Reason a sign request was denied or a backend agent is down.
+	Reason *string
+	// Source of the key that served a sign request, "local", the backend socket path or the
+	// PKCS#11 module path.
+	Source *string
+	// Frontend is the listener that denied a sign request.
+	Frontend *string
+}
+
+func (b0 Event_builder) Build() *Event {
+	m0 := &Event{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 10)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	if b.Type != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 10)
+		x.xxx_hidden_Type = *b.Type
+	}
+	if b.KeyFingerprint != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 10)
+		x.xxx_hidden_KeyFingerprint = b.KeyFingerprint
+	}
+	if b.KeyComment != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 4, 10)
+		x.xxx_hidden_KeyComment = b.KeyComment
+	}
+	if b.KeyType != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 5, 10)
+		x.xxx_hidden_KeyType = b.KeyType
+	}
+	if b.BackendSocketPath != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 6, 10)
+		x.xxx_hidden_BackendSocketPath = b.BackendSocketPath
+	}
+	if b.Reason != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 7, 10)
+		x.xxx_hidden_Reason = b.Reason
+	}
+	if b.Source != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 8, 10)
+		x.xxx_hidden_Source = b.Source
+	}
+	if b.Frontend != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 9, 10)
+		x.xxx_hidden_Frontend = b.Frontend
+	}
+	return m0
+}
+
+// Request for the state of the sign rate limits and lockout
+type StatusRequest struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *StatusRequest) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *StatusRequest) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *StatusRequest) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 2)
+}
+
+func (x *StatusRequest) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *StatusRequest) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *StatusRequest) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *StatusRequest) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *StatusRequest) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+type StatusRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id *string
+	Ts *timestamppb.Timestamp
+}
+
+func (b0 StatusRequest_builder) Build() *StatusRequest {
+	m0 := &StatusRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 2)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	return m0
+}
+
+// Peer whose sign requests were denied or rate limited
+type ThrottledPeer struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Peer        *string                `protobuf:"bytes,1,opt,name=peer"`
+	xxx_hidden_Pid         int64                  `protobuf:"varint,2,opt,name=pid"`
+	xxx_hidden_Uid         int64                  `protobuf:"varint,3,opt,name=uid"`
+	xxx_hidden_Process     *string                `protobuf:"bytes,4,opt,name=process"`
+	xxx_hidden_Frontend    *string                `protobuf:"bytes,5,opt,name=frontend"`
+	xxx_hidden_RateLimited int64                  `protobuf:"varint,6,opt,name=rate_limited,json=rateLimited"`
+	xxx_hidden_Denied      int64                  `protobuf:"varint,7,opt,name=denied"`
+	xxx_hidden_LastDenied  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=last_denied,json=lastDenied"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *ThrottledPeer) Reset() {
+	*x = ThrottledPeer{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ThrottledPeer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ThrottledPeer) ProtoMessage() {}
+
+func (x *ThrottledPeer) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *ThrottledPeer) GetPeer() string {
+	if x != nil {
+		if x.xxx_hidden_Peer != nil {
+			return *x.xxx_hidden_Peer
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *ThrottledPeer) GetPid() int64 {
+	if x != nil {
+		return x.xxx_hidden_Pid
+	}
+	return 0
+}
+
+func (x *ThrottledPeer) GetUid() int64 {
+	if x != nil {
+		return x.xxx_hidden_Uid
+	}
+	return 0
+}
+
+func (x *ThrottledPeer) GetProcess() string {
+	if x != nil {
+		if x.xxx_hidden_Process != nil {
+			return *x.xxx_hidden_Process
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *ThrottledPeer) GetFrontend() string {
+	if x != nil {
+		if x.xxx_hidden_Frontend != nil {
+			return *x.xxx_hidden_Frontend
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *ThrottledPeer) GetRateLimited() int64 {
+	if x != nil {
+		return x.xxx_hidden_RateLimited
+	}
+	return 0
+}
+
+func (x *ThrottledPeer) GetDenied() int64 {
+	if x != nil {
+		return x.xxx_hidden_Denied
+	}
+	return 0
+}
+
+func (x *ThrottledPeer) GetLastDenied() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_LastDenied
+	}
+	return nil
+}
+
+func (x *ThrottledPeer) SetPeer(v string) {
+	x.xxx_hidden_Peer = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 8)
+}
+
+func (x *ThrottledPeer) SetPid(v int64) {
+	x.xxx_hidden_Pid = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 1, 8)
+}
+
+func (x *ThrottledPeer) SetUid(v int64) {
+	x.xxx_hidden_Uid = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 8)
+}
+
+func (x *ThrottledPeer) SetProcess(v string) {
+	x.xxx_hidden_Process = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 3, 8)
+}
+
+func (x *ThrottledPeer) SetFrontend(v string) {
+	x.xxx_hidden_Frontend = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 4, 8)
+}
+
+func (x *ThrottledPeer) SetRateLimited(v int64) {
+	x.xxx_hidden_RateLimited = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 5, 8)
+}
+
+func (x *ThrottledPeer) SetDenied(v int64) {
+	x.xxx_hidden_Denied = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 6, 8)
+}
+
+func (x *ThrottledPeer) SetLastDenied(v *timestamppb.Timestamp) {
+	x.xxx_hidden_LastDenied = v
+}
+
+func (x *ThrottledPeer) HasPeer() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *ThrottledPeer) HasPid() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 1)
+}
+
+func (x *ThrottledPeer) HasUid() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *ThrottledPeer) HasProcess() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 3)
+}
+
+func (x *ThrottledPeer) HasFrontend() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 4)
+}
+
+func (x *ThrottledPeer) HasRateLimited() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 5)
+}
+
+func (x *ThrottledPeer) HasDenied() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 6)
+}
+
+func (x *ThrottledPeer) HasLastDenied() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_LastDenied != nil
+}
+
+func (x *ThrottledPeer) ClearPeer() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Peer = nil
+}
+
+func (x *ThrottledPeer) ClearPid() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 1)
+	x.xxx_hidden_Pid = 0
+}
+
+func (x *ThrottledPeer) ClearUid() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_Uid = 0
+}
+
+func (x *ThrottledPeer) ClearProcess() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 3)
+	x.xxx_hidden_Process = nil
+}
+
+func (x *ThrottledPeer) ClearFrontend() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 4)
+	x.xxx_hidden_Frontend = nil
+}
+
+func (x *ThrottledPeer) ClearRateLimited() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 5)
+	x.xxx_hidden_RateLimited = 0
+}
+
+func (x *ThrottledPeer) ClearDenied() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 6)
+	x.xxx_hidden_Denied = 0
+}
+
+func (x *ThrottledPeer) ClearLastDenied() {
+	x.xxx_hidden_LastDenied = nil
+}
+
+type ThrottledPeer_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	// Peer identifies the client, "pid <pid>" or the frontend name when the peer is unknown.
+	Peer        *string
+	Pid         *int64
+	Uid         *int64
+	Process     *string
+	Frontend    *string
+	RateLimited *int64
+	Denied      *int64
+	LastDenied  *timestamppb.Timestamp
+}
+
+func (b0 ThrottledPeer_builder) Build() *ThrottledPeer {
+	m0 := &ThrottledPeer{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Peer != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 8)
+		x.xxx_hidden_Peer = b.Peer
+	}
+	if b.Pid != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 1, 8)
+		x.xxx_hidden_Pid = *b.Pid
+	}
+	if b.Uid != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 8)
+		x.xxx_hidden_Uid = *b.Uid
+	}
+	if b.Process != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 3, 8)
+		x.xxx_hidden_Process = b.Process
+	}
+	if b.Frontend != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 4, 8)
+		x.xxx_hidden_Frontend = b.Frontend
+	}
+	if b.RateLimited != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 5, 8)
+		x.xxx_hidden_RateLimited = *b.RateLimited
+	}
+	if b.Denied != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 6, 8)
+		x.xxx_hidden_Denied = *b.Denied
+	}
+	x.xxx_hidden_LastDenied = b.LastDenied
+	return m0
+}
+
+// State of the sign rate limits and lockout
+type StatusResponse struct {
+	state                     protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id             *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts             *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	xxx_hidden_Locked         bool                   `protobuf:"varint,10,opt,name=locked"`
+	xxx_hidden_LockedUntil    *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=locked_until,json=lockedUntil"`
+	xxx_hidden_LockReason     *string                `protobuf:"bytes,12,opt,name=lock_reason,json=lockReason"`
+	xxx_hidden_ThrottledPeers *[]*ThrottledPeer      `protobuf:"bytes,13,rep,name=throttled_peers,json=throttledPeers"`
+	XXX_raceDetectHookData    protoimpl.RaceDetectHookData
+	XXX_presence              [1]uint32
+	unknownFields             protoimpl.UnknownFields
+	sizeCache                 protoimpl.SizeCache
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *StatusResponse) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetLocked() bool {
+	if x != nil {
+		return x.xxx_hidden_Locked
+	}
+	return false
+}
+
+func (x *StatusResponse) GetLockedUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_LockedUntil
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetLockReason() string {
+	if x != nil {
+		if x.xxx_hidden_LockReason != nil {
+			return *x.xxx_hidden_LockReason
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetThrottledPeers() []*ThrottledPeer {
+	if x != nil {
+		if x.xxx_hidden_ThrottledPeers != nil {
+			return *x.xxx_hidden_ThrottledPeers
+		}
+	}
+	return nil
+}
+
+func (x *StatusResponse) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 6)
+}
+
+func (x *StatusResponse) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *StatusResponse) SetLocked(v bool) {
+	x.xxx_hidden_Locked = v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 2, 6)
+}
+
+func (x *StatusResponse) SetLockedUntil(v *timestamppb.Timestamp) {
+	x.xxx_hidden_LockedUntil = v
+}
+
+func (x *StatusResponse) SetLockReason(v string) {
+	x.xxx_hidden_LockReason = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 4, 6)
+}
+
+func (x *StatusResponse) SetThrottledPeers(v []*ThrottledPeer) {
+	x.xxx_hidden_ThrottledPeers = &v
+}
+
+func (x *StatusResponse) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *StatusResponse) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *StatusResponse) HasLocked() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 2)
+}
+
+func (x *StatusResponse) HasLockedUntil() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_LockedUntil != nil
+}
+
+func (x *StatusResponse) HasLockReason() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 4)
+}
+
+func (x *StatusResponse) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *StatusResponse) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+func (x *StatusResponse) ClearLocked() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 2)
+	x.xxx_hidden_Locked = false
+}
+
+func (x *StatusResponse) ClearLockedUntil() {
+	x.xxx_hidden_LockedUntil = nil
+}
+
+func (x *StatusResponse) ClearLockReason() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 4)
+	x.xxx_hidden_LockReason = nil
+}
+
+type StatusResponse_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id *string
+	Ts *timestamppb.Timestamp
+	// Locked is set while sign requests are refused after too many denied requests.
+	Locked *bool
+	// Unset when the lock lasts until it is cleared with Unlock.
+	LockedUntil    *timestamppb.Timestamp
+	LockReason     *string
+	ThrottledPeers []*ThrottledPeer
+}
+
+func (b0 StatusResponse_builder) Build() *StatusResponse {
+	m0 := &StatusResponse{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 6)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	if b.Locked != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 2, 6)
+		x.xxx_hidden_Locked = *b.Locked
+	}
+	x.xxx_hidden_LockedUntil = b.LockedUntil
+	if b.LockReason != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 4, 6)
+		x.xxx_hidden_LockReason = b.LockReason
+	}
+	x.xxx_hidden_ThrottledPeers = &b.ThrottledPeers
+	return m0
+}
+
+// Request to clear the sign lockout
+type UnlockRequest struct {
+	state                  protoimpl.MessageState `protogen:"opaque.v1"`
+	xxx_hidden_Id          *string                `protobuf:"bytes,1,opt,name=id"`
+	xxx_hidden_Ts          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts"`
+	XXX_raceDetectHookData protoimpl.RaceDetectHookData
+	XXX_presence           [1]uint32
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *UnlockRequest) Reset() {
+	*x = UnlockRequest{}
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockRequest) ProtoMessage() {}
+
+func (x *UnlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+func (x *UnlockRequest) GetId() string {
+	if x != nil {
+		if x.xxx_hidden_Id != nil {
+			return *x.xxx_hidden_Id
+		}
+		return ""
+	}
+	return ""
+}
+
+func (x *UnlockRequest) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.xxx_hidden_Ts
+	}
+	return nil
+}
+
+func (x *UnlockRequest) SetId(v string) {
+	x.xxx_hidden_Id = &v
+	protoimpl.X.SetPresent(&(x.XXX_presence[0]), 0, 2)
+}
+
+func (x *UnlockRequest) SetTs(v *timestamppb.Timestamp) {
+	x.xxx_hidden_Ts = v
+}
+
+func (x *UnlockRequest) HasId() bool {
+	if x == nil {
+		return false
+	}
+	return protoimpl.X.Present(&(x.XXX_presence[0]), 0)
+}
+
+func (x *UnlockRequest) HasTs() bool {
+	if x == nil {
+		return false
+	}
+	return x.xxx_hidden_Ts != nil
+}
+
+func (x *UnlockRequest) ClearId() {
+	protoimpl.X.ClearPresent(&(x.XXX_presence[0]), 0)
+	x.xxx_hidden_Id = nil
+}
+
+func (x *UnlockRequest) ClearTs() {
+	x.xxx_hidden_Ts = nil
+}
+
+type UnlockRequest_builder struct {
+	_ [0]func() // Prevents comparability and use of unkeyed literals for the builder.
+
+	Id *string
+	Ts *timestamppb.Timestamp
+}
+
+func (b0 UnlockRequest_builder) Build() *UnlockRequest {
+	m0 := &UnlockRequest{}
+	b, x := &b0, m0
+	_, _ = b, x
+	if b.Id != nil {
+		protoimpl.X.SetPresentNonAtomic(&(x.XXX_presence[0]), 0, 2)
+		x.xxx_hidden_Id = b.Id
+	}
+	x.xxx_hidden_Ts = b.Ts
+	return m0
+}
+
+var File_github_com_na4ma4_ssh_agent_mux_api_commands_proto protoreflect.FileDescriptor
+
+const file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_rawDesc = "" +
+	"\n" +
+	"2github.com/na4ma4/ssh-agent-mux/api/commands.proto\x12\x0fsshagentmux.api\x1a!google/protobuf/go_features.proto\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a.github.com/dosquad/go-cliversion/version.proto\"\xd4\x04\n" +
+	"\x06Config\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x1f\n" +
+	"\vsocket_path\x18\n" +
+	" \x01(\tR\n" +
+	"socketPath\x12.\n" +
+	"\x13backend_socket_path\x18\v \x03(\tR\x11backendSocketPath\x12\x10\n" +
+	"\x03pid\x18\f \x01(\x03R\x03pid\x129\n" +
+	"\n" +
+	"start_time\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x12D\n" +
+	"\x10shutdown_timeout\x18\x0e \x01(\v2\x19.google.protobuf.DurationR\x0fshutdownTimeout\x12\x18\n" +
+	"\aprofile\x18\x0f \x01(\tR\aprofile\x127\n" +
+	"\tlisteners\x18\x10 \x03(\v2\x19.sshagentmux.api.ListenerR\tlisteners\x12=\n" +
+	"\x1bprimary_backend_socket_path\x18\x11 \x01(\tR\x18primaryBackendSocketPath\x12.\n" +
+	"\x13control_socket_path\x18\x12 \x01(\tR\x11controlSocketPath\x12\x18\n" +
+	"\aversion\x18d \x01(\tR\aversion\x12B\n" +
+	"\fversion_info\x18e \x01(\v2\x1f.dosquad.cliversion.VersionInfoR\vversionInfoJ\x04\b\x03\x10\n" +
+	"J\x04\b\x13\x10d\"\x8e\x01\n" +
+	"\bListener\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x1b\n" +
+	"\tread_only\x18\x04 \x01(\bR\breadOnly\x12#\n" +
+	"\rallow_control\x18\x05 \x01(\bR\fallowControl\"B\n" +
+	"\x04Ping\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\"\xe4\x01\n" +
+	"\x04Pong\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x123\n" +
+	"\aping_ts\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x06pingTs\x129\n" +
+	"\n" +
+	"start_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x12\x10\n" +
+	"\x03pid\x18\n" +
+	" \x01(\x03R\x03pid\x12\x18\n" +
+	"\aversion\x18\v \x01(\tR\aversionJ\x04\b\x05\x10\n" +
+	"\"i\n" +
+	"\x0fShutdownRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x14\n" +
+	"\x05force\x18\n" +
+	" \x01(\bR\x05forceJ\x04\b\x03\x10\n" +
+	"\"\xbe\x01\n" +
+	"\x0fCommandResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x18\n" +
+	"\asuccess\x18\n" +
+	" \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\v \x01(\tR\amessage\x125\n" +
+	"\x16connections_terminated\x18\f \x01(\x03R\x15connectionsTerminatedJ\x04\b\x03\x10\n" +
+	"\"K\n" +
+	"\rConfigRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\"\xde\x01\n" +
+	"\rKeygenRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x12\n" +
+	"\x04type\x18\n" +
+	" \x01(\tR\x04type\x12\x12\n" +
+	"\x04bits\x18\v \x01(\x05R\x04bits\x125\n" +
+	"\blifetime\x18\f \x01(\v2\x19.google.protobuf.DurationR\blifetime\x12\x18\n" +
+	"\acomment\x18\r \x01(\tR\acomment\x12\x12\n" +
+	"\x04tags\x18\x0e \x03(\tR\x04tagsJ\x04\b\x03\x10\n" +
+	"\"\xc9\x01\n" +
+	"\x0eKeygenResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\n" +
+	" \x01(\tR\tpublicKey\x12 \n" +
+	"\vfingerprint\x18\v \x01(\tR\vfingerprint\x124\n" +
+	"\aexpires\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\aexpiresJ\x04\b\x03\x10\n" +
+	"\"\xe8\x01\n" +
+	"\x10CertIssueRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x10\n" +
+	"\x03key\x18\n" +
+	" \x01(\tR\x03key\x12\x1e\n" +
+	"\n" +
+	"principals\x18\v \x03(\tR\n" +
+	"principals\x125\n" +
+	"\bvalidity\x18\f \x01(\v2\x19.google.protobuf.DurationR\bvalidity\x12\x15\n" +
+	"\x06key_id\x18\r \x01(\tR\x05keyId\x12\x12\n" +
+	"\x04host\x18\x0e \x01(\bR\x04hostJ\x04\b\x03\x10\n" +
+	"\"\x9e\x02\n" +
+	"\x11CertIssueResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12 \n" +
+	"\vcertificate\x18\n" +
+	" \x01(\tR\vcertificate\x12\x16\n" +
+	"\x06serial\x18\v \x01(\x04R\x06serial\x12=\n" +
+	"\fvalid_before\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\vvalidBefore\x12'\n" +
+	"\x0fkey_fingerprint\x18\r \x01(\tR\x0ekeyFingerprint\x12%\n" +
+	"\x0eca_fingerprint\x18\x0e \x01(\tR\rcaFingerprintJ\x04\b\x03\x10\n" +
+	"\"M\n" +
+	"\x0fListKeysRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\"\xd6\x01\n" +
+	"\x03Key\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\tR\tpublicKey\x12 \n" +
+	"\vfingerprint\x18\x02 \x01(\tR\vfingerprint\x12\x18\n" +
+	"\acomment\x18\x03 \x01(\tR\acomment\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\x12\x12\n" +
+	"\x04tags\x18\x06 \x03(\tR\x04tags\x124\n" +
+	"\aexpires\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\aexpires\"~\n" +
+	"\x10ListKeysResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12(\n" +
+	"\x04keys\x18\n" +
+	" \x03(\v2\x14.sshagentmux.api.KeyR\x04keysJ\x04\b\x03\x10\n" +
+	"\"\xb2\x01\n" +
+	"\x15ManageBackendsRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x126\n" +
+	"\x06action\x18\n" +
+	" \x01(\x0e2\x1e.sshagentmux.api.BackendActionR\x06action\x12\x1f\n" +
+	"\vsocket_path\x18\v \x01(\tR\n" +
+	"socketPathJ\x04\b\x03\x10\n" +
+	"\"\xa4\x01\n" +
+	"\x16ManageBackendsResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12.\n" +
+	"\x13backend_socket_path\x18\n" +
+	" \x03(\tR\x11backendSocketPath\x12\x18\n" +
+	"\achanged\x18\v \x01(\bR\achangedJ\x04\b\x03\x10\n" +
+	"\"\x89\x01\n" +
+	"\x13StreamEventsRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x120\n" +
+	"\x05types\x18\n" +
+	" \x03(\x0e2\x1a.sshagentmux.api.EventTypeR\x05typesJ\x04\b\x03\x10\n" +
+	"\"\xda\x02\n" +
+	"\x05Event\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12.\n" +
+	"\x04type\x18\n" +
+	" \x01(\x0e2\x1a.sshagentmux.api.EventTypeR\x04type\x12'\n" +
+	"\x0fkey_fingerprint\x18\v \x01(\tR\x0ekeyFingerprint\x12\x1f\n" +
+	"\vkey_comment\x18\f \x01(\tR\n" +
+	"keyComment\x12\x19\n" +
+	"\bkey_type\x18\r \x01(\tR\akeyType\x12.\n" +
+	"\x13backend_socket_path\x18\x0e \x01(\tR\x11backendSocketPath\x12\x16\n" +
+	"\x06reason\x18\x0f \x01(\tR\x06reason\x12\x16\n" +
+	"\x06source\x18\x10 \x01(\tR\x06source\x12\x1a\n" +
+	"\bfrontend\x18\x11 \x01(\tR\bfrontendJ\x04\b\x03\x10\n" +
+	"\"K\n" +
+	"\rStatusRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\"\xf5\x01\n" +
+	"\rThrottledPeer\x12\x12\n" +
+	"\x04peer\x18\x01 \x01(\tR\x04peer\x12\x10\n" +
+	"\x03pid\x18\x02 \x01(\x03R\x03pid\x12\x10\n" +
+	"\x03uid\x18\x03 \x01(\x03R\x03uid\x12\x18\n" +
+	"\aprocess\x18\x04 \x01(\tR\aprocess\x12\x1a\n" +
+	"\bfrontend\x18\x05 \x01(\tR\bfrontend\x12!\n" +
+	"\frate_limited\x18\x06 \x01(\x03R\vrateLimited\x12\x16\n" +
+	"\x06denied\x18\a \x01(\x03R\x06denied\x12;\n" +
+	"\vlast_denied\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastDenied\"\x93\x02\n" +
+	"\x0eStatusResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x16\n" +
+	"\x06locked\x18\n" +
+	" \x01(\bR\x06locked\x12=\n" +
+	"\flocked_until\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\vlockedUntil\x12\x1f\n" +
+	"\vlock_reason\x18\f \x01(\tR\n" +
+	"lockReason\x12G\n" +
+	"\x0fthrottled_peers\x18\r \x03(\v2\x1e.sshagentmux.api.ThrottledPeerR\x0ethrottledPeersJ\x04\b\x03\x10\n" +
+	"\"K\n" +
+	"\rUnlockRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts*{\n" +
+	"\rBackendAction\x12\x1e\n" +
+	"\x1aBACKEND_ACTION_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13BACKEND_ACTION_LIST\x10\x01\x12\x16\n" +
+	"\x12BACKEND_ACTION_ADD\x10\x02\x12\x19\n" +
+	"\x15BACKEND_ACTION_REMOVE\x10\x03*\x87\x03\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14EVENT_TYPE_KEY_ADDED\x10\x01\x12\x1a\n" +
+	"\x16EVENT_TYPE_KEY_REMOVED\x10\x02\x12\x1c\n" +
+	"\x18EVENT_TYPE_BACKEND_ADDED\x10\x03\x12\x1e\n" +
+	"\x1aEVENT_TYPE_BACKEND_REMOVED\x10\x04\x12\x1a\n" +
+	"\x16EVENT_TYPE_KEY_EXPIRED\x10\x05\x12\x1a\n" +
+	"\x16EVENT_TYPE_SIGN_SERVED\x10\x06\x12\x1a\n" +
+	"\x16EVENT_TYPE_SIGN_DENIED\x10\a\x12\x19\n" +
+	"\x15EVENT_TYPE_BACKEND_UP\x10\b\x12\x1b\n" +
+	"\x17EVENT_TYPE_BACKEND_DOWN\x10\t\x12\x1e\n" +
+	"\x1aEVENT_TYPE_CONFIG_RELOADED\x10\n" +
+	"\x12\x1d\n" +
+	"\x19EVENT_TYPE_SIGNING_LOCKED\x10\v\x12\x1f\n" +
+	"\x1bEVENT_TYPE_SIGNING_UNLOCKED\x10\f2\x95\x06\n" +
+	"\n" +
+	"MuxControl\x124\n" +
+	"\x04Ping\x12\x15.sshagentmux.api.Ping\x1a\x15.sshagentmux.api.Pong\x12D\n" +
+	"\tGetConfig\x12\x1e.sshagentmux.api.ConfigRequest\x1a\x17.sshagentmux.api.Config\x12N\n" +
+	"\bShutdown\x12 .sshagentmux.api.ShutdownRequest\x1a .sshagentmux.api.CommandResponse\x12I\n" +
+	"\x06Keygen\x12\x1e.sshagentmux.api.KeygenRequest\x1a\x1f.sshagentmux.api.KeygenResponse\x12R\n" +
+	"\tCertIssue\x12!.sshagentmux.api.CertIssueRequest\x1a\".sshagentmux.api.CertIssueResponse\x12O\n" +
+	"\bListKeys\x12 .sshagentmux.api.ListKeysRequest\x1a!.sshagentmux.api.ListKeysResponse\x12a\n" +
+	"\x0eManageBackends\x12&.sshagentmux.api.ManageBackendsRequest\x1a'.sshagentmux.api.ManageBackendsResponse\x12N\n" +
+	"\fStreamEvents\x12$.sshagentmux.api.StreamEventsRequest\x1a\x16.sshagentmux.api.Event0\x01\x12L\n" +
+	"\tGetStatus\x12\x1e.sshagentmux.api.StatusRequest\x1a\x1f.sshagentmux.api.StatusResponse\x12J\n" +
+	"\x06Unlock\x12\x1e.sshagentmux.api.UnlockRequest\x1a .sshagentmux.api.CommandResponseB-Z#github.com/na4ma4/ssh-agent-mux/api\x92\x03\x05\xd2>\x02\x10\x03b\beditionsp\xe9\a"
+
+var file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
 var file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_goTypes = []any{
-	(*Config)(nil),                    // 0: sshagentmux.api.Config
-	(*Ping)(nil),                      // 1: sshagentmux.api.Ping
-	(*Pong)(nil),                      // 2: sshagentmux.api.Pong
-	(*ShutdownRequest)(nil),           // 3: sshagentmux.api.ShutdownRequest
-	(*CommandResponse)(nil),           // 4: sshagentmux.api.CommandResponse
-	(*ConfigRequest)(nil),             // 5: sshagentmux.api.ConfigRequest
-	(*timestamppb.Timestamp)(nil),     // 6: google.protobuf.Timestamp
-	(*go_cliversion.VersionInfo)(nil), // 7: dosquad.cliversion.VersionInfo
+	(BackendAction)(0),                // 0: sshagentmux.api.BackendAction
+	(EventType)(0),                    // 1: sshagentmux.api.EventType
+	(*Config)(nil),                    // 2: sshagentmux.api.Config
+	(*Listener)(nil),                  // 3: sshagentmux.api.Listener
+	(*Ping)(nil),                      // 4: sshagentmux.api.Ping
+	(*Pong)(nil),                      // 5: sshagentmux.api.Pong
+	(*ShutdownRequest)(nil),           // 6: sshagentmux.api.ShutdownRequest
+	(*CommandResponse)(nil),           // 7: sshagentmux.api.CommandResponse
+	(*ConfigRequest)(nil),             // 8: sshagentmux.api.ConfigRequest
+	(*KeygenRequest)(nil),             // 9: sshagentmux.api.KeygenRequest
+	(*KeygenResponse)(nil),            // 10: sshagentmux.api.KeygenResponse
+	(*CertIssueRequest)(nil),          // 11: sshagentmux.api.CertIssueRequest
+	(*CertIssueResponse)(nil),         // 12: sshagentmux.api.CertIssueResponse
+	(*ListKeysRequest)(nil),           // 13: sshagentmux.api.ListKeysRequest
+	(*Key)(nil),                       // 14: sshagentmux.api.Key
+	(*ListKeysResponse)(nil),          // 15: sshagentmux.api.ListKeysResponse
+	(*ManageBackendsRequest)(nil),     // 16: sshagentmux.api.ManageBackendsRequest
+	(*ManageBackendsResponse)(nil),    // 17: sshagentmux.api.ManageBackendsResponse
+	(*StreamEventsRequest)(nil),       // 18: sshagentmux.api.StreamEventsRequest
+	(*Event)(nil),                     // 19: sshagentmux.api.Event
+	(*StatusRequest)(nil),             // 20: sshagentmux.api.StatusRequest
+	(*ThrottledPeer)(nil),             // 21: sshagentmux.api.ThrottledPeer
+	(*StatusResponse)(nil),            // 22: sshagentmux.api.StatusResponse
+	(*UnlockRequest)(nil),             // 23: sshagentmux.api.UnlockRequest
+	(*timestamppb.Timestamp)(nil),     // 24: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),       // 25: google.protobuf.Duration
+	(*go_cliversion.VersionInfo)(nil), // 26: dosquad.cliversion.VersionInfo
 }
 var file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_depIdxs = []int32{
-	6,  // 0: sshagentmux.api.Config.ts:type_name -> google.protobuf.Timestamp
-	6,  // 1: sshagentmux.api.Config.start_time:type_name -> google.protobuf.Timestamp
-	7,  // 2: sshagentmux.api.Config.version_info:type_name -> dosquad.cliversion.VersionInfo
-	6,  // 3: sshagentmux.api.Ping.ts:type_name -> google.protobuf.Timestamp
-	6,  // 4: sshagentmux.api.Pong.ts:type_name -> google.protobuf.Timestamp
-	6,  // 5: sshagentmux.api.Pong.ping_ts:type_name -> google.protobuf.Timestamp
-	6,  // 6: sshagentmux.api.Pong.start_time:type_name -> google.protobuf.Timestamp
-	6,  // 7: sshagentmux.api.ShutdownRequest.ts:type_name -> google.protobuf.Timestamp
-	6,  // 8: sshagentmux.api.CommandResponse.ts:type_name -> google.protobuf.Timestamp
-	6,  // 9: sshagentmux.api.ConfigRequest.ts:type_name -> google.protobuf.Timestamp
-	10, // [10:10] is the sub-list for method output_type
-	10, // [10:10] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	24, // 0: sshagentmux.api.Config.ts:type_name -> google.protobuf.Timestamp
+	24, // 1: sshagentmux.api.Config.start_time:type_name -> google.protobuf.Timestamp
+	25, // 2: sshagentmux.api.Config.shutdown_timeout:type_name -> google.protobuf.Duration
+	3,  // 3: sshagentmux.api.Config.listeners:type_name -> sshagentmux.api.Listener
+	26, // 4: sshagentmux.api.Config.version_info:type_name -> dosquad.cliversion.VersionInfo
+	24, // 5: sshagentmux.api.Ping.ts:type_name -> google.protobuf.Timestamp
+	24, // 6: sshagentmux.api.Pong.ts:type_name -> google.protobuf.Timestamp
+	24, // 7: sshagentmux.api.Pong.ping_ts:type_name -> google.protobuf.Timestamp
+	24, // 8: sshagentmux.api.Pong.start_time:type_name -> google.protobuf.Timestamp
+	24, // 9: sshagentmux.api.ShutdownRequest.ts:type_name -> google.protobuf.Timestamp
+	24, // 10: sshagentmux.api.CommandResponse.ts:type_name -> google.protobuf.Timestamp
+	24, // 11: sshagentmux.api.ConfigRequest.ts:type_name -> google.protobuf.Timestamp
+	24, // 12: sshagentmux.api.KeygenRequest.ts:type_name -> google.protobuf.Timestamp
+	25, // 13: sshagentmux.api.KeygenRequest.lifetime:type_name -> google.protobuf.Duration
+	24, // 14: sshagentmux.api.KeygenResponse.ts:type_name -> google.protobuf.Timestamp
+	24, // 15: sshagentmux.api.KeygenResponse.expires:type_name -> google.protobuf.Timestamp
+	24, // 16: sshagentmux.api.CertIssueRequest.ts:type_name -> google.protobuf.Timestamp
+	25, // 17: sshagentmux.api.CertIssueRequest.validity:type_name -> google.protobuf.Duration
+	24, // 18: sshagentmux.api.CertIssueResponse.ts:type_name -> google.protobuf.Timestamp
+	24, // 19: sshagentmux.api.CertIssueResponse.valid_before:type_name -> google.protobuf.Timestamp
+	24, // 20: sshagentmux.api.ListKeysRequest.ts:type_name -> google.protobuf.Timestamp
+	24, // 21: sshagentmux.api.Key.expires:type_name -> google.protobuf.Timestamp
+	24, // 22: sshagentmux.api.ListKeysResponse.ts:type_name -> google.protobuf.Timestamp
+	14, // 23: sshagentmux.api.ListKeysResponse.keys:type_name -> sshagentmux.api.Key
+	24, // 24: sshagentmux.api.ManageBackendsRequest.ts:type_name -> google.protobuf.Timestamp
+	0,  // 25: sshagentmux.api.ManageBackendsRequest.action:type_name -> sshagentmux.api.BackendAction
+	24, // 26: sshagentmux.api.ManageBackendsResponse.ts:type_name -> google.protobuf.Timestamp
+	24, // 27: sshagentmux.api.StreamEventsRequest.ts:type_name -> google.protobuf.Timestamp
+	1,  // 28: sshagentmux.api.StreamEventsRequest.types:type_name -> sshagentmux.api.EventType
+	24, // 29: sshagentmux.api.Event.ts:type_name -> google.protobuf.Timestamp
+	1,  // 30: sshagentmux.api.Event.type:type_name -> sshagentmux.api.EventType
+	24, // 31: sshagentmux.api.StatusRequest.ts:type_name -> google.protobuf.Timestamp
+	24, // 32: sshagentmux.api.ThrottledPeer.last_denied:type_name -> google.protobuf.Timestamp
+	24, // 33: sshagentmux.api.StatusResponse.ts:type_name -> google.protobuf.Timestamp
+	24, // 34: sshagentmux.api.StatusResponse.locked_until:type_name -> google.protobuf.Timestamp
+	21, // 35: sshagentmux.api.StatusResponse.throttled_peers:type_name -> sshagentmux.api.ThrottledPeer
+	24, // 36: sshagentmux.api.UnlockRequest.ts:type_name -> google.protobuf.Timestamp
+	4,  // 37: sshagentmux.api.MuxControl.Ping:input_type -> sshagentmux.api.Ping
+	8,  // 38: sshagentmux.api.MuxControl.GetConfig:input_type -> sshagentmux.api.ConfigRequest
+	6,  // 39: sshagentmux.api.MuxControl.Shutdown:input_type -> sshagentmux.api.ShutdownRequest
+	9,  // 40: sshagentmux.api.MuxControl.Keygen:input_type -> sshagentmux.api.KeygenRequest
+	11, // 41: sshagentmux.api.MuxControl.CertIssue:input_type -> sshagentmux.api.CertIssueRequest
+	13, // 42: sshagentmux.api.MuxControl.ListKeys:input_type -> sshagentmux.api.ListKeysRequest
+	16, // 43: sshagentmux.api.MuxControl.ManageBackends:input_type -> sshagentmux.api.ManageBackendsRequest
+	18, // 44: sshagentmux.api.MuxControl.StreamEvents:input_type -> sshagentmux.api.StreamEventsRequest
+	20, // 45: sshagentmux.api.MuxControl.GetStatus:input_type -> sshagentmux.api.StatusRequest
+	23, // 46: sshagentmux.api.MuxControl.Unlock:input_type -> sshagentmux.api.UnlockRequest
+	5,  // 47: sshagentmux.api.MuxControl.Ping:output_type -> sshagentmux.api.Pong
+	2,  // 48: sshagentmux.api.MuxControl.GetConfig:output_type -> sshagentmux.api.Config
+	7,  // 49: sshagentmux.api.MuxControl.Shutdown:output_type -> sshagentmux.api.CommandResponse
+	10, // 50: sshagentmux.api.MuxControl.Keygen:output_type -> sshagentmux.api.KeygenResponse
+	12, // 51: sshagentmux.api.MuxControl.CertIssue:output_type -> sshagentmux.api.CertIssueResponse
+	15, // 52: sshagentmux.api.MuxControl.ListKeys:output_type -> sshagentmux.api.ListKeysResponse
+	17, // 53: sshagentmux.api.MuxControl.ManageBackends:output_type -> sshagentmux.api.ManageBackendsResponse
+	19, // 54: sshagentmux.api.MuxControl.StreamEvents:output_type -> sshagentmux.api.Event
+	22, // 55: sshagentmux.api.MuxControl.GetStatus:output_type -> sshagentmux.api.StatusResponse
+	7,  // 56: sshagentmux.api.MuxControl.Unlock:output_type -> sshagentmux.api.CommandResponse
+	47, // [47:57] is the sub-list for method output_type
+	37, // [37:47] is the sub-list for method input_type
+	37, // [37:37] is the sub-list for extension type_name
+	37, // [37:37] is the sub-list for extension extendee
+	0,  // [0:37] is the sub-list for field type_name
 }
 
 func init() { file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_init() }
@@ -1100,13 +4752,14 @@ func file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_rawDesc), len(file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   6,
+			NumEnums:      2,
+			NumMessages:   22,
 			NumExtensions: 0,
-			NumServices:   0,
+			NumServices:   1,
 		},
 		GoTypes:           file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_goTypes,
 		DependencyIndexes: file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_depIdxs,
+		EnumInfos:         file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_enumTypes,
 		MessageInfos:      file_github_com_na4ma4_ssh_agent_mux_api_commands_proto_msgTypes,
 	}.Build()
 	File_github_com_na4ma4_ssh_agent_mux_api_commands_proto = out.File
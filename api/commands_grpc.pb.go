@@ -0,0 +1,471 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v6.33.4
+// source: github.com/na4ma4/ssh-agent-mux/api/commands.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MuxControl_Ping_FullMethodName           = "/sshagentmux.api.MuxControl/Ping"
+	MuxControl_GetConfig_FullMethodName      = "/sshagentmux.api.MuxControl/GetConfig"
+	MuxControl_Shutdown_FullMethodName       = "/sshagentmux.api.MuxControl/Shutdown"
+	MuxControl_Keygen_FullMethodName         = "/sshagentmux.api.MuxControl/Keygen"
+	MuxControl_CertIssue_FullMethodName      = "/sshagentmux.api.MuxControl/CertIssue"
+	MuxControl_ListKeys_FullMethodName       = "/sshagentmux.api.MuxControl/ListKeys"
+	MuxControl_ManageBackends_FullMethodName = "/sshagentmux.api.MuxControl/ManageBackends"
+	MuxControl_StreamEvents_FullMethodName   = "/sshagentmux.api.MuxControl/StreamEvents"
+	MuxControl_GetStatus_FullMethodName      = "/sshagentmux.api.MuxControl/GetStatus"
+	MuxControl_Unlock_FullMethodName         = "/sshagentmux.api.MuxControl/Unlock"
+)
+
+// MuxControlClient is the client API for MuxControl service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Control API of the mux agent, served on the control socket
+type MuxControlClient interface {
+	Ping(ctx context.Context, in *Ping, opts ...grpc.CallOption) (*Pong, error)
+	GetConfig(ctx context.Context, in *ConfigRequest, opts ...grpc.CallOption) (*Config, error)
+	Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*CommandResponse, error)
+	Keygen(ctx context.Context, in *KeygenRequest, opts ...grpc.CallOption) (*KeygenResponse, error)
+	CertIssue(ctx context.Context, in *CertIssueRequest, opts ...grpc.CallOption) (*CertIssueResponse, error)
+	ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error)
+	ManageBackends(ctx context.Context, in *ManageBackendsRequest, opts ...grpc.CallOption) (*ManageBackendsResponse, error)
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	GetStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	Unlock(ctx context.Context, in *UnlockRequest, opts ...grpc.CallOption) (*CommandResponse, error)
+}
+
+type muxControlClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMuxControlClient(cc grpc.ClientConnInterface) MuxControlClient {
+	return &muxControlClient{cc}
+}
+
+func (c *muxControlClient) Ping(ctx context.Context, in *Ping, opts ...grpc.CallOption) (*Pong, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Pong)
+	err := c.cc.Invoke(ctx, MuxControl_Ping_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *muxControlClient) GetConfig(ctx context.Context, in *ConfigRequest, opts ...grpc.CallOption) (*Config, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Config)
+	err := c.cc.Invoke(ctx, MuxControl_GetConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *muxControlClient) Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*CommandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandResponse)
+	err := c.cc.Invoke(ctx, MuxControl_Shutdown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *muxControlClient) Keygen(ctx context.Context, in *KeygenRequest, opts ...grpc.CallOption) (*KeygenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeygenResponse)
+	err := c.cc.Invoke(ctx, MuxControl_Keygen_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *muxControlClient) CertIssue(ctx context.Context, in *CertIssueRequest, opts ...grpc.CallOption) (*CertIssueResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CertIssueResponse)
+	err := c.cc.Invoke(ctx, MuxControl_CertIssue_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *muxControlClient) ListKeys(ctx context.Context, in *ListKeysRequest, opts ...grpc.CallOption) (*ListKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListKeysResponse)
+	err := c.cc.Invoke(ctx, MuxControl_ListKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *muxControlClient) ManageBackends(ctx context.Context, in *ManageBackendsRequest, opts ...grpc.CallOption) (*ManageBackendsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ManageBackendsResponse)
+	err := c.cc.Invoke(ctx, MuxControl_ManageBackends_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *muxControlClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MuxControl_ServiceDesc.Streams[0], MuxControl_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MuxControl_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+func (c *muxControlClient) GetStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, MuxControl_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *muxControlClient) Unlock(ctx context.Context, in *UnlockRequest, opts ...grpc.CallOption) (*CommandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandResponse)
+	err := c.cc.Invoke(ctx, MuxControl_Unlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MuxControlServer is the server API for MuxControl service.
+// All implementations must embed UnimplementedMuxControlServer
+// for forward compatibility.
+//
+// Control API of the mux agent, served on the control socket
+type MuxControlServer interface {
+	Ping(context.Context, *Ping) (*Pong, error)
+	GetConfig(context.Context, *ConfigRequest) (*Config, error)
+	Shutdown(context.Context, *ShutdownRequest) (*CommandResponse, error)
+	Keygen(context.Context, *KeygenRequest) (*KeygenResponse, error)
+	CertIssue(context.Context, *CertIssueRequest) (*CertIssueResponse, error)
+	ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error)
+	ManageBackends(context.Context, *ManageBackendsRequest) (*ManageBackendsResponse, error)
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	GetStatus(context.Context, *StatusRequest) (*StatusResponse, error)
+	Unlock(context.Context, *UnlockRequest) (*CommandResponse, error)
+	mustEmbedUnimplementedMuxControlServer()
+}
+
+// UnimplementedMuxControlServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMuxControlServer struct{}
+
+func (UnimplementedMuxControlServer) Ping(context.Context, *Ping) (*Pong, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedMuxControlServer) GetConfig(context.Context, *ConfigRequest) (*Config, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfig not implemented")
+}
+func (UnimplementedMuxControlServer) Shutdown(context.Context, *ShutdownRequest) (*CommandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Shutdown not implemented")
+}
+func (UnimplementedMuxControlServer) Keygen(context.Context, *KeygenRequest) (*KeygenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Keygen not implemented")
+}
+func (UnimplementedMuxControlServer) CertIssue(context.Context, *CertIssueRequest) (*CertIssueResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CertIssue not implemented")
+}
+func (UnimplementedMuxControlServer) ListKeys(context.Context, *ListKeysRequest) (*ListKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListKeys not implemented")
+}
+func (UnimplementedMuxControlServer) ManageBackends(context.Context, *ManageBackendsRequest) (*ManageBackendsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ManageBackends not implemented")
+}
+func (UnimplementedMuxControlServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedMuxControlServer) GetStatus(context.Context, *StatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedMuxControlServer) Unlock(context.Context, *UnlockRequest) (*CommandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unlock not implemented")
+}
+func (UnimplementedMuxControlServer) mustEmbedUnimplementedMuxControlServer() {}
+func (UnimplementedMuxControlServer) testEmbeddedByValue()                    {}
+
+// UnsafeMuxControlServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MuxControlServer will
+// result in compilation errors.
+type UnsafeMuxControlServer interface {
+	mustEmbedUnimplementedMuxControlServer()
+}
+
+func RegisterMuxControlServer(s grpc.ServiceRegistrar, srv MuxControlServer) {
+	// If the following call pancis, it indicates UnimplementedMuxControlServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MuxControl_ServiceDesc, srv)
+}
+
+func _MuxControl_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Ping)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MuxControlServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MuxControl_Ping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MuxControlServer).Ping(ctx, req.(*Ping))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MuxControl_GetConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MuxControlServer).GetConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MuxControl_GetConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MuxControlServer).GetConfig(ctx, req.(*ConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MuxControl_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShutdownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MuxControlServer).Shutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MuxControl_Shutdown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MuxControlServer).Shutdown(ctx, req.(*ShutdownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MuxControl_Keygen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeygenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MuxControlServer).Keygen(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MuxControl_Keygen_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MuxControlServer).Keygen(ctx, req.(*KeygenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MuxControl_CertIssue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertIssueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MuxControlServer).CertIssue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MuxControl_CertIssue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MuxControlServer).CertIssue(ctx, req.(*CertIssueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MuxControl_ListKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MuxControlServer).ListKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MuxControl_ListKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MuxControlServer).ListKeys(ctx, req.(*ListKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MuxControl_ManageBackends_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ManageBackendsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MuxControlServer).ManageBackends(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MuxControl_ManageBackends_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MuxControlServer).ManageBackends(ctx, req.(*ManageBackendsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MuxControl_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MuxControlServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MuxControl_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+func _MuxControl_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MuxControlServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MuxControl_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MuxControlServer).GetStatus(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MuxControl_Unlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MuxControlServer).Unlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MuxControl_Unlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MuxControlServer).Unlock(ctx, req.(*UnlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MuxControl_ServiceDesc is the grpc.ServiceDesc for MuxControl service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MuxControl_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sshagentmux.api.MuxControl",
+	HandlerType: (*MuxControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ping",
+			Handler:    _MuxControl_Ping_Handler,
+		},
+		{
+			MethodName: "GetConfig",
+			Handler:    _MuxControl_GetConfig_Handler,
+		},
+		{
+			MethodName: "Shutdown",
+			Handler:    _MuxControl_Shutdown_Handler,
+		},
+		{
+			MethodName: "Keygen",
+			Handler:    _MuxControl_Keygen_Handler,
+		},
+		{
+			MethodName: "CertIssue",
+			Handler:    _MuxControl_CertIssue_Handler,
+		},
+		{
+			MethodName: "ListKeys",
+			Handler:    _MuxControl_ListKeys_Handler,
+		},
+		{
+			MethodName: "ManageBackends",
+			Handler:    _MuxControl_ManageBackends_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _MuxControl_GetStatus_Handler,
+		},
+		{
+			MethodName: "Unlock",
+			Handler:    _MuxControl_Unlock_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _MuxControl_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "github.com/na4ma4/ssh-agent-mux/api/commands.proto",
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/spf13/cobra"
+)
+
+var backendsCmd = &cobra.Command{
+	Use:   "backends",
+	Short: "List or change the backend agents of the running agent",
+	Long: `List, add or remove the backend agents of the running ssh-agent-mux instance.
+
+Changes last until the agent stops, add the backend agents to the config file to keep them.`,
+}
+
+var backendsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the backend agents",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return backendsCommand(cmd, api.BackendAction_BACKEND_ACTION_LIST, "")
+	},
+}
+
+var backendsAddCmd = &cobra.Command{
+	Use:   "add <socket-path>",
+	Short: "Add a backend agent",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return backendsCommand(cmd, api.BackendAction_BACKEND_ACTION_ADD, args[0])
+	},
+}
+
+var backendsRemoveCmd = &cobra.Command{
+	Use:   "remove <socket-path>",
+	Short: "Remove a backend agent",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return backendsCommand(cmd, api.BackendAction_BACKEND_ACTION_REMOVE, args[0])
+	},
+}
+
+func backendsCommand(cmd *cobra.Command, action api.BackendAction, socketPath string) error {
+	cmd.SilenceUsage = true
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeoutForControl)
+	defer cancel()
+
+	var logger *slog.Logger
+	{
+		var closer func()
+		logger, closer = getLogger()
+		defer closer()
+	}
+
+	socket, err := newCommandClient(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	resp, err := socket.ManageBackends(ctx, action, socketPath)
+	if err != nil {
+		logger.ErrorContext(ctx, "Backends command failed", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	logger.DebugContext(ctx, "Managed backend agents",
+		slog.String("backend-action", action.String()),
+		slog.Bool("changed", resp.GetChanged()),
+	)
+
+	for _, backendPath := range resp.GetBackendSocketPath() {
+		fmt.Fprintln(os.Stdout, backendPath)
+	}
+
+	return nil
+}
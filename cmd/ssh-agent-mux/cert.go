@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Manage certificates for local keys",
+}
+
+var certIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "Issue a certificate for a local key with the built-in CA",
+	Long: `Issue a certificate for a key held by the running agent using the built-in certificate authority.
+
+The certificate is attached to the key in the agent so it is offered to servers immediately, it is
+also printed so it can be saved or inspected with "ssh-keygen -L -f -".`,
+	Args: cobra.NoArgs,
+	RunE: certIssueCommand,
+}
+
+func certIssueCommand(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeoutForKeygen)
+	defer cancel()
+
+	var logger *slog.Logger
+	{
+		var closer func()
+		logger, closer = getLogger()
+		defer closer()
+	}
+
+	principals := viper.GetStringSlice("cert.principals")
+	if len(principals) == 0 {
+		// OpenSSH accepts a certificate without principals for every user or host
+		return errors.New("at least one principal is required (--principals)")
+	}
+
+	socket, err := newCommandClient(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	resp, err := socket.CertIssue(ctx, api.CertIssueRequest_builder{
+		Key:        proto.String(viper.GetString("cert.key")),
+		Principals: principals,
+		Validity:   durationpb.New(viper.GetDuration("cert.validity")),
+		KeyId:      proto.String(viper.GetString("cert.key-id")),
+		Host:       proto.Bool(viper.GetBool("cert.host")),
+	}.Build())
+	if err != nil {
+		logger.ErrorContext(ctx, "Certificate issue command failed", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	logger.InfoContext(ctx, "Issued certificate",
+		slog.String("key-fingerprint", resp.GetKeyFingerprint()),
+		slog.String("ca-fingerprint", resp.GetCaFingerprint()),
+		slog.Uint64("cert-serial", resp.GetSerial()),
+		slog.Time("cert-valid-before", resp.GetValidBefore().AsTime().Local()), //nolint:gosmopolitan // local time
+	)
+
+	fmt.Fprintln(os.Stdout, resp.GetCertificate())
+
+	return nil
+}
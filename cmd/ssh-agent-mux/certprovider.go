@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/certprovider"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/spf13/viper"
+)
+
+// Cert provider types for the "cert-provider.type" setting.
+const (
+	certProviderHTTP    = "http"
+	certProviderCommand = "command"
+)
+
+// certProviderConfig is the "cert-provider" config file setting.
+type certProviderConfig struct {
+	certprovider.HTTPConfig `mapstructure:",squash"`
+
+	// Type is the provider type, "http" (the default) or "command".
+	Type    string   `mapstructure:"type"`
+	Command []string `mapstructure:"command"`
+
+	Keys        muxagent.KeyPolicy `mapstructure:"keys"`
+	RenewBefore time.Duration      `mapstructure:"renew-before"`
+}
+
+// loadCertProvider returns the mux option enabling the configured cert provider, or nil when
+// no provider is configured.
+func loadCertProvider() (muxagent.Option, error) {
+	if !viper.IsSet("cert-provider") {
+		return nil, nil //nolint:nilnil // no provider configured
+	}
+
+	var config certProviderConfig
+	if err := viper.UnmarshalKey("cert-provider", &config); err != nil {
+		return nil, fmt.Errorf("failed to parse cert-provider config: %w", err)
+	}
+
+	var provider certprovider.Provider
+	{
+		var err error
+		switch config.Type {
+		case "", certProviderHTTP:
+			provider, err = certprovider.NewHTTPProvider(config.HTTPConfig)
+		case certProviderCommand:
+			provider, err = certprovider.NewCommandProvider(certprovider.CommandConfig{
+				Command: config.Command,
+				Timeout: config.Timeout,
+			})
+		default:
+			err = fmt.Errorf("%w: unknown cert-provider type %q", certprovider.ErrInvalidConfig, config.Type)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return muxagent.WithCertProvider(provider, muxagent.CertProviderOptions{
+		Keys:        config.Keys,
+		RenewBefore: config.RenewBefore,
+	}), nil
+}
@@ -6,4 +6,12 @@ const (
 	retryInterval = 10 * time.Millisecond
 
 	timeoutForSocketCreation = 5 * time.Second
+	timeoutForInstancePing   = 2 * time.Second
+	timeoutForKeygen         = 30 * time.Second
+	timeoutForControl        = 5 * time.Second
+
+	defaultShutdownTimeout = 5 * time.Second
+	forcedShutdownGrace    = 1 * time.Second
+
+	defaultCertValidity = 15 * time.Minute
 )
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxclient"
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
+	"github.com/spf13/viper"
+)
+
+// serveControlSocket serves the control API over gRPC on the dedicated control socket, which is
+// only accessible to the current user. The returned function stops the server.
+func serveControlSocket(
+	ctx context.Context, logger *slog.Logger, muxAgent *muxagent.MuxAgent, socketPath string,
+) (func(), error) {
+	if err := paths.EnsureDir(filepath.Dir(socketPath)); err != nil {
+		logger.ErrorContext(ctx, "Control socket directory is not usable", slogtool.ErrorAttr(err))
+		return nil, err
+	}
+
+	if err := removeSocketIfExists(ctx, logger, socketPath, muxclient.WithControlSocket(socketPath)); err != nil {
+		logger.ErrorContext(ctx, "Failed to remove existing control socket", slogtool.ErrorAttr(err))
+		return nil, err
+	}
+
+	l, closeListener, err := makeListener(ctx, socketPath)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to listen on control socket", slogtool.ErrorAttr(err))
+		return nil, err
+	}
+
+	logger.DebugContext(ctx, "Listening",
+		slog.String("listener", controlFrontendName),
+		slog.String("socket-path", socketPath),
+	)
+
+	server := muxAgent.NewControlServer()
+	go func() {
+		if serveErr := server.Serve(l); serveErr != nil {
+			logger.ErrorContext(ctx, "Control socket stopped", slogtool.ErrorAttr(serveErr))
+		}
+	}()
+
+	return func() {
+		// Let in-flight calls, such as the shutdown reply, finish before closing the socket.
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(viper.GetDuration("shutdown-timeout")):
+			server.Stop()
+		}
+
+		closeListener()
+	}, nil
+}
@@ -14,8 +14,8 @@ import (
 
 var ErrSocketActive = errors.New("socket is active and ssh-agent-mux is running")
 
-func removeSocketIfExists(ctx context.Context, logger *slog.Logger, socketPath string) error {
-	conn, _ := muxclient.NewMuxClient(logger, socketPath)
+func removeSocketIfExists(ctx context.Context, logger *slog.Logger, socketPath string, opts ...muxclient.Option) error {
+	conn, _ := muxclient.NewMuxClient(logger, socketPath, opts...)
 
 	// check if socket exists
 	if socketExists, err := conn.SocketExists(ctx); err != nil {
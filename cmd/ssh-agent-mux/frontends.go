@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/listener"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh/agent"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	primaryFrontendName = "agent"
+	controlFrontendName = "control"
+)
+
+// frontend is a listener and the agent served on it.
+type frontend struct {
+	name     string
+	listener net.Listener
+	agent    agent.ExtendedAgent
+}
+
+// listenerConfig is an additional listener from the "listeners" config file setting.
+type listenerConfig struct {
+	Name         string             `mapstructure:"name"`
+	Type         string             `mapstructure:"type"`
+	Address      string             `mapstructure:"address"`
+	TLS          listener.TLSConfig `mapstructure:"tls"`
+	Keys         muxagent.KeyPolicy `mapstructure:"keys"`
+	ReadOnly     bool               `mapstructure:"read-only"`
+	AllowControl bool               `mapstructure:"allow-control"`
+	Tags         []string           `mapstructure:"tags"`
+	Extensions   []string           `mapstructure:"extensions"`
+	Forwarding   forwardingConfig   `mapstructure:"forwarding"`
+}
+
+// forwardingConfig enables the restricted mode for listeners used by forwarded agent connections,
+// their sign requests are limited by the sign-limits.forwarded setting.
+type forwardingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// options converts the config into view options, keys must also match the forwardable policy.
+func (c forwardingConfig) options(forwardable muxagent.KeyPolicy) muxagent.ForwardingOptions {
+	if !c.Enabled {
+		return muxagent.ForwardingOptions{}
+	}
+
+	return muxagent.ForwardingOptions{
+		Enabled:     true,
+		Forwardable: forwardable,
+	}
+}
+
+// loadListenerConfigs reads the additional listeners from the profile config.
+func loadListenerConfigs() ([]listenerConfig, error) {
+	var configs []listenerConfig
+	if err := viper.UnmarshalKey("listeners", &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse listeners config: %w", err)
+	}
+
+	names := map[string]bool{primaryFrontendName: true, controlFrontendName: true}
+	for _, cfg := range configs {
+		if cfg.Name == "" {
+			return nil, errors.New("listener config is missing a name")
+		}
+
+		if names[cfg.Name] {
+			return nil, fmt.Errorf("duplicate listener name: %s", cfg.Name)
+		}
+
+		names[cfg.Name] = true
+	}
+
+	return configs, nil
+}
+
+// listenerConfigsToAPI converts listener configs for reporting in the running config.
+func listenerConfigsToAPI(configs []listenerConfig) []*api.Listener {
+	out := make([]*api.Listener, 0, len(configs))
+	for _, cfg := range configs {
+		out = append(out, api.Listener_builder{
+			Name:         proto.String(cfg.Name),
+			Type:         proto.String(cfg.Type),
+			Address:      proto.String(cfg.Address),
+			ReadOnly:     proto.Bool(cfg.ReadOnly),
+			AllowControl: proto.Bool(cfg.AllowControl),
+		}.Build())
+	}
+
+	return out
+}
+
+// makeFrontends creates the additional listeners, each serving a filtered view of the mux agent.
+func makeFrontends(
+	ctx context.Context, logger *slog.Logger, muxAgent *muxagent.MuxAgent, configs []listenerConfig,
+) ([]frontend, func(), error) {
+	var forwardable muxagent.KeyPolicy
+	if err := viper.UnmarshalKey("forwardable", &forwardable); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse forwardable config: %w", err)
+	}
+
+	frontends := make([]frontend, 0, len(configs))
+	closeAll := func() {
+		for _, fe := range frontends {
+			_ = fe.listener.Close()
+		}
+	}
+
+	for _, cfg := range configs {
+		l, err := listener.Listen(ctx, cfg.Type, cfg.Address, cfg.TLS)
+		if err != nil {
+			closeAll()
+			logger.ErrorContext(ctx, "Failed to create listener",
+				slog.String("listener", cfg.Name),
+				slogtool.ErrorAttr(err),
+			)
+			return nil, nil, fmt.Errorf("listener %s: %w", cfg.Name, err)
+		}
+
+		logger.DebugContext(ctx, "Listening",
+			slog.String("listener", cfg.Name),
+			slog.String("listener-type", cfg.Type),
+			slog.String("listener-address", l.Addr().String()),
+		)
+
+		frontends = append(frontends, frontend{
+			name:     cfg.Name,
+			listener: l,
+			agent: muxAgent.NewView(muxagent.ViewOptions{
+				Name:         cfg.Name,
+				Keys:         cfg.Keys,
+				ReadOnly:     cfg.ReadOnly,
+				AllowControl: cfg.AllowControl,
+				Tags:         cfg.Tags,
+				Extensions:   cfg.Extensions,
+				Forwarding:   cfg.Forwarding.options(forwardable),
+			}),
+		})
+	}
+
+	return frontends, closeAll, nil
+}
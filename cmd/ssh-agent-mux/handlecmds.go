@@ -12,6 +12,7 @@ import (
 	"github.com/na4ma4/go-timestring"
 	"github.com/na4ma4/ssh-agent-mux/api"
 	"github.com/na4ma4/ssh-agent-mux/internal/muxclient"
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
 	"github.com/spf13/viper"
 	"google.golang.org/protobuf/encoding/protojson"
 )
@@ -19,24 +20,58 @@ import (
 func handleCommand(ctx context.Context, logger *slog.Logger, command string) error {
 	logger.DebugContext(ctx, "Executing command", slog.String("command", command))
 
-	var socketPath string
-	{
-		socketPaths := viper.GetStringSlice("backend-agent")
-		if len(socketPaths) == 0 {
-			if socketPath = viper.GetString("socket"); socketPath == "" {
-				return errors.New("no backend agent socket specified for command mode")
-			}
-		}
+	socket, err := newCommandClient(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "ping":
+		return handleCommandPing(ctx, logger, socket)
+	case "shutdown", "close", "stop":
+		return handleCommandShutdown(ctx, logger, socket, false)
+	case "force-shutdown", "kill":
+		return handleCommandShutdown(ctx, logger, socket, true)
+	case "config", "config-json":
+		return handleCommandConfig(ctx, logger, socket, command)
+	case "query", "extensions":
+		return handleCommandQuery(ctx, logger, socket)
+	case "keys", "list-keys":
+		return handleCommandKeys(ctx, logger, socket)
+	case "status":
+		return handleCommandStatus(ctx, logger, socket)
+	case "unlock":
+		return handleCommandUnlock(ctx, logger, socket)
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+}
+
+// newCommandClient returns a client for the mux agent that commands are sent to.
+func newCommandClient(ctx context.Context, logger *slog.Logger) (*muxclient.MuxClient, error) {
+	// The default profile is usually reached through SSH_AUTH_SOCK, named profiles use their own socket.
+	socketPath := viper.GetString("socket")
+	if socketPaths := viper.GetStringSlice("backend-agent"); viper.GetString("profile") == paths.DefaultProfile &&
+		len(socketPaths) > 0 && socketPaths[0] != "" {
 		socketPath = socketPaths[0]
 	}
 
+	if socketPath == "" {
+		return nil, errors.New("no backend agent socket specified for command mode")
+	}
+
+	clientOptions := []muxclient.Option{muxclient.WithTokenFile(viper.GetString("control.token-file"))}
+	if controlSocket := viper.GetString("control.socket"); controlSocket != "" {
+		clientOptions = append(clientOptions, muxclient.WithControlSocket(controlSocket))
+	}
+
 	var socket *muxclient.MuxClient
 	{
 		var err error
-		socket, err = muxclient.NewMuxClient(logger, socketPath)
+		socket, err = muxclient.NewMuxClient(logger, socketPath, clientOptions...)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to create mux client", slogtool.ErrorAttr(err))
-			return err
+			return nil, err
 		}
 
 		if !socket.IsSocketWorking(ctx) && viper.GetString("socket") != socketPath {
@@ -45,24 +80,15 @@ func handleCommand(ctx context.Context, logger *slog.Logger, command string) err
 				slog.String("default-socket-path", viper.GetString("socket")),
 			)
 
-			socket, err = muxclient.NewMuxClient(logger, viper.GetString("socket"))
+			socket, err = muxclient.NewMuxClient(logger, viper.GetString("socket"), clientOptions...)
 			if err != nil {
 				logger.ErrorContext(ctx, "Failed to create mux client for default socket", slogtool.ErrorAttr(err))
-				return err
+				return nil, err
 			}
 		}
 	}
 
-	switch command {
-	case "ping":
-		return handleCommandPing(ctx, logger, socket)
-	case "shutdown", "close", "stop":
-		return handleCommandShutdown(ctx, logger, socket)
-	case "config", "config-json":
-		return handleCommandConfig(ctx, logger, socket, command)
-	default:
-		return fmt.Errorf("unknown command: %s", command)
-	}
+	return socket, nil
 }
 
 //nolint:gosmopolitan // I want local time here
@@ -112,16 +138,104 @@ func handleCommandPing(ctx context.Context, logger *slog.Logger, socket *muxclie
 	return nil
 }
 
-func handleCommandShutdown(ctx context.Context, logger *slog.Logger, socket *muxclient.MuxClient) error {
-	shutdownMsg, err := socket.Shutdown(ctx)
+func handleCommandQuery(ctx context.Context, logger *slog.Logger, socket *muxclient.MuxClient) error {
+	names, err := socket.Query(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Query command failed", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	fmt.Fprintln(os.Stdout, "Supported extensions:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stdout, "  - %s\n", name)
+	}
+
+	return nil
+}
+
+func handleCommandKeys(ctx context.Context, logger *slog.Logger, socket *muxclient.MuxClient) error {
+	resp, err := socket.ListKeys(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Keys command failed", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	for _, key := range resp.GetKeys() {
+		fmt.Fprintf(os.Stdout, "%s %s (%s) [%s]\n", key.GetFingerprint(), key.GetComment(), key.GetType(), key.GetSource())
+	}
+
+	return nil
+}
+
+//nolint:gosmopolitan // I want local time here
+func handleCommandStatus(ctx context.Context, logger *slog.Logger, socket *muxclient.MuxClient) error {
+	resp, err := socket.Status(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Status command failed", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	switch {
+	case !resp.GetLocked():
+		fmt.Fprintln(os.Stdout, "Signing: enabled")
+	case resp.HasLockedUntil():
+		fmt.Fprintf(os.Stdout, "Signing: locked until %s (%s)\n",
+			resp.GetLockedUntil().AsTime().Local().String(), resp.GetLockReason(),
+		)
+	default:
+		fmt.Fprintf(os.Stdout, "Signing: locked until unlocked (%s)\n", resp.GetLockReason())
+	}
+
+	if len(resp.GetThrottledPeers()) == 0 {
+		fmt.Fprintln(os.Stdout, "Throttled peers: none")
+		return nil
+	}
+
+	fmt.Fprintln(os.Stdout, "Throttled peers:")
+	for _, peer := range resp.GetThrottledPeers() {
+		fmt.Fprintf(os.Stdout, "  - %s", peer.GetPeer())
+		if process := peer.GetProcess(); process != "" {
+			fmt.Fprintf(os.Stdout, " (%s)", process)
+		}
+		if peer.HasUid() {
+			fmt.Fprintf(os.Stdout, " uid=%d", peer.GetUid())
+		}
+		if frontend := peer.GetFrontend(); frontend != "" {
+			fmt.Fprintf(os.Stdout, " frontend=%s", frontend)
+		}
+		fmt.Fprintf(os.Stdout, " rate-limited=%d denied=%d", peer.GetRateLimited(), peer.GetDenied())
+		if peer.HasLastDenied() {
+			fmt.Fprintf(os.Stdout, " last-denied=%s", peer.GetLastDenied().AsTime().Local().Format(time.RFC3339))
+		}
+		fmt.Fprintln(os.Stdout)
+	}
+
+	return nil
+}
+
+func handleCommandUnlock(ctx context.Context, logger *slog.Logger, socket *muxclient.MuxClient) error {
+	resp, err := socket.Unlock(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Unlock command failed", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Received unlock response: Status=%t, Message=%s\n", resp.GetSuccess(), resp.GetMessage())
+
+	return nil
+}
+
+func handleCommandShutdown(ctx context.Context, logger *slog.Logger, socket *muxclient.MuxClient, force bool) error {
+	shutdownMsg, err := socket.Shutdown(ctx, force)
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to create mux client", slogtool.ErrorAttr(err))
 		return err
 	}
 
-	fmt.Fprintf(os.Stdout, "Received shutdown response: ID=%s, TS=%s, Status=%t, Message=%s\n",
+	fmt.Fprintf(os.Stdout, "Received shutdown response: ID=%s, TS=%s, Status=%t, Message=%s, Terminated=%d\n",
 		shutdownMsg.GetId(), shutdownMsg.GetTs().AsTime().String(),
 		shutdownMsg.GetSuccess(), shutdownMsg.GetMessage(),
+		shutdownMsg.GetConnectionsTerminated(),
 	)
 
 	return nil
@@ -150,14 +264,30 @@ func handleCommandConfig(ctx context.Context, logger *slog.Logger, socket *muxcl
 	}
 
 	fmt.Fprintln(os.Stdout, "Received config:")
+	fmt.Fprintf(os.Stdout, "  Profile: %s\n", configMsg.GetProfile())
 	fmt.Fprintf(os.Stdout, "  Socket Path: %s\n", configMsg.GetSocketPath())
 	fmt.Fprintln(os.Stdout, "  Backend Socket Paths:")
 	for _, backendPath := range configMsg.GetBackendSocketPath() {
 		fmt.Fprintf(os.Stdout, "   - %s\n", backendPath)
 	}
+	if primary := configMsg.GetPrimaryBackendSocketPath(); primary != "" {
+		fmt.Fprintf(os.Stdout, "  Primary Backend Socket Path: %s\n", primary)
+	}
+	if controlSocket := configMsg.GetControlSocketPath(); controlSocket != "" {
+		fmt.Fprintf(os.Stdout, "  Control Socket Path: %s\n", controlSocket)
+	}
+	if len(configMsg.GetListeners()) > 0 {
+		fmt.Fprintln(os.Stdout, "  Listeners:")
+		for _, l := range configMsg.GetListeners() {
+			fmt.Fprintf(os.Stdout, "   - %s (%s %s, read-only=%t, allow-control=%t)\n",
+				l.GetName(), l.GetType(), l.GetAddress(), l.GetReadOnly(), l.GetAllowControl(),
+			)
+		}
+	}
 	fmt.Fprintf(os.Stdout, "  PID: %d\n", configMsg.GetPid())
 	//nolint:gosmopolitan // I want local time here
 	fmt.Fprintf(os.Stdout, "  Start Time: %s\n", configMsg.GetStartTime().AsTime().Local().String())
+	fmt.Fprintf(os.Stdout, "  Shutdown Timeout: %s\n", configMsg.GetShutdownTimeout().AsDuration())
 	fmt.Fprintf(os.Stdout, "  Version: %s\n", configMsg.GetVersion())
 
 	return nil
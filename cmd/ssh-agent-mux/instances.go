@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/go-timestring"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxclient"
+	"github.com/na4ma4/ssh-agent-mux/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+var listInstancesCmd = &cobra.Command{
+	Use:   "list-instances",
+	Short: "List running ssh-agent-mux instances",
+	Long:  `List the ssh-agent-mux instances registered in the runtime directory and check each one responds.`,
+	Args:  cobra.NoArgs,
+	RunE:  listInstancesCommand,
+}
+
+func listInstancesCommand(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	ctx := cmd.Context()
+
+	var logger *slog.Logger
+	{
+		var closer func()
+		logger, closer = getLogger()
+		defer closer()
+	}
+
+	reg := registry.Default()
+	instances, err := reg.List()
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to list instances", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd // column padding
+	fmt.Fprintln(w, "PROFILE\tPID\tSTATUS\tUPTIME\tVERSION\tSOCKET")
+
+	for _, instance := range instances {
+		status, uptime, version := "running", "-", instance.GetVersion()
+
+		if pong, pingErr := pingInstance(ctx, logger, instance.GetSocketPath()); pingErr != nil {
+			logger.DebugContext(ctx, "Instance did not respond to ping",
+				slog.String("profile", instance.GetProfile()),
+				slogtool.ErrorAttr(pingErr),
+			)
+
+			status = "not responding"
+			if !processExists(instance.GetPid()) {
+				status = "stale (removed)"
+				_ = reg.Remove(instance.GetProfile())
+			}
+		} else {
+			version = pong.GetVersion()
+			uptime = timestring.LongProcess.
+				Option(timestring.Abbreviated).
+				String(time.Since(pong.GetStartTime().AsTime()))
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			instance.GetProfile(), instance.GetPid(), status, uptime, version, instance.GetSocketPath(),
+		)
+	}
+
+	return w.Flush()
+}
+
+// pingInstance pings an instance with its own deadline, so a hung instance does not use up the
+// time of the instances listed after it.
+func pingInstance(ctx context.Context, logger *slog.Logger, socketPath string) (*api.Pong, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutForInstancePing)
+	defer cancel()
+
+	muxClient, err := muxclient.NewMuxClient(logger, socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return muxClient.Ping(ctx)
+}
+
+// processExists reports whether a process with the given pid is still running.
+func processExists(pid int64) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(int(pid), syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
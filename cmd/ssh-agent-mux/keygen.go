@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var keygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate a key inside the running agent",
+	Long: `Generate a key inside the running ssh-agent-mux instance and print its public key.
+
+The private key is generated in the agent process and never written to disk, use --lifetime for
+ephemeral keys that are wiped when they expire.`,
+	Args: cobra.NoArgs,
+	RunE: keygenCommand,
+}
+
+func keygenCommand(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeoutForKeygen)
+	defer cancel()
+
+	var logger *slog.Logger
+	{
+		var closer func()
+		logger, closer = getLogger()
+		defer closer()
+	}
+
+	socket, err := newCommandClient(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	resp, err := socket.Keygen(ctx, api.KeygenRequest_builder{
+		Type:     proto.String(viper.GetString("keygen.type")),
+		Bits:     proto.Int32(viper.GetInt32("keygen.bits")),
+		Lifetime: durationpb.New(viper.GetDuration("keygen.lifetime")),
+		Comment:  proto.String(viper.GetString("keygen.comment")),
+		Tags:     viper.GetStringSlice("keygen.tags"),
+	}.Build())
+	if err != nil {
+		logger.ErrorContext(ctx, "Keygen command failed", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	logger.DebugContext(ctx, "Generated key",
+		slog.String("key-fingerprint", resp.GetFingerprint()),
+	)
+
+	fmt.Fprintln(os.Stdout, resp.GetPublicKey())
+
+	return nil
+}
@@ -10,20 +10,24 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
-	"sync"
 	"syscall"
 
 	"github.com/dosquad/go-cliversion"
 	"github.com/na4ma4/go-contextual"
-	"github.com/na4ma4/go-permbits"
 	"github.com/na4ma4/go-slogtool"
 	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
 	"github.com/na4ma4/ssh-agent-mux/internal/daemon"
+	"github.com/na4ma4/ssh-agent-mux/internal/keystore"
+	"github.com/na4ma4/ssh-agent-mux/internal/listener"
 	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
+	"github.com/na4ma4/ssh-agent-mux/internal/registry"
+	"github.com/na4ma4/ssh-agent-mux/internal/securitykey"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"golang.org/x/crypto/ssh/agent"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -33,6 +37,8 @@ var rootCmd = &cobra.Command{
 	Long:    `ssh-agent-mux is an SSH agent that multiplexes local keys with one or more backend SSH agents.`,
 	RunE:    mainCommand,
 	Version: cliversion.Get().VersionString(),
+
+	PersistentPreRunE: loadProfile,
 }
 
 // ErrSignalReceived indicates that a termination signal was received.
@@ -50,34 +56,96 @@ func init() {
 	_ = viper.BindPFlag("foreground", rootCmd.PersistentFlags().Lookup("foreground"))
 	_ = viper.BindEnv("foreground", "SSH_AGENT_MUX_FOREGROUND")
 
-	_ = rootCmd.PersistentFlags().StringP("socket", "s", getDefaultSocketPath(),
+	_ = rootCmd.PersistentFlags().StringP("profile", "P", paths.DefaultProfile,
+		"Named profile, each profile has its own socket, config file and backend agents")
+	_ = viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	_ = viper.BindEnv("profile", "SSH_AGENT_MUX_PROFILE")
+
+	_ = rootCmd.PersistentFlags().StringP("socket", "s", paths.DefaultSocketPath(paths.DefaultProfile),
 		"Path to Unix socket for the agent")
 	_ = viper.BindPFlag("socket", rootCmd.PersistentFlags().Lookup("socket"))
 	_ = viper.BindEnv("socket", "SSH_AGENT_MUX_SOCKET")
 
 	_ = rootCmd.PersistentFlags().StringArrayP("backend-agent", "p", []string{os.Getenv("SSH_AUTH_SOCK")},
 		"Path to proxied SSH agent socket")
+	// SSH_AUTH_SOCK is only the flag default so profile config files can override it.
 	_ = viper.BindPFlag("backend-agent", rootCmd.PersistentFlags().Lookup("backend-agent"))
-	_ = viper.BindEnv("backend-agent", "SSH_AUTH_SOCK")
+
+	_ = rootCmd.PersistentFlags().String("primary-backend", "",
+		"Backend SSH agent socket that unrecognised agent requests are passed through to (default: none)")
+	_ = viper.BindPFlag("primary-backend", rootCmd.PersistentFlags().Lookup("primary-backend"))
+
+	_ = rootCmd.PersistentFlags().String("control-socket", "",
+		"Path to a dedicated Unix socket for control commands, admin commands are only served on it (default: none)")
+	_ = viper.BindPFlag("control.socket", rootCmd.PersistentFlags().Lookup("control-socket"))
+	_ = viper.BindEnv("control.socket", "SSH_AGENT_MUX_CONTROL_SOCKET")
 
 	_ = rootCmd.PersistentFlags().StringP("log-path", "l", "",
 		"Path to log file (default: stderr)")
 	_ = viper.BindPFlag("log-path", rootCmd.PersistentFlags().Lookup("log-path"))
 	_ = viper.BindEnv("log-path", "SSH_AGENT_MUX_LOGPATH")
 
+	_ = rootCmd.PersistentFlags().Duration("shutdown-timeout", defaultShutdownTimeout,
+		"Time to wait for active connections to finish when shutting down")
+	_ = viper.BindPFlag("shutdown-timeout", rootCmd.PersistentFlags().Lookup("shutdown-timeout"))
+	_ = viper.BindEnv("shutdown-timeout", "SSH_AGENT_MUX_SHUTDOWN_TIMEOUT")
+
+	_ = rootCmd.PersistentFlags().StringSlice("pkcs11-providers", muxagent.DefaultPKCS11Providers,
+		"Glob patterns of PKCS#11 module paths that may be loaded with ssh-add -s")
+	_ = viper.BindPFlag("pkcs11-providers", rootCmd.PersistentFlags().Lookup("pkcs11-providers"))
+
 	_ = rootCmd.PersistentFlags().StringP("command", "c", "",
 		"Command to run instead of the agent")
 	_ = viper.BindPFlag("command", rootCmd.PersistentFlags().Lookup("command"))
 	_ = viper.BindEnv("command", "SSH_AGENT_MUX_COMMAND")
-}
 
-func getDefaultSocketPath() string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		homeDir = os.TempDir()
-	}
+	rootCmd.AddCommand(listInstancesCmd)
+
+	_ = keygenCmd.Flags().StringP("type", "t", muxagent.KeyTypeEd25519, "Key type (ed25519, ecdsa, rsa)")
+	_ = viper.BindPFlag("keygen.type", keygenCmd.Flags().Lookup("type"))
+
+	_ = keygenCmd.Flags().IntP("bits", "b", 0, "Key size in bits for ecdsa and rsa keys")
+	_ = viper.BindPFlag("keygen.bits", keygenCmd.Flags().Lookup("bits"))
+
+	_ = keygenCmd.Flags().Duration("lifetime", 0, "Lifetime of the key, the key is wiped when it expires (0 = no limit)")
+	_ = viper.BindPFlag("keygen.lifetime", keygenCmd.Flags().Lookup("lifetime"))
+
+	_ = keygenCmd.Flags().StringP("comment", "C", "", "Key comment")
+	_ = viper.BindPFlag("keygen.comment", keygenCmd.Flags().Lookup("comment"))
+
+	_ = keygenCmd.Flags().StringSlice("tag", nil, "Tag the key for listener key policies (repeatable)")
+	_ = viper.BindPFlag("keygen.tags", keygenCmd.Flags().Lookup("tag"))
+
+	rootCmd.AddCommand(keygenCmd)
 
-	return filepath.Join(homeDir, ".ssh", "ssh-agent-mux.sock")
+	_ = certIssueCmd.Flags().StringP("key", "k", "", "Local key to certify, by SHA256 fingerprint or comment")
+	_ = viper.BindPFlag("cert.key", certIssueCmd.Flags().Lookup("key"))
+
+	_ = certIssueCmd.Flags().StringSliceP("principals", "n", nil, "Principals (user or host names) to certify")
+	_ = viper.BindPFlag("cert.principals", certIssueCmd.Flags().Lookup("principals"))
+
+	_ = certIssueCmd.Flags().DurationP("validity", "V", defaultCertValidity, "Certificate validity")
+	_ = viper.BindPFlag("cert.validity", certIssueCmd.Flags().Lookup("validity"))
+
+	_ = certIssueCmd.Flags().StringP("key-id", "I", "", "Certificate key identity (default: key comment)")
+	_ = viper.BindPFlag("cert.key-id", certIssueCmd.Flags().Lookup("key-id"))
+
+	_ = certIssueCmd.Flags().Bool("host", false, "Issue a host certificate instead of a user certificate")
+	_ = viper.BindPFlag("cert.host", certIssueCmd.Flags().Lookup("host"))
+
+	certCmd.AddCommand(certIssueCmd)
+	rootCmd.AddCommand(certCmd)
+
+	backendsCmd.AddCommand(backendsListCmd, backendsAddCmd, backendsRemoveCmd)
+	rootCmd.AddCommand(backendsCmd)
+
+	_ = watchCmd.Flags().Bool("json", false, "Print events as JSON, one object per line")
+	_ = viper.BindPFlag("watch.json", watchCmd.Flags().Lookup("json"))
+
+	_ = watchCmd.Flags().StringSliceP("type", "t", nil, "Only print these event types, e.g. sign-denied")
+	_ = viper.BindPFlag("watch.types", watchCmd.Flags().Lookup("type"))
+
+	rootCmd.AddCommand(watchCmd)
 }
 
 func main() {
@@ -124,16 +192,87 @@ func mainCommand(cmd *cobra.Command, _ []string) error {
 		return handleCommand(ctx, logger, command)
 	}
 
+	listenerConfigs, err := loadListenerConfigs()
+	if err != nil {
+		logger.ErrorContext(ctx, "Invalid listener config", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	var signPolicies []muxagent.SignPolicy
+	if err = viper.UnmarshalKey("sign-policies", &signPolicies); err != nil {
+		logger.ErrorContext(ctx, "Invalid sign policy config", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	var eventHooks []muxagent.EventHook
+	if err = viper.UnmarshalKey("hooks", &eventHooks); err != nil {
+		logger.ErrorContext(ctx, "Invalid hooks config", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	var signLimits muxagent.SignLimits
+	if err = viper.UnmarshalKey("sign-limits", &signLimits); err != nil {
+		logger.ErrorContext(ctx, "Invalid sign limits config", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	var caConfig muxagent.CAConfig
+	if err = viper.UnmarshalKey("ca", &caConfig); err != nil {
+		logger.ErrorContext(ctx, "Invalid CA config", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	var controlPolicy muxagent.ControlPolicy
+	if err = viper.UnmarshalKey("control", &controlPolicy); err != nil {
+		logger.ErrorContext(ctx, "Invalid control config", slogtool.ErrorAttr(err))
+		return err
+	}
+	controlPolicy.SocketPath = viper.GetString("control.socket")
+
+	var securityKeyConfig securitykey.Fido2Config
+	if err = viper.UnmarshalKey("security-key", &securityKeyConfig); err != nil {
+		logger.ErrorContext(ctx, "Invalid security key config", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	muxOptions := []muxagent.Option{
+		muxagent.WithSignPolicies(signPolicies...),
+		muxagent.WithEventHooks(eventHooks...),
+		muxagent.WithSignLimits(signLimits),
+		muxagent.WithCertAuthority(caConfig),
+		muxagent.WithPKCS11Providers(viper.GetStringSlice("pkcs11-providers")...),
+		muxagent.WithSecurityKeyAuthenticator(securitykey.NewFido2Authenticator(securityKeyConfig)),
+	}
+
+	if certProviderOption, certErr := loadCertProvider(); certErr != nil {
+		logger.ErrorContext(ctx, "Invalid cert provider config", slogtool.ErrorAttr(certErr))
+		return certErr
+	} else if certProviderOption != nil {
+		muxOptions = append(muxOptions, certProviderOption)
+	}
+
+	if notifierOption, notifyErr := loadSignNotifier(); notifyErr != nil {
+		logger.ErrorContext(ctx, "Invalid notify config", slogtool.ErrorAttr(notifyErr))
+		return notifyErr
+	} else if notifierOption != nil {
+		muxOptions = append(muxOptions, notifierOption)
+	}
+
 	config := api.Config_builder{
 		SocketPath: proto.String(viper.GetString("socket")),
 		BackendSocketPath: append(
 			[]string{},
 			viper.GetStringSlice("backend-agent")...,
 		),
-		StartTime:   timestamppb.Now(),
-		Version:     proto.String(cliversion.Get().VersionString()),
-		VersionInfo: cliversion.Get(),
-		Pid:         proto.Int64(int64(os.Getpid())),
+		PrimaryBackendSocketPath: proto.String(viper.GetString("primary-backend")),
+		ControlSocketPath:        proto.String(controlPolicy.SocketPath),
+		Profile:                  proto.String(viper.GetString("profile")),
+		Listeners:                listenerConfigsToAPI(listenerConfigs),
+		StartTime:                timestamppb.Now(),
+		ShutdownTimeout:          durationpb.New(viper.GetDuration("shutdown-timeout")),
+		Version:                  proto.String(cliversion.Get().VersionString()),
+		VersionInfo:              cliversion.Get(),
+		Pid:                      proto.Int64(int64(os.Getpid())),
 	}.Build()
 
 	if viper.GetBool("foreground") {
@@ -152,6 +291,12 @@ func mainCommand(cmd *cobra.Command, _ []string) error {
 		slog.String("backend-socket-path", viper.GetString("backend-agent")),
 	)
 
+	// Create the socket directory as a private directory, or verify an existing one is safe
+	if err := paths.EnsureDir(filepath.Dir(viper.GetString("socket"))); err != nil {
+		logger.ErrorContext(ctx, "Socket directory is not usable", slogtool.ErrorAttr(err))
+		return err
+	}
+
 	// Check socket and remove if it exists and is not active
 	if rmErr := removeSocketIfExists(ctx, logger, viper.GetString("socket")); rmErr != nil {
 		if errors.Is(rmErr, ErrSocketActive) {
@@ -162,11 +307,27 @@ func mainCommand(cmd *cobra.Command, _ []string) error {
 		return rmErr
 	}
 
+	// Keep local key material out of core dumps and away from debuggers
+	if err := keystore.ProtectProcess(); err != nil {
+		logger.WarnContext(ctx, "Failed to protect process memory", slogtool.ErrorAttr(err))
+	}
+
+	// Write a fresh control token that muxclient attaches to admin requests
+	if controlPolicy.TokenFile != "" {
+		controlPolicy.Token, err = muxagent.WriteControlToken(controlPolicy.TokenFile)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to write control token", slogtool.ErrorAttr(err))
+			return err
+		}
+		defer func() { _ = os.Remove(controlPolicy.TokenFile) }()
+	}
+	muxOptions = append(muxOptions, muxagent.WithControlPolicy(controlPolicy))
+
 	// Create the multiplexing agent
 	var muxAgent *muxagent.MuxAgent
 	{
 		var err error
-		muxAgent, err = muxagent.NewMuxAgent(ctx, logger, config)
+		muxAgent, err = muxagent.NewMuxAgent(ctx, logger, config, muxOptions...)
 		if err != nil {
 			logger.ErrorContext(ctx, "Failed to create mux agent", slogtool.ErrorAttr(err))
 			return err
@@ -187,18 +348,40 @@ func mainCommand(cmd *cobra.Command, _ []string) error {
 		defer cancel()
 	}
 
-	// Set socket permissions
-	if err := os.Chmod(viper.GetString("socket"), permbits.MustString("u=rw,a=")); err != nil {
-		logger.ErrorContext(ctx, "Failed to set socket permissions", slogtool.ErrorAttr(err))
-		return err
+	logger.DebugContext(ctx, "Listening", slog.String("socket-path", viper.GetString("socket")))
+
+	// Create additional listeners, each serving a filtered view of the agent
+	frontends := []frontend{{name: primaryFrontendName, listener: listener, agent: muxAgent}}
+	{
+		extraFrontends, closeFrontends, err := makeFrontends(ctx, logger, muxAgent, listenerConfigs)
+		if err != nil {
+			return err
+		}
+		defer closeFrontends()
+
+		frontends = append(frontends, extraFrontends...)
 	}
 
-	logger.DebugContext(ctx, "Listening", slog.String("socket-path", viper.GetString("socket")))
+	// Serve the control API on the dedicated control socket
+	if controlPolicy.SocketPath != "" {
+		stopControl, err := serveControlSocket(ctx, logger, muxAgent, controlPolicy.SocketPath)
+		if err != nil {
+			return err
+		}
+		defer stopControl()
+	}
+
+	// Record the instance so it can be found with list-instances
+	if deregister, err := registry.Default().Register(config); err != nil {
+		logger.WarnContext(ctx, "Failed to register instance", slogtool.ErrorAttr(err))
+	} else {
+		defer deregister()
+	}
 
 	PrintConfig(config)
 
 	// Run the main event loop
-	return wrapEventLoop(ctx, logger, listener, muxAgent)
+	return wrapEventLoop(ctx, logger, muxAgent, frontends)
 }
 
 func runMainProgramDaemonMode(ctx context.Context, logger *slog.Logger) error {
@@ -228,8 +411,8 @@ const (
 	defaultSignalChannelBufferSize = 1
 )
 
-func wrapEventLoop(ctx context.Context, logger *slog.Logger, listener net.Listener, muxAgent *muxagent.MuxAgent) error {
-	if err := runEventLoop(ctx, logger, listener, muxAgent); err != nil {
+func wrapEventLoop(ctx context.Context, logger *slog.Logger, muxAgent *muxagent.MuxAgent, frontends []frontend) error {
+	if err := runEventLoop(ctx, logger, muxAgent, frontends); err != nil {
 		if errors.Is(err, context.Canceled) {
 			logger.DebugContext(ctx, "Shutting down gracefully", slog.String("reason", err.Error()))
 			return nil
@@ -250,79 +433,153 @@ func wrapEventLoop(ctx context.Context, logger *slog.Logger, listener net.Listen
 	return nil
 }
 
-func runEventLoop(ctx context.Context, logger *slog.Logger, listener net.Listener, muxAgent *muxagent.MuxAgent) error {
+// acceptedConn is a connection accepted on one of the frontends.
+type acceptedConn struct {
+	conn     net.Conn
+	frontend frontend
+}
+
+func runEventLoop(ctx context.Context, logger *slog.Logger, muxAgent *muxagent.MuxAgent, frontends []frontend) error {
 	// Handle signals for graceful shutdown
 	sigChan := make(chan os.Signal, defaultSignalChannelBufferSize)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(sigChan)
 
+	// Reload the config file on SIGHUP
+	reloadChan := make(chan os.Signal, defaultSignalChannelBufferSize)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	defer signal.Stop(reloadChan)
+
 	// Track active connections for graceful shutdown
-	var wg sync.WaitGroup
-
-	// Accept connections in a goroutine
-	connChan := make(chan net.Conn)
-	errChan := make(chan error, 1)
-	go func() {
-		for {
-			conn, err := listener.Accept()
-			if err != nil {
-				select {
-				case errChan <- err:
-				default:
-					// Channel full or main loop already exited, ignore
-				}
-				return
-			}
-			select {
-			case connChan <- conn:
-			case <-ctx.Done():
-				// Context cancelled, close the connection and exit
-				_ = conn.Close()
-				return
-			}
+	conns := muxAgent.Connections()
+
+	// Accept connections on every frontend in its own goroutine
+	connChan := make(chan acceptedConn)
+	errChan := make(chan error, len(frontends))
+	for _, fe := range frontends {
+		go acceptConnections(ctx, fe, connChan, errChan)
+	}
+
+	closeListeners := func() {
+		// Close listeners to unblock Accept goroutines
+		for _, fe := range frontends {
+			_ = fe.listener.Close()
 		}
-	}()
+	}
 
 	// Main event loop
 	for {
 		select {
 		case <-ctx.Done():
-			// Close listener to unblock Accept goroutine
-			_ = listener.Close()
-			// Wait for active connections to finish
-			wg.Wait()
+			closeListeners()
+			drainConnections(logger, conns)
 			return ctx.Err()
 		case sig := <-sigChan:
 			logger.DebugContext(ctx, "Received signal, shutting down", slog.String("signal", sig.String()))
-			// Close listener to unblock Accept goroutine
-			_ = listener.Close()
-			// Wait for active connections to finish
-			wg.Wait()
+			closeListeners()
+			drainConnections(logger, conns)
 			return fmt.Errorf("%w: %s", ErrSignalReceived, sig.String())
+		case <-reloadChan:
+			reloadConfig(ctx, logger, muxAgent)
 		case err := <-errChan:
 			logger.ErrorContext(ctx, "Listener error", slogtool.ErrorAttr(err))
-			// Wait for active connections to finish
-			wg.Wait()
+			closeListeners()
+			drainConnections(logger, conns)
 			return err
-		case conn := <-connChan:
-			logger.DebugContext(ctx, "New connection accepted", slog.String("remote-addr", conn.RemoteAddr().String()))
-			wg.Add(1)
-			go handleConnection(ctx, logger, conn, muxAgent, &wg)
+		case accepted := <-connChan:
+			logger.DebugContext(ctx, "New connection accepted",
+				slog.String("frontend", accepted.frontend.name),
+				slog.String("remote-addr", accepted.conn.RemoteAddr().String()),
+			)
+			conns.Add(accepted.conn)
+			go handleConnection(ctx, logger, accepted.conn, muxAgent, accepted.frontend)
 		}
 	}
 }
 
+// acceptConnections accepts connections on a frontend listener until it is closed.
+func acceptConnections(ctx context.Context, fe frontend, connChan chan<- acceptedConn, errChan chan<- error) {
+	for {
+		conn, err := fe.listener.Accept()
+		if err != nil {
+			select {
+			case errChan <- fmt.Errorf("frontend %s: %w", fe.name, err):
+			default:
+				// Channel full or main loop already exited, ignore
+			}
+			return
+		}
+		select {
+		case connChan <- acceptedConn{conn: conn, frontend: fe}:
+		case <-ctx.Done():
+			// Context cancelled, close the connection and exit
+			_ = conn.Close()
+			return
+		}
+	}
+}
+
+// drainConnections waits for active connections to finish until the shutdown timeout expires,
+// then closes any connections that are still open.
+func drainConnections(logger *slog.Logger, conns *muxagent.ConnTracker) {
+	// The main context is usually already cancelled at this point.
+	ctx := context.Background()
+
+	logger.DebugContext(ctx, "Draining connections",
+		slog.Int("active-connections", conns.Len()),
+		slog.Duration("shutdown-timeout", viper.GetDuration("shutdown-timeout")),
+	)
+
+	drainCtx, cancel := context.WithTimeout(ctx, viper.GetDuration("shutdown-timeout"))
+	defer cancel()
+
+	if err := conns.Wait(drainCtx); err == nil {
+		return
+	}
+
+	terminated := conns.CloseAll()
+	logger.InfoContext(ctx, "Shutdown timeout reached, terminated connections",
+		slog.Int("connections-terminated", terminated),
+	)
+
+	graceCtx, graceCancel := context.WithTimeout(ctx, forcedShutdownGrace)
+	defer graceCancel()
+
+	if err := conns.Wait(graceCtx); err != nil {
+		logger.ErrorContext(ctx, "Connections did not close after termination",
+			slog.Int("active-connections", conns.Len()),
+		)
+	}
+}
+
 func handleConnection(
 	ctx context.Context, logger *slog.Logger, conn net.Conn,
-	muxAgent *muxagent.MuxAgent, wg *sync.WaitGroup,
+	muxAgent *muxagent.MuxAgent, fe frontend,
 ) {
-	defer wg.Done()
+	defer muxAgent.Connections().Done(conn)
 	defer conn.Close()
 
 	logger.DebugContext(ctx, "Handling connection", slog.String("remote-addr", conn.RemoteAddr().String()))
 
+	// Identify the peer for control extension checks, TLS and vsock connections have no peer
+	// credentials. Unix socket connections whose credentials cannot be read fail closed and may
+	// only call control extensions with the control token.
+	peer, err := muxagent.IdentifyPeer(conn)
+	session := muxAgent.NewSession(fe.agent, peer)
+	switch {
+	case err != nil:
+		logger.WarnContext(ctx, "Failed to identify peer", slogtool.ErrorAttr(err))
+		session = muxAgent.NewUnidentifiedSession(fe.agent)
+	case peer != nil:
+		logger.DebugContext(ctx, "Identified peer",
+			slog.Int64("peer-uid", int64(peer.UID)),
+			slog.Int("peer-pid", peer.PID),
+		)
+	}
+
 	// Serve the agent protocol on this connection
-	if err := agent.ServeAgent(muxAgent, conn); err != nil && !errors.Is(err, io.EOF) {
+	server := agentproto.NewServer(session, agentproto.WithLogger(logger))
+	if err = server.Serve(conn); err != nil && !isConnectionClosedError(err) {
 		logger.ErrorContext(ctx, "Error serving agent", slogtool.ErrorAttr(err))
 	}
 
@@ -332,16 +589,19 @@ func handleConnection(
 	)
 }
 
+// isConnectionClosedError reports whether err is the result of the client or the shutdown
+// procedure closing the connection.
+func isConnectionClosedError(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, net.ErrClosed)
+}
+
+// makeListener creates a unix socket listener only accessible by the current user, the returned
+// function closes it and removes the socket.
 func makeListener(ctx context.Context, socketPath string) (net.Listener, func(), error) {
-	// Create Unix socket listener
-	listenConfig := &net.ListenConfig{}
-	listener, err := listenConfig.Listen(ctx, "unix", socketPath)
+	l, err := listener.ListenUnix(ctx, socketPath)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return listener, func() {
-		_ = listener.Close()
-		_ = os.Remove(socketPath)
-	}, nil
+	return l, func() { _ = l.Close() }, nil
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/na4ma4/ssh-agent-mux/internal/notify"
+	"github.com/spf13/viper"
+)
+
+// Notifier types for the "notify.type" setting.
+const (
+	notifierDBus    = "dbus"
+	notifierCommand = "command"
+)
+
+// notifyConfig is the "notify" config file setting.
+type notifyConfig struct {
+	notify.DBusConfig `mapstructure:",squash"`
+
+	// Type is the notifier type, "dbus" (the default) or "command".
+	Type    string   `mapstructure:"type"`
+	Command []string `mapstructure:"command"`
+
+	ForwardedOnly bool          `mapstructure:"forwarded-only"`
+	Throttle      time.Duration `mapstructure:"throttle"`
+}
+
+// loadSignNotifier returns the mux option enabling sign notifications, or nil when notifications
+// are not configured.
+func loadSignNotifier() (muxagent.Option, error) {
+	if !viper.IsSet("notify") {
+		return nil, nil //nolint:nilnil // notifications not configured
+	}
+
+	var config notifyConfig
+	if err := viper.UnmarshalKey("notify", &config); err != nil {
+		return nil, fmt.Errorf("failed to parse notify config: %w", err)
+	}
+
+	var notifier notify.Notifier
+	switch config.Type {
+	case "", notifierDBus:
+		notifier = notify.NewDBusNotifier(config.DBusConfig)
+	case notifierCommand:
+		commandNotifier, err := notify.NewCommandNotifier(notify.CommandConfig{
+			Command: config.Command,
+			Timeout: config.Timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifier = commandNotifier
+	default:
+		return nil, fmt.Errorf("%w: unknown notify type %q", notify.ErrInvalidConfig, config.Type)
+	}
+
+	return muxagent.WithSignNotifier(notifier, muxagent.SignNotifyOptions{
+		ForwardedOnly: config.ForwardedOnly,
+		Throttle:      config.Throttle,
+	}), nil
+}
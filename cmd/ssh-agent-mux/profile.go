@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// loadProfile reads the config file for the selected profile and sets the profile defaults.
+//
+// Settings are resolved in the order flags, environment, profile config file, profile defaults.
+func loadProfile(_ *cobra.Command, _ []string) error {
+	profile := viper.GetString("profile")
+	if profile == "" {
+		profile = paths.DefaultProfile
+		viper.Set("profile", profile)
+	}
+
+	if err := paths.ValidateProfile(profile); err != nil {
+		return err
+	}
+
+	viper.SetDefault("socket", paths.DefaultSocketPath(profile))
+
+	viper.SetConfigName(profile)
+	viper.AddConfigPath(paths.ConfigDir())
+
+	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return fmt.Errorf("failed to read config for profile %s: %w", profile, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/spf13/viper"
+)
+
+// reloadConfig re-reads the config file of the profile and applies its backend agents and sign
+// policies to the running agent, the running config is kept when the config file is invalid.
+func reloadConfig(ctx context.Context, logger *slog.Logger, muxAgent *muxagent.MuxAgent) {
+	logger.InfoContext(ctx, "Reloading config", slog.String("config-file", viper.ConfigFileUsed()))
+
+	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			logger.ErrorContext(ctx, "Failed to reload config", slogtool.ErrorAttr(err))
+			return
+		}
+	}
+
+	var signPolicies []muxagent.SignPolicy
+	if err := viper.UnmarshalKey("sign-policies", &signPolicies); err != nil {
+		logger.ErrorContext(ctx, "Invalid sign policy config, keeping running config", slogtool.ErrorAttr(err))
+		return
+	}
+
+	muxAgent.Reload(viper.GetStringSlice("backend-agent"), signPolicies)
+}
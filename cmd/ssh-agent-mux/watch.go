@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Print the events of the running agent",
+	Long: `Print the events of the running ssh-agent-mux instance as they happen: keys added, removed
+or expired, sign requests served or denied, backend agents added, removed, up or down and config
+reloads.
+
+Events are streamed from the control socket, use --type to only print some event types.`,
+	Args: cobra.NoArgs,
+	RunE: watchCommand,
+}
+
+func watchCommand(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var logger *slog.Logger
+	{
+		var closer func()
+		logger, closer = getLogger()
+		defer closer()
+	}
+
+	types, err := muxagent.ParseEventTypes(viper.GetStringSlice("watch.types")...)
+	if err != nil {
+		return err
+	}
+
+	socket, err := newCommandClient(ctx, logger)
+	if err != nil {
+		return err
+	}
+
+	printEvent := printEventText
+	if viper.GetBool("watch.json") {
+		printEvent = printEventJSON
+	}
+
+	if err = socket.Watch(ctx, types, func(ev *api.Event) error {
+		return printEvent(os.Stdout, ev)
+	}); err != nil && ctx.Err() == nil {
+		logger.ErrorContext(ctx, "Watch command failed", slogtool.ErrorAttr(err))
+		return err
+	}
+
+	return nil
+}
+
+// printEventText prints an event as a line of text.
+func printEventText(w io.Writer, ev *api.Event) error {
+	fields := []string{
+		ev.GetTs().AsTime().Local().Format(time.RFC3339),
+		muxagent.EventName(ev.GetType()),
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"fingerprint", ev.GetKeyFingerprint()},
+		{"type", ev.GetKeyType()},
+		{"comment", ev.GetKeyComment()},
+		{"backend", ev.GetBackendSocketPath()},
+		{"source", ev.GetSource()},
+		{"frontend", ev.GetFrontend()},
+		{"reason", ev.GetReason()},
+	} {
+		if field.value != "" {
+			fields = append(fields, fmt.Sprintf("%s=%q", field.name, field.value))
+		}
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(fields, " "))
+
+	return err
+}
+
+// printEventJSON prints an event as a line of JSON.
+func printEventJSON(w io.Writer, ev *api.Event) error {
+	out, err := protojson.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(out))
+
+	return err
+}
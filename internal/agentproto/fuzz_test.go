@@ -0,0 +1,89 @@
+package agentproto_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
+	"golang.org/x/crypto/ssh"
+)
+
+// addEd25519Request returns an SSH_AGENTC_ADD_ID_CONSTRAINED request with a lifetime and a
+// confirmation constraint.
+func addEd25519Request(f *testing.F) []byte {
+	f.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		f.Fatalf("Failed to generate key: %v", err)
+	}
+
+	req := append([]byte{agentproto.MsgAddIDConstrained}, ssh.Marshal(struct {
+		Type    string
+		Pub     []byte
+		Priv    []byte
+		Comment string
+	}{ssh.KeyAlgoED25519, pub, priv, "fuzz"})...)
+
+	return append(req, 1, 0, 0, 0, 60, 2) //nolint:mnd // lifetime and confirm constraints
+}
+
+func FuzzReadMessage(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 1, agentproto.MsgRequestIdentities})
+	f.Add([]byte{0, 0, 0, 0})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 1})
+	f.Add([]byte{0, 0, 0, 5, 1})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := agentproto.ReadMessage(bytes.NewReader(data), agentproto.DefaultMaxMessageSize)
+		if err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err = agentproto.WriteMessage(&buf, msg); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+
+		if !bytes.HasPrefix(data, buf.Bytes()) {
+			t.Errorf("Message did not round trip: %x", data)
+		}
+	})
+}
+
+func FuzzServeMessage(f *testing.F) {
+	f.Add([]byte{agentproto.MsgRequestIdentities})
+	f.Add([]byte{agentproto.MsgSignRequest})
+	f.Add([]byte{agentproto.MsgRemoveIdentity, 0, 0, 0, 4, 0, 0, 0, 0})
+	f.Add(append([]byte{agentproto.MsgLock}, ssh.Marshal(struct{ Passphrase string }{"x"})...))
+	f.Add(append([]byte{agentproto.MsgExtension}, ssh.Marshal(struct{ Name string }{"query"})...))
+	f.Add(addEd25519Request(f))
+
+	f.Fuzz(func(t *testing.T, req []byte) {
+		server := agentproto.NewServer(newKeyring())
+
+		if reply := server.ServeMessage(req); len(reply) == 0 {
+			t.Errorf("Expected a reply to %x", req)
+		}
+	})
+}
+
+func FuzzParseAddedKey(f *testing.F) {
+	f.Add(addEd25519Request(f))
+	f.Add([]byte{agentproto.MsgAddIdentity})
+	f.Add(append([]byte{agentproto.MsgAddIdentity}, ssh.Marshal(struct{ Type string }{ssh.KeyAlgoRSA})...))
+	f.Add(append([]byte{agentproto.MsgAddIDConstrained}, ssh.Marshal(struct{ Type string }{ssh.CertAlgoSKED25519v01})...))
+
+	f.Fuzz(func(t *testing.T, req []byte) {
+		added, err := agentproto.ParseAddedKey(req)
+		if err != nil {
+			return
+		}
+
+		if _, ok := added.PrivateKey.(ssh.Signer); ok {
+			t.Errorf("Expected a private key, got a signer")
+		}
+	})
+}
@@ -0,0 +1,506 @@
+package agentproto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/securitykey"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Key constraints from draft-miller-ssh-agent.
+const (
+	constrainLifetime    = 1
+	constrainConfirm     = 2
+	constrainExtension   = 255
+	constrainExtensionV0 = 192
+)
+
+// Constraint extensions whose details are decoded, other extensions are rejected as their
+// length is unknown.
+const (
+	ExtensionSKProvider          = "sk-provider@openssh.com"
+	ExtensionRestrictDestination = "restrict-destination-v00@openssh.com"
+	ExtensionAssociatedCerts     = "associated-certs-v00@openssh.com"
+)
+
+var (
+	// ErrUnsupportedKeyType indicates an added key of a type the codec does not decode.
+	ErrUnsupportedKeyType = errors.New("agent: unsupported key type")
+	// ErrUnsupportedConstraint indicates an unknown key constraint.
+	ErrUnsupportedConstraint = errors.New("agent: unsupported key constraint")
+	// ErrKeyMismatch indicates that the private key does not match its public key or certificate.
+	ErrKeyMismatch = errors.New("agent: private key does not match public key")
+)
+
+//nolint:gochecknoglobals // lookup table
+var curves = map[string]elliptic.Curve{
+	"nistp256": elliptic.P256(),
+	"nistp384": elliptic.P384(),
+	"nistp521": elliptic.P521(),
+}
+
+type addKeyHeader struct {
+	Type string
+	Rest []byte `ssh:"rest"`
+}
+
+type addKeyTrailer struct {
+	Comment     string
+	Constraints []byte `ssh:"rest"`
+}
+
+// ParseAddedKey decodes an SSH_AGENTC_ADD_IDENTITY or SSH_AGENTC_ADD_ID_CONSTRAINED request.
+func ParseAddedKey(req []byte) (*agent.AddedKey, error) {
+	if len(req) == 0 || (req[0] != MsgAddIdentity && req[0] != MsgAddIDConstrained) {
+		return nil, ErrMalformedMessage
+	}
+
+	var header addKeyHeader
+	if err := ssh.Unmarshal(req[1:], &header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	added := &agent.AddedKey{}
+
+	var (
+		rest []byte
+		err  error
+	)
+	switch header.Type {
+	case ssh.KeyAlgoRSA:
+		rest, err = parseRSAKey(header.Rest, added)
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		rest, err = parseECDSAKey(header.Type, header.Rest, added)
+	case ssh.KeyAlgoED25519:
+		rest, err = parseEd25519Key(header.Type, header.Rest, added)
+	case ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256:
+		rest, err = parseSecurityKey(header.Type, header.Rest, added)
+	case ssh.CertAlgoRSAv01:
+		rest, err = parseRSACert(header.Type, header.Rest, added)
+	case ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01:
+		rest, err = parseECDSACert(header.Type, header.Rest, added)
+	case ssh.CertAlgoED25519v01:
+		rest, err = parseEd25519Cert(header.Type, header.Rest, added)
+	case ssh.CertAlgoSKED25519v01, ssh.CertAlgoSKECDSA256v01:
+		rest, err = parseSecurityKeyCert(header.Type, header.Rest, added)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedKeyType, header.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var trailer addKeyTrailer
+	if err = ssh.Unmarshal(rest, &trailer); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+	added.Comment = trailer.Comment
+
+	if req[0] == MsgAddIdentity && len(trailer.Constraints) > 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrMalformedMessage)
+	}
+
+	if err = ParseConstraints(trailer.Constraints, added); err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// ParseConstraints decodes key constraints into added.
+func ParseConstraints(constraints []byte, added *agent.AddedKey) error {
+	for len(constraints) > 0 {
+		switch constraints[0] {
+		case constrainLifetime:
+			var msg struct {
+				Lifetime uint32
+				Rest     []byte `ssh:"rest"`
+			}
+			if err := ssh.Unmarshal(constraints[1:], &msg); err != nil {
+				return fmt.Errorf("%w: lifetime constraint: %w", ErrMalformedMessage, err)
+			}
+			added.LifetimeSecs, constraints = msg.Lifetime, msg.Rest
+		case constrainConfirm:
+			added.ConfirmBeforeUse, constraints = true, constraints[1:]
+		case constrainExtension, constrainExtensionV0:
+			ext, rest, err := parseConstraintExtension(constraints[1:])
+			if err != nil {
+				return err
+			}
+			added.ConstraintExtensions, constraints = append(added.ConstraintExtensions, ext), rest
+		default:
+			return fmt.Errorf("%w: %d", ErrUnsupportedConstraint, constraints[0])
+		}
+	}
+
+	return nil
+}
+
+func parseConstraintExtension(data []byte) (agent.ConstraintExtension, []byte, error) {
+	var msg struct {
+		Name string
+		Rest []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &msg); err != nil {
+		return agent.ConstraintExtension{}, nil, fmt.Errorf("%w: constraint extension: %w", ErrMalformedMessage, err)
+	}
+
+	ext := agent.ConstraintExtension{ExtensionName: msg.Name}
+
+	switch msg.Name {
+	case ExtensionSKProvider, ExtensionRestrictDestination:
+		var details struct {
+			Details []byte
+			Rest    []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(msg.Rest, &details); err != nil {
+			return ext, nil, fmt.Errorf("%w: %s constraint: %w", ErrMalformedMessage, msg.Name, err)
+		}
+		ext.ExtensionDetails = details.Details
+
+		return ext, details.Rest, nil
+	case ExtensionAssociatedCerts:
+		if len(msg.Rest) == 0 {
+			return ext, nil, fmt.Errorf("%w: %s constraint", ErrMalformedMessage, msg.Name)
+		}
+		ext.ExtensionDetails = msg.Rest[:1]
+
+		return ext, msg.Rest[1:], nil
+	default:
+		return ext, nil, fmt.Errorf("%w: %s", ErrUnsupportedConstraint, msg.Name)
+	}
+}
+
+func parseRSAKey(data []byte, added *agent.AddedKey) ([]byte, error) {
+	var msg struct {
+		N, E, D, Iqmp, P, Q *big.Int
+		Rest                []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("%w: RSA key: %w", ErrMalformedMessage, err)
+	}
+
+	key, err := rsaPrivateKey(msg.N, msg.E, msg.D, msg.P, msg.Q)
+	if err != nil {
+		return nil, err
+	}
+	added.PrivateKey = key
+
+	return msg.Rest, nil
+}
+
+func parseRSACert(keyType string, data []byte, added *agent.AddedKey) ([]byte, error) {
+	var msg struct {
+		Cert          []byte
+		D, Iqmp, P, Q *big.Int
+		Rest          []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("%w: RSA certificate: %w", ErrMalformedMessage, err)
+	}
+
+	cert, err := parseCertificate(keyType, msg.Cert)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, ok := cryptoPublicKey(cert.Key).(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: certificate is not for an RSA key", ErrKeyMismatch)
+	}
+
+	key, err := rsaPrivateKey(pub.N, big.NewInt(int64(pub.E)), msg.D, msg.P, msg.Q)
+	if err != nil {
+		return nil, err
+	}
+	added.PrivateKey, added.Certificate = key, cert
+
+	return msg.Rest, nil
+}
+
+func rsaPrivateKey(n, e, d, p, q *big.Int) (*rsa.PrivateKey, error) {
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%w: RSA exponent out of range", ErrMalformedMessage)
+	}
+
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+		D:         d,
+		Primes:    []*big.Int{p, q},
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeyMismatch, err)
+	}
+	key.Precompute()
+
+	return key, nil
+}
+
+func parseECDSAKey(keyType string, data []byte, added *agent.AddedKey) ([]byte, error) {
+	var msg struct {
+		Curve string
+		Q     []byte
+		D     *big.Int
+		Rest  []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("%w: ECDSA key: %w", ErrMalformedMessage, err)
+	}
+
+	pubKey, err := ssh.ParsePublicKey(ssh.Marshal(struct {
+		Type  string
+		Curve string
+		Q     []byte
+	}{keyType, msg.Curve, msg.Q}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: ECDSA public key: %w", ErrMalformedMessage, err)
+	}
+
+	key, err := ecdsaPrivateKey(pubKey, msg.D)
+	if err != nil {
+		return nil, err
+	}
+	added.PrivateKey = key
+
+	return msg.Rest, nil
+}
+
+func parseECDSACert(keyType string, data []byte, added *agent.AddedKey) ([]byte, error) {
+	var msg struct {
+		Cert []byte
+		D    *big.Int
+		Rest []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("%w: ECDSA certificate: %w", ErrMalformedMessage, err)
+	}
+
+	cert, err := parseCertificate(keyType, msg.Cert)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsaPrivateKey(cert.Key, msg.D)
+	if err != nil {
+		return nil, err
+	}
+	added.PrivateKey, added.Certificate = key, cert
+
+	return msg.Rest, nil
+}
+
+// ecdsaPrivateKey returns the private key for pubKey with scalar d, checking that they match.
+func ecdsaPrivateKey(pubKey ssh.PublicKey, d *big.Int) (*ecdsa.PrivateKey, error) {
+	pub, ok := cryptoPublicKey(pubKey).(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an ECDSA key", ErrKeyMismatch)
+	}
+
+	key := &ecdsa.PrivateKey{PublicKey: *pub, D: d}
+
+	ecdhKey, err := key.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeyMismatch, err)
+	}
+
+	ecdhPub, err := pub.ECDH()
+	if err != nil || !ecdhKey.PublicKey().Equal(ecdhPub) {
+		return nil, ErrKeyMismatch
+	}
+
+	return key, nil
+}
+
+func parseEd25519Key(keyType string, data []byte, added *agent.AddedKey) ([]byte, error) {
+	var msg struct {
+		Pub  []byte
+		Priv []byte
+		Rest []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("%w: Ed25519 key: %w", ErrMalformedMessage, err)
+	}
+
+	pubKey, err := ssh.ParsePublicKey(ssh.Marshal(struct {
+		Type string
+		Pub  []byte
+	}{keyType, msg.Pub}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: Ed25519 public key: %w", ErrMalformedMessage, err)
+	}
+
+	key, err := ed25519PrivateKey(pubKey, msg.Priv)
+	if err != nil {
+		return nil, err
+	}
+	added.PrivateKey = key
+
+	return msg.Rest, nil
+}
+
+func parseEd25519Cert(keyType string, data []byte, added *agent.AddedKey) ([]byte, error) {
+	var msg struct {
+		Cert []byte
+		Pub  []byte
+		Priv []byte
+		Rest []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("%w: Ed25519 certificate: %w", ErrMalformedMessage, err)
+	}
+
+	cert, err := parseCertificate(keyType, msg.Cert)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ed25519PrivateKey(cert.Key, msg.Priv)
+	if err != nil {
+		return nil, err
+	}
+	added.PrivateKey, added.Certificate = key, cert
+
+	return msg.Rest, nil
+}
+
+// ed25519PrivateKey returns the private key for pubKey, checking that they match.
+func ed25519PrivateKey(pubKey ssh.PublicKey, priv []byte) (ed25519.PrivateKey, error) {
+	pub, ok := cryptoPublicKey(pubKey).(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an Ed25519 key", ErrKeyMismatch)
+	}
+
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: Ed25519 private key size %d", ErrMalformedMessage, len(priv))
+	}
+
+	key := ed25519.PrivateKey(priv)
+	if derived, _ := key.Public().(ed25519.PublicKey); !derived.Equal(pub) {
+		return nil, ErrKeyMismatch
+	}
+
+	return key, nil
+}
+
+type securityKeyFields struct {
+	Application string
+	Flags       byte
+	KeyHandle   []byte
+	Reserved    []byte
+	Rest        []byte `ssh:"rest"`
+}
+
+func parseSecurityKey(keyType string, data []byte, added *agent.AddedKey) ([]byte, error) {
+	var (
+		pubKeyBlob []byte
+		fields     []byte
+	)
+
+	if keyType == ssh.KeyAlgoSKED25519 {
+		var msg struct {
+			Pub  []byte
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(data, &msg); err != nil {
+			return nil, fmt.Errorf("%w: security key: %w", ErrMalformedMessage, err)
+		}
+		pubKeyBlob, fields = ssh.Marshal(struct {
+			Type string
+			Pub  []byte
+		}{keyType, msg.Pub}), msg.Rest
+	} else {
+		var msg struct {
+			Curve string
+			Q     []byte
+			Rest  []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(data, &msg); err != nil {
+			return nil, fmt.Errorf("%w: security key: %w", ErrMalformedMessage, err)
+		}
+		pubKeyBlob, fields = ssh.Marshal(struct {
+			Type  string
+			Curve string
+			Q     []byte
+		}{keyType, msg.Curve, msg.Q}), msg.Rest
+	}
+
+	var sk securityKeyFields
+	if err := ssh.Unmarshal(fields, &sk); err != nil {
+		return nil, fmt.Errorf("%w: security key: %w", ErrMalformedMessage, err)
+	}
+
+	// The application is part of the public key.
+	pubKey, err := ssh.ParsePublicKey(append(pubKeyBlob, ssh.Marshal(struct{ Application string }{sk.Application})...))
+	if err != nil {
+		return nil, fmt.Errorf("%w: security key public key: %w", ErrMalformedMessage, err)
+	}
+
+	added.PrivateKey = &securitykey.PrivateKey{
+		PublicKey:   pubKey,
+		Application: sk.Application,
+		Flags:       sk.Flags,
+		KeyHandle:   sk.KeyHandle,
+	}
+
+	return sk.Rest, nil
+}
+
+func parseSecurityKeyCert(keyType string, data []byte, added *agent.AddedKey) ([]byte, error) {
+	var msg struct {
+		Cert []byte
+		Rest []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("%w: security key certificate: %w", ErrMalformedMessage, err)
+	}
+
+	cert, err := parseCertificate(keyType, msg.Cert)
+	if err != nil {
+		return nil, err
+	}
+
+	var sk securityKeyFields
+	if err = ssh.Unmarshal(msg.Rest, &sk); err != nil {
+		return nil, fmt.Errorf("%w: security key certificate: %w", ErrMalformedMessage, err)
+	}
+
+	added.PrivateKey = &securitykey.PrivateKey{
+		PublicKey:   cert.Key,
+		Application: sk.Application,
+		Flags:       sk.Flags,
+		KeyHandle:   sk.KeyHandle,
+	}
+	added.Certificate = cert
+
+	return sk.Rest, nil
+}
+
+func parseCertificate(keyType string, blob []byte) (*ssh.Certificate, error) {
+	pubKey, err := ssh.ParsePublicKey(blob)
+	if err != nil {
+		return nil, fmt.Errorf("%w: certificate: %w", ErrMalformedMessage, err)
+	}
+
+	cert, ok := pubKey.(*ssh.Certificate)
+	if !ok || cert.Type() != keyType {
+		return nil, fmt.Errorf("%w: certificate type %s", ErrMalformedMessage, pubKey.Type())
+	}
+
+	return cert, nil
+}
+
+func cryptoPublicKey(pubKey ssh.PublicKey) any {
+	if cryptoPub, ok := pubKey.(ssh.CryptoPublicKey); ok {
+		return cryptoPub.CryptoPublicKey()
+	}
+
+	return nil
+}
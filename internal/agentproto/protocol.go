@@ -0,0 +1,85 @@
+// Package agentproto implements the SSH agent protocol (draft-miller-ssh-agent): the message
+// framing, the request and reply codecs and a server dispatching requests to an agent.
+package agentproto
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Message numbers from draft-miller-ssh-agent.
+const (
+	MsgFailure                    byte = 5
+	MsgSuccess                    byte = 6
+	MsgRequestIdentities          byte = 11
+	MsgIdentitiesAnswer           byte = 12
+	MsgSignRequest                byte = 13
+	MsgSignResponse               byte = 14
+	MsgAddIdentity                byte = 17
+	MsgRemoveIdentity             byte = 18
+	MsgRemoveAllIdentities        byte = 19
+	MsgAddSmartcardKey            byte = 20
+	MsgRemoveSmartcardKey         byte = 21
+	MsgLock                       byte = 22
+	MsgUnlock                     byte = 23
+	MsgAddIDConstrained           byte = 25
+	MsgAddSmartcardKeyConstrained byte = 26
+	MsgExtension                  byte = 27
+	MsgExtensionFailure           byte = 28
+)
+
+// DefaultMaxMessageSize is the largest message accepted by default, the same limit as ssh-agent.
+const DefaultMaxMessageSize = 256 * 1024
+
+var (
+	// ErrEmptyMessage indicates a message with a zero length.
+	ErrEmptyMessage = errors.New("agent: empty message")
+	// ErrMessageTooLarge indicates a message larger than the size limit.
+	ErrMessageTooLarge = errors.New("agent: message too large")
+	// ErrMalformedMessage indicates a message that could not be decoded.
+	ErrMalformedMessage = errors.New("agent: malformed message")
+)
+
+// ReadMessage reads a length prefixed message, messages larger than maxSize are rejected
+// before they are read.
+func ReadMessage(r io.Reader, maxSize uint32) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(length[:])
+	if size == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if size > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, size)
+	}
+
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// WriteMessage writes a length prefixed message in a single write.
+func WriteMessage(w io.Writer, msg []byte) error {
+	if len(msg) == 0 {
+		return ErrEmptyMessage
+	}
+	if uint64(len(msg)) > uint64(^uint32(0)) {
+		return fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, len(msg))
+	}
+
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(msg)), uint32(len(msg))) //nolint:gosec // checked above
+	_, err := w.Write(append(frame, msg...))
+
+	return err
+}
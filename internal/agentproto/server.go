@@ -0,0 +1,327 @@
+package agentproto
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/na4ma4/go-slogtool"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ErrUnsupportedMessage indicates a request type the server has no handler for.
+var ErrUnsupportedMessage = errors.New("agent: unsupported message")
+
+// Handler answers a request, req includes the message type and the returned reply is written
+// as is. A returned error is answered with SSH_AGENT_FAILURE.
+type Handler func(req []byte) ([]byte, error)
+
+// SmartcardAgent is an agent that can load keys from PKCS#11 providers (ssh-add -s and -e).
+type SmartcardAgent interface {
+	// AddSmartcardKey loads the keys of a PKCS#11 module, a non-zero lifetime unloads the
+	// module after that many seconds.
+	AddSmartcardKey(provider, pin string, lifetime uint32) error
+	// RemoveSmartcardKey unloads a PKCS#11 module.
+	RemoveSmartcardKey(provider string) error
+}
+
+// PassthroughAgent is an agent that answers requests the server does not recognise, for example
+// by forwarding them verbatim to another agent.
+type PassthroughAgent interface {
+	// Passthrough returns the raw reply to a request, req includes the message type.
+	Passthrough(req []byte) ([]byte, error)
+}
+
+// Server serves the agent protocol, dispatching each request to the handler for its message type.
+type Server struct {
+	agent    agent.ExtendedAgent
+	handlers map[byte]Handler
+	fallback Handler
+	maxSize  uint32
+	logger   *slog.Logger
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithMaxMessageSize sets the largest request accepted, replacing DefaultMaxMessageSize.
+func WithMaxMessageSize(size uint32) ServerOption {
+	return func(s *Server) {
+		s.maxSize = size
+	}
+}
+
+// WithLogger sets the logger that failed requests are logged to.
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// NewServer returns a server answering requests with a, the smartcard requests are only handled
+// when a implements SmartcardAgent and unrecognised requests are only answered when a implements
+// PassthroughAgent.
+func NewServer(a agent.ExtendedAgent, opts ...ServerOption) *Server {
+	s := &Server{
+		agent:   a,
+		maxSize: DefaultMaxMessageSize,
+		logger:  slog.New(slog.DiscardHandler),
+	}
+
+	s.handlers = map[byte]Handler{
+		MsgRequestIdentities:   s.handleRequestIdentities,
+		MsgSignRequest:         s.handleSignRequest,
+		MsgAddIdentity:         s.handleAddIdentity,
+		MsgAddIDConstrained:    s.handleAddIdentity,
+		MsgRemoveIdentity:      s.handleRemoveIdentity,
+		MsgRemoveAllIdentities: s.handleRemoveAllIdentities,
+		MsgLock:                s.handleLock,
+		MsgUnlock:              s.handleUnlock,
+		MsgExtension:           s.handleExtension,
+	}
+
+	if smartcard, ok := a.(SmartcardAgent); ok {
+		handleSmartcard := func(req []byte) ([]byte, error) { return handleSmartcardRequest(smartcard, req) }
+		s.handlers[MsgAddSmartcardKey] = handleSmartcard
+		s.handlers[MsgAddSmartcardKeyConstrained] = handleSmartcard
+		s.handlers[MsgRemoveSmartcardKey] = handleSmartcard
+	}
+
+	if passthrough, ok := a.(PassthroughAgent); ok {
+		s.fallback = passthrough.Passthrough
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Handle sets the handler for a message type, replacing the built-in handler. A nil handler
+// removes the handler so the message type is treated as unrecognised.
+func (s *Server) Handle(msgType byte, h Handler) {
+	if h == nil {
+		delete(s.handlers, msgType)
+		return
+	}
+
+	s.handlers[msgType] = h
+}
+
+// Serve answers requests read from c until reading fails, an oversized request ends the
+// connection like it does for ssh-agent.
+func (s *Server) Serve(c io.ReadWriter) error {
+	for {
+		req, err := ReadMessage(c, s.maxSize)
+		if err != nil {
+			return err
+		}
+
+		if err = WriteMessage(c, s.ServeMessage(req)); err != nil {
+			return err
+		}
+	}
+}
+
+// ServeMessage returns the reply to a single request.
+func (s *Server) ServeMessage(req []byte) []byte {
+	if len(req) == 0 {
+		return []byte{MsgFailure}
+	}
+
+	h, ok := s.handlers[req[0]]
+	if !ok {
+		h = s.fallback
+	}
+	if h == nil {
+		h = unsupported
+	}
+
+	reply, err := h(req)
+	if err != nil {
+		s.logger.Debug("Agent request failed",
+			slog.Int("message-type", int(req[0])),
+			slogtool.ErrorAttr(err),
+		)
+		return []byte{MsgFailure}
+	}
+
+	if len(reply) == 0 {
+		return []byte{MsgSuccess}
+	}
+
+	return reply
+}
+
+func unsupported([]byte) ([]byte, error) {
+	return nil, ErrUnsupportedMessage
+}
+
+func (s *Server) handleRequestIdentities(req []byte) ([]byte, error) {
+	if len(req) != 1 {
+		return nil, fmt.Errorf("%w: trailing data", ErrMalformedMessage)
+	}
+
+	keys, err := s.agent.List()
+	if err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, 0, 5) //nolint:mnd // message type and key count
+	reply = append(reply, MsgIdentitiesAnswer)
+	reply = append(reply, ssh.Marshal(struct{ Count uint32 }{uint32(len(keys))})...) //nolint:gosec // bounded by memory
+	for _, key := range keys {
+		reply = append(reply, ssh.Marshal(struct {
+			Blob    []byte
+			Comment string
+		}{key.Blob, key.Comment})...)
+	}
+
+	return reply, nil
+}
+
+func (s *Server) handleSignRequest(req []byte) ([]byte, error) {
+	var msg struct {
+		KeyBlob []byte
+		Data    []byte
+		Flags   uint32
+	}
+	if err := ssh.Unmarshal(req[1:], &msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	pubKey, err := ssh.ParsePublicKey(msg.KeyBlob)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	var sig *ssh.Signature
+	if msg.Flags == 0 {
+		sig, err = s.agent.Sign(pubKey, msg.Data)
+	} else {
+		sig, err = s.agent.SignWithFlags(pubKey, msg.Data, agent.SignatureFlags(msg.Flags))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{MsgSignResponse}, ssh.Marshal(struct{ Signature []byte }{ssh.Marshal(sig)})...), nil
+}
+
+func (s *Server) handleAddIdentity(req []byte) ([]byte, error) {
+	added, err := ParseAddedKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, s.agent.Add(*added)
+}
+
+func (s *Server) handleRemoveIdentity(req []byte) ([]byte, error) {
+	var msg struct{ KeyBlob []byte }
+	if err := ssh.Unmarshal(req[1:], &msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	var keyType struct {
+		Format string
+		Rest   []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(msg.KeyBlob, &keyType); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	return nil, s.agent.Remove(&agent.Key{Format: keyType.Format, Blob: msg.KeyBlob})
+}
+
+func (s *Server) handleRemoveAllIdentities(req []byte) ([]byte, error) {
+	if len(req) != 1 {
+		return nil, fmt.Errorf("%w: trailing data", ErrMalformedMessage)
+	}
+
+	return nil, s.agent.RemoveAll()
+}
+
+func (s *Server) handleLock(req []byte) ([]byte, error) {
+	var msg struct{ Passphrase []byte }
+	if err := ssh.Unmarshal(req[1:], &msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	return nil, s.agent.Lock(msg.Passphrase)
+}
+
+func (s *Server) handleUnlock(req []byte) ([]byte, error) {
+	var msg struct{ Passphrase []byte }
+	if err := ssh.Unmarshal(req[1:], &msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	return nil, s.agent.Unlock(msg.Passphrase)
+}
+
+// handleExtension answers an SSH_AGENTC_EXTENSION request. An unsupported extension is answered
+// with SSH_AGENT_FAILURE and a failed one with SSH_AGENT_EXTENSION_FAILURE, the reply of a
+// successful extension is written as is.
+func (s *Server) handleExtension(req []byte) ([]byte, error) {
+	var msg struct {
+		ExtensionType string
+		Contents      []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(req[1:], &msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	reply, err := s.agent.Extension(msg.ExtensionType, msg.Contents)
+	if errors.Is(err, agent.ErrExtensionUnsupported) {
+		return nil, err
+	}
+
+	if err != nil {
+		s.logger.Debug("Agent extension failed",
+			slog.String("extension", msg.ExtensionType),
+			slogtool.ErrorAttr(err),
+		)
+		return []byte{MsgExtensionFailure}, nil
+	}
+
+	return reply, nil
+}
+
+func handleSmartcardRequest(a SmartcardAgent, req []byte) ([]byte, error) {
+	if req[0] == MsgRemoveSmartcardKey {
+		var msg struct{ Provider, PIN string }
+		if err := ssh.Unmarshal(req[1:], &msg); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+		}
+
+		return nil, a.RemoveSmartcardKey(msg.Provider)
+	}
+
+	var msg struct {
+		Provider    string
+		PIN         string
+		Constraints []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(req[1:], &msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedMessage, err)
+	}
+
+	if req[0] == MsgAddSmartcardKey && len(msg.Constraints) > 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrMalformedMessage)
+	}
+
+	var constraints agent.AddedKey
+	if err := ParseConstraints(msg.Constraints, &constraints); err != nil {
+		return nil, err
+	}
+
+	if constraints.ConfirmBeforeUse || len(constraints.ConstraintExtensions) > 0 {
+		return nil, fmt.Errorf("%w: smartcard keys only support a lifetime", ErrUnsupportedConstraint)
+	}
+
+	return nil, a.AddSmartcardKey(msg.Provider, msg.PIN, constraints.LifetimeSecs)
+}
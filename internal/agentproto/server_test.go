@@ -0,0 +1,238 @@
+package agentproto_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// extensionAgent answers the "echo" extension with its contents and fails all others.
+type extensionAgent struct {
+	agent.ExtendedAgent
+}
+
+func (a extensionAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	switch extensionType {
+	case "echo":
+		return contents, nil
+	case "fail":
+		return nil, errors.New("extension failed")
+	default:
+		return nil, agent.ErrExtensionUnsupported
+	}
+}
+
+func newKeyring() agent.ExtendedAgent {
+	return agent.NewKeyring().(agent.ExtendedAgent) //nolint:forcetypeassert // the keyring is extended
+}
+
+// serve serves a over an in-memory connection, it returns the client end and the Serve result.
+func serve(t *testing.T, a agent.ExtendedAgent, opts ...agentproto.ServerOption) (net.Conn, <-chan error) {
+	t.Helper()
+
+	server, client := net.Pipe()
+	t.Cleanup(func() { _ = client.Close() })
+
+	served := make(chan error, 1)
+	go func() {
+		defer server.Close()
+		served <- agentproto.NewServer(a, opts...).Serve(server)
+	}()
+
+	return client, served
+}
+
+func testKeys(t *testing.T) []agent.AddedKey {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048) //nolint:mnd // test key size
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	_, certKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	caSigner, err := ssh.NewSignerFromKey(ed25519Key)
+	if err != nil {
+		t.Fatalf("Failed to create CA signer: %v", err)
+	}
+
+	certPub, err := ssh.NewPublicKey(certKey.Public())
+	if err != nil {
+		t.Fatalf("Failed to create public key: %v", err)
+	}
+
+	cert := &ssh.Certificate{
+		Key:             certPub,
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"user"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err = cert.SignCert(rand.Reader, caSigner); err != nil {
+		t.Fatalf("Failed to sign certificate: %v", err)
+	}
+
+	return []agent.AddedKey{
+		{PrivateKey: rsaKey, Comment: "rsa"},
+		{PrivateKey: ecdsaKey, Comment: "ecdsa", LifetimeSecs: 60},
+		{PrivateKey: ed25519Key, Comment: "ed25519"},
+		{PrivateKey: certKey, Certificate: cert, Comment: "cert"},
+	}
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	conn, _ := serve(t, newKeyring())
+	client := agent.NewClient(conn)
+
+	added := testKeys(t)
+	for _, key := range added {
+		if err := client.Add(key); err != nil {
+			t.Fatalf("Failed to add %s key: %v", key.Comment, err)
+		}
+	}
+
+	keys, err := client.List()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+
+	if len(keys) != len(added) {
+		t.Fatalf("Expected %d keys, got %d", len(added), len(keys))
+	}
+
+	data := []byte("data to sign")
+	for _, key := range keys {
+		sig, signErr := client.Sign(key, data)
+		if signErr != nil {
+			t.Fatalf("Failed to sign with %s key: %v", key.Comment, signErr)
+		}
+
+		if err = key.Verify(data, sig); err != nil {
+			t.Errorf("Signature of %s key did not verify: %v", key.Comment, err)
+		}
+	}
+
+	if sig, signErr := client.SignWithFlags(keys[0], data, agent.SignatureFlagRsaSha512); signErr != nil {
+		t.Errorf("Failed to sign with flags: %v", signErr)
+	} else if sig.Format != ssh.KeyAlgoRSASHA512 {
+		t.Errorf("Expected %s signature, got %s", ssh.KeyAlgoRSASHA512, sig.Format)
+	}
+
+	if err = client.Lock([]byte("secret")); err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+
+	if _, err = client.Sign(keys[0], data); err == nil {
+		t.Error("Expected signing to fail while locked")
+	}
+
+	if err = client.Unlock([]byte("wrong")); err == nil {
+		t.Error("Expected unlocking with the wrong passphrase to fail")
+	}
+
+	if err = client.Unlock([]byte("secret")); err != nil {
+		t.Fatalf("Failed to unlock: %v", err)
+	}
+
+	if err = client.Remove(keys[0]); err != nil {
+		t.Fatalf("Failed to remove key: %v", err)
+	}
+
+	if keys, _ = client.List(); len(keys) != len(added)-1 {
+		t.Errorf("Expected %d keys after removing one, got %d", len(added)-1, len(keys))
+	}
+
+	if err = client.RemoveAll(); err != nil {
+		t.Fatalf("Failed to remove all keys: %v", err)
+	}
+
+	if keys, _ = client.List(); len(keys) != 0 {
+		t.Errorf("Expected no keys, got %d", len(keys))
+	}
+}
+
+func TestServerExtensionReplies(t *testing.T) {
+	conn, _ := serve(t, extensionAgent{newKeyring()})
+	client := agent.NewClient(conn)
+
+	reply, err := client.Extension("echo", []byte{agentproto.MsgSuccess, 1, 2})
+	if err != nil {
+		t.Fatalf("Failed to call extension: %v", err)
+	}
+
+	if string(reply) != string([]byte{agentproto.MsgSuccess, 1, 2}) {
+		t.Errorf("Expected the reply to be written as is, got %v", reply)
+	}
+
+	if _, err = client.Extension("unknown@example.com", nil); !errors.Is(err, agent.ErrExtensionUnsupported) {
+		t.Errorf("Expected ErrExtensionUnsupported, got %v", err)
+	}
+
+	if _, err = client.Extension("fail", nil); err == nil || errors.Is(err, agent.ErrExtensionUnsupported) {
+		t.Errorf("Expected an extension failure, got %v", err)
+	}
+}
+
+func TestServerMessageReplies(t *testing.T) {
+	server := agentproto.NewServer(newKeyring())
+	addSmartcard := append([]byte{agentproto.MsgAddSmartcardKey}, ssh.Marshal(struct{ Provider, PIN string }{"p", ""})...)
+
+	for _, tc := range []struct {
+		name  string
+		req   []byte
+		reply byte
+	}{
+		{name: "unknown message", req: []byte{200}, reply: agentproto.MsgFailure},
+		{name: "truncated sign request", req: []byte{agentproto.MsgSignRequest, 0, 0}, reply: agentproto.MsgFailure},
+		{name: "trailing data", req: []byte{agentproto.MsgRemoveAllIdentities, 0}, reply: agentproto.MsgFailure},
+		{name: "smartcard unsupported", req: addSmartcard, reply: agentproto.MsgFailure},
+		{name: "remove all", req: []byte{agentproto.MsgRemoveAllIdentities}, reply: agentproto.MsgSuccess},
+		{name: "identities", req: []byte{agentproto.MsgRequestIdentities}, reply: agentproto.MsgIdentitiesAnswer},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if reply := server.ServeMessage(tc.req); reply[0] != tc.reply {
+				t.Errorf("Expected reply %d, got %d", tc.reply, reply[0])
+			}
+		})
+	}
+
+	server.Handle(200, func([]byte) ([]byte, error) { return []byte{201}, nil })
+	if reply := server.ServeMessage([]byte{200}); reply[0] != 201 {
+		t.Errorf("Expected the registered handler to answer, got %d", reply[0])
+	}
+}
+
+func TestServerRejectsOversizedMessages(t *testing.T) {
+	conn, served := serve(t, newKeyring(), agentproto.WithMaxMessageSize(16)) //nolint:mnd // small limit
+
+	if _, err := conn.Write(binary.BigEndian.AppendUint32(nil, 17)); err != nil { //nolint:mnd // over the limit
+		t.Fatalf("Failed to write request: %v", err)
+	}
+
+	if err := <-served; !errors.Is(err, agentproto.ErrMessageTooLarge) {
+		t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+	}
+}
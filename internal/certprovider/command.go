@@ -0,0 +1,70 @@
+package certprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/hookexec"
+	"golang.org/x/crypto/ssh"
+)
+
+const defaultCommandTimeout = 30 * time.Second
+
+// CommandConfig configures a command hook certificate provider.
+type CommandConfig struct {
+	// Command is the program and arguments to run, the public key is written to its stdin in
+	// authorized_keys format and the certificate is read from its stdout.
+	Command []string `mapstructure:"command"`
+	// Timeout limits each run, it defaults to 30s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// CommandProvider requests certificates by running a command, eg. an existing signing script.
+//
+// The command receives the key comment, fingerprint and current certificate (if any) in the
+// SSH_AGENT_MUX_KEY_COMMENT, SSH_AGENT_MUX_KEY_FINGERPRINT and SSH_AGENT_MUX_CERTIFICATE
+// environment variables.
+type CommandProvider struct {
+	config CommandConfig
+}
+
+// NewCommandProvider creates a command hook certificate provider.
+func NewCommandProvider(config CommandConfig) (*CommandProvider, error) {
+	if len(config.Command) == 0 {
+		return nil, fmt.Errorf("%w: command is required", ErrInvalidConfig)
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultCommandTimeout
+	}
+
+	return &CommandProvider{config: config}, nil
+}
+
+// IssueCertificate runs the command and parses the certificate it prints.
+func (p *CommandProvider) IssueCertificate(ctx context.Context, req Request) (*ssh.Certificate, error) {
+	env := []string{
+		"SSH_AGENT_MUX_KEY_COMMENT=" + req.Comment,
+		"SSH_AGENT_MUX_KEY_FINGERPRINT=" + ssh.FingerprintSHA256(req.PublicKey),
+	}
+
+	if req.Certificate != nil {
+		env = append(env,
+			"SSH_AGENT_MUX_CERTIFICATE="+strings.TrimSpace(string(ssh.MarshalAuthorizedKey(req.Certificate))),
+		)
+	}
+
+	stdout, err := hookexec.Command{
+		Args:    p.config.Command,
+		Stdin:   ssh.MarshalAuthorizedKey(req.PublicKey),
+		Env:     env,
+		Timeout: p.config.Timeout,
+	}.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("certificate command failed: %w", err)
+	}
+
+	return ParseCertificate(req, string(stdout))
+}
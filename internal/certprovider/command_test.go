@@ -0,0 +1,79 @@
+package certprovider_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/certprovider"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCommandProviderIssuesCertificate(t *testing.T) {
+	dir := t.TempDir()
+	pubKey := newTestKey(t)
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA signer: %v", err)
+	}
+
+	cert := &ssh.Certificate{Key: pubKey, CertType: ssh.UserCert, KeyId: "hook", ValidBefore: ssh.CertTimeInfinity}
+	if err = cert.SignCert(rand.Reader, caSigner); err != nil {
+		t.Fatalf("Failed to sign certificate: %v", err)
+	}
+
+	certPath := filepath.Join(dir, "cert.pub")
+	if err = os.WriteFile(certPath, ssh.MarshalAuthorizedKey(cert), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+
+	// The hook records the key it was given and prints the certificate, like a signing script.
+	script := `cat > "$0/key.pub" && echo "$SSH_AGENT_MUX_KEY_COMMENT" > "$0/comment" && cat "$0/cert.pub"`
+	provider, err := certprovider.NewCommandProvider(certprovider.CommandConfig{
+		Command: []string{"/bin/sh", "-c", script, dir},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	issued, err := provider.IssueCertificate(t.Context(), certprovider.Request{PublicKey: pubKey, Comment: "deploy"})
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+
+	if issued.KeyId != "hook" {
+		t.Errorf("Expected certificate from hook, got key id %q", issued.KeyId)
+	}
+
+	keyBytes, _ := os.ReadFile(filepath.Join(dir, "key.pub"))
+	if string(keyBytes) != string(ssh.MarshalAuthorizedKey(pubKey)) {
+		t.Errorf("Expected public key on stdin, got %q", keyBytes)
+	}
+
+	if comment, _ := os.ReadFile(filepath.Join(dir, "comment")); string(comment) != "deploy\n" {
+		t.Errorf("Expected key comment in environment, got %q", comment)
+	}
+
+	failing, err := certprovider.NewCommandProvider(certprovider.CommandConfig{Command: []string{"/bin/false"}})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	if _, err = failing.IssueCertificate(t.Context(), certprovider.Request{PublicKey: pubKey}); err == nil {
+		t.Error("Expected failing command to return an error")
+	}
+
+	_, err = certprovider.NewCommandProvider(certprovider.CommandConfig{})
+	if !errors.Is(err, certprovider.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig without command, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package certprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrInvalidConfig indicates that a provider configuration is incomplete.
+var ErrInvalidConfig = errors.New("invalid cert provider config")
+
+const (
+	defaultHTTPTimeout = 30 * time.Second
+	maxResponseSize    = 1 << 20
+)
+
+// HTTPConfig configures an HTTP certificate provider.
+type HTTPConfig struct {
+	// URL is the endpoint the public key is POSTed to.
+	URL string `mapstructure:"url"`
+	// Headers are added to every request.
+	Headers map[string]string `mapstructure:"headers"`
+	// TokenFile is read for every request and sent as a bearer token.
+	TokenFile string `mapstructure:"token-file"`
+	// Principals and Validity are passed to the endpoint, it may override them.
+	Principals []string      `mapstructure:"principals"`
+	Validity   time.Duration `mapstructure:"validity"`
+	// Timeout limits each request, it defaults to 30s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// HTTPRequest is the JSON body POSTed to the endpoint.
+type HTTPRequest struct {
+	PublicKey  string   `json:"public_key"`
+	KeyID      string   `json:"key_id,omitempty"`
+	Principals []string `json:"valid_principals,omitempty"`
+	TTL        string   `json:"ttl,omitempty"`
+}
+
+// HTTPResponse is the JSON response from the endpoint, the certificate is read from
+// "certificate", "signed_key" or "data.signed_key" (HashiCorp Vault).
+// A text/plain response is read as the certificate itself.
+type HTTPResponse struct {
+	Certificate string `json:"certificate"`
+	SignedKey   string `json:"signed_key"`
+	Data        struct {
+		SignedKey string `json:"signed_key"`
+	} `json:"data"`
+}
+
+// certificate returns the first certificate field that is set.
+func (r HTTPResponse) certificate() string {
+	for _, cert := range []string{r.Certificate, r.SignedKey, r.Data.SignedKey} {
+		if cert != "" {
+			return cert
+		}
+	}
+
+	return ""
+}
+
+// HTTPProvider requests certificates by POSTing public keys to an HTTP endpoint.
+type HTTPProvider struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+// NewHTTPProvider creates an HTTP certificate provider.
+func NewHTTPProvider(config HTTPConfig) (*HTTPProvider, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidConfig)
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	return &HTTPProvider{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// IssueCertificate POSTs the public key to the endpoint and parses the returned certificate.
+func (p *HTTPProvider) IssueCertificate(ctx context.Context, req Request) (*ssh.Certificate, error) {
+	body := HTTPRequest{
+		PublicKey:  strings.TrimSpace(string(ssh.MarshalAuthorizedKey(req.PublicKey))),
+		KeyID:      req.Comment,
+		Principals: p.config.Principals,
+	}
+	if p.config.Validity > 0 {
+		body.TTL = p.config.Validity.String()
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/plain")
+	for name, value := range p.config.Headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	if p.config.TokenFile != "" {
+		token, tokenErr := os.ReadFile(p.config.TokenFile)
+		if tokenErr != nil {
+			return nil, fmt.Errorf("failed to read token file: %w", tokenErr)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("certificate request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("certificate request failed: %s: %s", resp.Status, strings.TrimSpace(string(respBytes)))
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/json" {
+		return ParseCertificate(req, string(respBytes))
+	}
+
+	var certResp HTTPResponse
+	if err = json.Unmarshal(respBytes, &certResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return ParseCertificate(req, certResp.certificate())
+}
@@ -0,0 +1,163 @@
+package certprovider_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/certprovider"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	pubKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to convert public key: %v", err)
+	}
+
+	return pubKey
+}
+
+// newTestCA returns an HTTP handler that signs posted public keys, like a Vault SSH secrets engine.
+func newTestCA(t *testing.T, token string) http.HandlerFunc {
+	t.Helper()
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA signer: %v", err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+
+		var req certprovider.HTTPRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		cert := &ssh.Certificate{
+			Key:             pubKey,
+			CertType:        ssh.UserCert,
+			KeyId:           req.KeyID,
+			ValidPrincipals: req.Principals,
+			ValidBefore:     uint64(time.Now().Add(time.Hour).Unix()), //nolint:gosec // test value
+		}
+		if err = cert.SignCert(rand.Reader, caSigner); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]string{"signed_key": string(ssh.MarshalAuthorizedKey(cert))},
+		})
+	}
+}
+
+func TestHTTPProviderIssuesCertificate(t *testing.T) {
+	server := httptest.NewServer(newTestCA(t, "secret"))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	provider, err := certprovider.NewHTTPProvider(certprovider.HTTPConfig{
+		URL:        server.URL,
+		TokenFile:  tokenFile,
+		Principals: []string{"deploy"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	pubKey := newTestKey(t)
+	cert, err := provider.IssueCertificate(t.Context(), certprovider.Request{PublicKey: pubKey, Comment: "deploy"})
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+
+	if cert.KeyId != "deploy" || len(cert.ValidPrincipals) != 1 || cert.ValidPrincipals[0] != "deploy" {
+		t.Errorf("Expected certificate for deploy, got key id %q principals %v", cert.KeyId, cert.ValidPrincipals)
+	}
+
+	if string(cert.Key.Marshal()) != string(pubKey.Marshal()) {
+		t.Error("Expected certificate for the requested key")
+	}
+}
+
+func TestHTTPProviderErrors(t *testing.T) {
+	server := httptest.NewServer(newTestCA(t, "secret"))
+	defer server.Close()
+
+	if _, err := certprovider.NewHTTPProvider(certprovider.HTTPConfig{}); !errors.Is(err, certprovider.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig without url, got %v", err)
+	}
+
+	provider, err := certprovider.NewHTTPProvider(certprovider.HTTPConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	if _, err = provider.IssueCertificate(t.Context(), certprovider.Request{PublicKey: newTestKey(t)}); err == nil {
+		t.Error("Expected request without token to fail")
+	}
+
+	if _, err = certprovider.ParseCertificate(certprovider.Request{PublicKey: newTestKey(t)}, ""); !errors.Is(
+		err, certprovider.ErrNoCertificate,
+	) {
+		t.Errorf("Expected ErrNoCertificate for empty response, got %v", err)
+	}
+
+	// A certificate for another key must be rejected
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA signer: %v", err)
+	}
+
+	cert := &ssh.Certificate{Key: newTestKey(t), CertType: ssh.UserCert, ValidBefore: ssh.CertTimeInfinity}
+	if err = cert.SignCert(rand.Reader, caSigner); err != nil {
+		t.Fatalf("Failed to sign certificate: %v", err)
+	}
+
+	if _, err = certprovider.ParseCertificate(
+		certprovider.Request{PublicKey: newTestKey(t)}, string(ssh.MarshalAuthorizedKey(cert)),
+	); !errors.Is(err, certprovider.ErrCertificateMismatch) {
+		t.Errorf("Expected ErrCertificateMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Package certprovider obtains SSH certificates for the mux agent's local keys from external
+// certificate authorities.
+package certprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrNoCertificate indicates that a provider response did not contain a certificate.
+var ErrNoCertificate = errors.New("no certificate in provider response")
+
+// ErrCertificateMismatch indicates that a provider returned a certificate for a different key.
+var ErrCertificateMismatch = errors.New("certificate does not match requested key")
+
+// Request describes the key a certificate is requested for.
+type Request struct {
+	PublicKey ssh.PublicKey
+	Comment   string
+	// Certificate is the certificate being renewed, nil when the key has no certificate.
+	Certificate *ssh.Certificate
+}
+
+// Provider issues certificates for public keys.
+type Provider interface {
+	IssueCertificate(ctx context.Context, req Request) (*ssh.Certificate, error)
+}
+
+// ParseCertificate parses a certificate in authorized_keys format and checks it was issued for
+// the requested key.
+func ParseCertificate(req Request, certLine string) (*ssh.Certificate, error) {
+	certLine = strings.TrimSpace(certLine)
+	if certLine == "" {
+		return nil, ErrNoCertificate
+	}
+
+	pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(certLine))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	cert, ok := pubKey.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%w: got %s", ErrNoCertificate, pubKey.Type())
+	}
+
+	if string(cert.Key.Marshal()) != string(req.PublicKey.Marshal()) {
+		return nil, ErrCertificateMismatch
+	}
+
+	return cert, nil
+}
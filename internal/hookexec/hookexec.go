@@ -0,0 +1,60 @@
+// Package hookexec runs the user configured commands of ssh-agent-mux, such as certificate
+// provider, notification and event hooks.
+package hookexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrNoCommand indicates a hook without a program to run.
+var ErrNoCommand = errors.New("hook command is empty")
+
+// Command is a run of a hook command.
+type Command struct {
+	// Args are the program and its arguments.
+	Args []string
+	// Stdin is written to the command's stdin, nil leaves stdin empty.
+	Stdin []byte
+	// Env are extra KEY=value environment variables, added to the agent's environment.
+	Env []string
+	// Timeout limits the run, the command is killed once it passes. Zero means no limit beyond
+	// the context.
+	Timeout time.Duration
+}
+
+// Run runs the command and returns its stdout. A failed run returns an error holding the
+// command's stderr, as that is where hooks explain what went wrong.
+func (c Command) Run(ctx context.Context) ([]byte, error) {
+	if len(c.Args) == 0 {
+		return nil, ErrNoCommand
+	}
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...) //nolint:gosec // configured hook
+	if c.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(c.Stdin)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), c.Env...)
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
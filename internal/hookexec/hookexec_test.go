@@ -0,0 +1,43 @@
+package hookexec_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/hookexec"
+)
+
+func TestCommandRun(t *testing.T) {
+	out, err := hookexec.Command{
+		Args:  []string{"/bin/sh", "-c", `printf '%s|' "$HOOK_VALUE"; cat`},
+		Stdin: []byte("input"),
+		Env:   []string{"HOOK_VALUE=value"},
+	}.Run(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to run command: %v", err)
+	}
+
+	if string(out) != "value|input" {
+		t.Errorf("Expected the environment and stdin passed to the command, got %q", out)
+	}
+
+	_, err = hookexec.Command{Args: []string{"/bin/sh", "-c", "echo rejected >&2; exit 1"}}.Run(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("Expected the error to hold the command's stderr, got %v", err)
+	}
+
+	start := time.Now()
+	slow := hookexec.Command{Args: []string{"sleep", "5"}, Timeout: 50 * time.Millisecond}
+	if _, err = slow.Run(t.Context()); err == nil {
+		t.Error("Expected a command running past its timeout to fail")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Expected the command to be killed at its timeout, took %s", time.Since(start))
+	}
+
+	if _, err = (hookexec.Command{}).Run(t.Context()); !errors.Is(err, hookexec.ErrNoCommand) {
+		t.Errorf("Expected ErrNoCommand, got %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+package keystore
+
+// InLockedBuffer reports whether the key material was moved into locked memory.
+func (k *Key) InLockedBuffer() bool {
+	return k.buffer != nil
+}
@@ -0,0 +1,28 @@
+//go:build !unix
+
+package keystore
+
+// lockedBuffer falls back to the Go heap on platforms without mlock support.
+type lockedBuffer struct {
+	mem []byte
+}
+
+func newLockedBuffer(size int) *lockedBuffer {
+	return &lockedBuffer{mem: make([]byte, size)}
+}
+
+// Bytes returns the usable portion of the buffer.
+func (b *lockedBuffer) Bytes() []byte {
+	return b.mem
+}
+
+// Locked reports whether the buffer is locked into RAM.
+func (b *lockedBuffer) Locked() bool {
+	return false
+}
+
+// Destroy wipes the buffer, the buffer must not be used afterwards.
+func (b *lockedBuffer) Destroy() {
+	clear(b.mem)
+	b.mem = nil
+}
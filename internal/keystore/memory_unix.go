@@ -0,0 +1,123 @@
+//go:build unix
+
+package keystore
+
+import (
+	"os"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// pageLocks reference counts locked pages, buffers can share a page and munlock is not nested.
+//
+//nolint:gochecknoglobals // memory locks are process wide.
+var pageLocks = struct {
+	sync.Mutex
+
+	counts map[uintptr]int
+}{counts: make(map[uintptr]int)}
+
+// lockedBuffer is a heap allocation whose pages are locked into RAM where possible.
+//
+// The buffer is allocated by the Go runtime (rather than mmap) because the standard library
+// crypto packages cache derived keys using weak pointers, which only work for heap memory.
+type lockedBuffer struct {
+	mem    []byte
+	locked bool
+}
+
+func newLockedBuffer(size int) *lockedBuffer {
+	b := &lockedBuffer{mem: make([]byte, size)}
+	b.locked = b.lock()
+
+	return b
+}
+
+// Bytes returns the usable portion of the buffer.
+func (b *lockedBuffer) Bytes() []byte {
+	return b.mem
+}
+
+// Locked reports whether the buffer is locked into RAM.
+func (b *lockedBuffer) Locked() bool {
+	return b.locked
+}
+
+// Destroy wipes the buffer and unlocks any pages no longer used by other buffers,
+// the buffer must not be used afterwards.
+func (b *lockedBuffer) Destroy() {
+	if b.mem == nil {
+		return
+	}
+
+	clear(b.mem)
+
+	if b.locked {
+		b.unlock()
+	}
+
+	b.mem = nil
+}
+
+// pages returns the addresses of the pages spanned by the buffer.
+func (b *lockedBuffer) pages() []uintptr {
+	if len(b.mem) == 0 {
+		return nil
+	}
+
+	pageSize := uintptr(os.Getpagesize())
+	start := uintptr(unsafe.Pointer(unsafe.SliceData(b.mem)))
+	end := start + uintptr(len(b.mem))
+
+	var pages []uintptr
+	for page := start &^ (pageSize - 1); page < end; page += pageSize {
+		pages = append(pages, page)
+	}
+
+	return pages
+}
+
+func (b *lockedBuffer) lock() bool {
+	pages := b.pages()
+	if len(pages) == 0 {
+		return false
+	}
+
+	pageLocks.Lock()
+	defer pageLocks.Unlock()
+
+	if err := unix.Mlock(b.mem); err != nil {
+		return false
+	}
+
+	for _, page := range pages {
+		pageLocks.counts[page]++
+	}
+
+	return true
+}
+
+func (b *lockedBuffer) unlock() {
+	pageSize := uintptr(os.Getpagesize())
+	start := uintptr(unsafe.Pointer(unsafe.SliceData(b.mem)))
+	end := start + uintptr(len(b.mem))
+
+	pageLocks.Lock()
+	defer pageLocks.Unlock()
+
+	for _, page := range b.pages() {
+		pageLocks.counts[page]--
+		if pageLocks.counts[page] > 0 {
+			continue
+		}
+
+		delete(pageLocks.counts, page)
+
+		// Unlock the part of the buffer inside this page, munlock applies to the whole page.
+		from := max(page, start) - start
+		to := min(page+pageSize, end) - start
+		_ = unix.Munlock(b.mem[from:to])
+	}
+}
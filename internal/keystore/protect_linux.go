@@ -0,0 +1,23 @@
+//go:build linux
+
+package keystore
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// ProtectProcess disables core dumps and marks the process as non-dumpable, which also
+// prevents other processes of the same user from attaching with ptrace.
+func ProtectProcess() error {
+	if err := unix.Prctl(unix.PR_SET_DUMPABLE, 0, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set process non-dumpable: %w", err)
+	}
+
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{}); err != nil {
+		return fmt.Errorf("failed to disable core dumps: %w", err)
+	}
+
+	return nil
+}
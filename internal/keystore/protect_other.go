@@ -0,0 +1,8 @@
+//go:build !unix
+
+package keystore
+
+// ProtectProcess is a no-op on platforms without process hardening support.
+func ProtectProcess() error {
+	return nil
+}
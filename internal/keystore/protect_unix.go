@@ -0,0 +1,18 @@
+//go:build unix && !linux
+
+package keystore
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// ProtectProcess disables core dumps for the process.
+func ProtectProcess() error {
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{}); err != nil {
+		return fmt.Errorf("failed to disable core dumps: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,466 @@
+// Package keystore provides protected in-memory storage for private keys held by the mux agent.
+//
+// Ed25519 key material is copied into memory that is locked into RAM where the platform allows
+// it. RSA, ECDSA and DSA keys stay on the Go heap, their secrets are big.Int values the standard
+// library copies into its own precomputed state, so they can be swapped out. All key material is
+// wiped when a key is removed, expires or the store is closed.
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ErrKeyNotFound indicates that the requested key is not held by the store.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrNotSigner indicates that the private key does not implement crypto.Signer.
+var ErrNotSigner = errors.New("private key does not implement crypto.Signer")
+
+// sshPublicKeyer is a private key that is not a crypto.Signer but knows its SSH public key,
+// such as a security key whose signatures are made by an authenticator.
+type sshPublicKeyer interface {
+	SSHPublicKey() ssh.PublicKey
+}
+
+// ErrCertificateMismatch indicates that a certificate was issued for a different key.
+var ErrCertificateMismatch = errors.New("certificate does not match key")
+
+// ErrCertificateChanged indicates that the certificate of a key changed before it could be swapped.
+var ErrCertificateChanged = errors.New("certificate was changed concurrently")
+
+// Key is a private key held by the store.
+//
+// The PrivateKey field is only valid for the duration of the callback passed to Store.Use and
+// must not be retained, the memory backing it is wiped when the key is removed.
+type Key struct {
+	PrivateKey any
+	PublicKey  ssh.PublicKey
+	Comment    string
+	Expires    time.Time
+	Tags       []string
+	// Certificate is the certificate attached to the key, if any.
+	Certificate *ssh.Certificate
+
+	buffer *lockedBuffer
+}
+
+// KeyOption sets additional properties on a key when it is added.
+type KeyOption func(*Key)
+
+// WithTags labels a key, tags are used by frontends to decide which keys are visible.
+func WithTags(tags ...string) KeyOption {
+	return func(k *Key) {
+		k.Tags = append(k.Tags, tags...)
+	}
+}
+
+// Info is the public description of a stored key.
+type Info struct {
+	Key *agent.Key
+	// Certificate is the attached certificate, including expired certificates.
+	Certificate *ssh.Certificate
+	// CertificateKey is the listing entry for the attached certificate, nil when there is no
+	// certificate or it has expired.
+	CertificateKey *agent.Key
+	Tags           []string
+	Expires        time.Time
+}
+
+// Expired reports whether the key lifetime has passed at the given time.
+func (k *Key) Expired(now time.Time) bool {
+	return !k.Expires.IsZero() && !now.Before(k.Expires)
+}
+
+// Info returns the public description of the key, the certificate is omitted once it has expired.
+func (k *Key) Info(now time.Time) Info {
+	info := Info{
+		Key:         k.AgentKey(),
+		Certificate: k.Certificate,
+		Tags:        append([]string(nil), k.Tags...),
+		Expires:     k.Expires,
+	}
+
+	if k.Certificate != nil && CertificateValid(k.Certificate, now) {
+		info.CertificateKey = &agent.Key{
+			Format:  k.Certificate.Type(),
+			Blob:    k.Certificate.Marshal(),
+			Comment: k.Comment,
+		}
+	}
+
+	return info
+}
+
+// CertificateValid reports whether the certificate has not expired at the given time.
+func CertificateValid(cert *ssh.Certificate, now time.Time) bool {
+	if cert.ValidBefore == ssh.CertTimeInfinity {
+		return true
+	}
+
+	return uint64(now.Unix()) < cert.ValidBefore //nolint:gosec // unix time is positive
+}
+
+// checkCertificate verifies that the certificate was issued for the key.
+func checkCertificate(pubKey ssh.PublicKey, cert *ssh.Certificate) error {
+	if cert == nil {
+		return nil
+	}
+
+	if !bytes.Equal(cert.Key.Marshal(), pubKey.Marshal()) {
+		return ErrCertificateMismatch
+	}
+
+	return nil
+}
+
+// AgentKey returns the public representation of the key for agent listings.
+func (k *Key) AgentKey() *agent.Key {
+	return &agent.Key{
+		Format:  k.PublicKey.Type(),
+		Blob:    k.PublicKey.Marshal(),
+		Comment: k.Comment,
+	}
+}
+
+// protect moves the key material into locked memory where the key type allows it. Only ed25519
+// keys are a plain byte slice that can be moved, RSA, ECDSA and DSA keys are left on the Go heap
+// as the standard library keeps its own copies of their secrets that cannot be placed in locked
+// memory.
+func (k *Key) protect() {
+	switch privateKey := k.PrivateKey.(type) {
+	case ed25519.PrivateKey:
+		k.buffer = newLockedBuffer(len(privateKey))
+		copy(k.buffer.Bytes(), privateKey)
+		clear(privateKey)
+		k.PrivateKey = ed25519.PrivateKey(k.buffer.Bytes())
+	case *ed25519.PrivateKey:
+		k.buffer = newLockedBuffer(len(*privateKey))
+		copy(k.buffer.Bytes(), *privateKey)
+		clear(*privateKey)
+		k.PrivateKey = ed25519.PrivateKey(k.buffer.Bytes())
+	}
+}
+
+func (k *Key) wipe() {
+	wipePrivateKey(k.PrivateKey)
+	k.PrivateKey = nil
+
+	if k.buffer != nil {
+		k.buffer.Destroy()
+		k.buffer = nil
+	}
+}
+
+// Store holds private keys indexed by their public key blob.
+type Store struct {
+	mu   sync.RWMutex
+	keys map[string]*Key
+	now  func() time.Time
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithClock overrides the clock used to evaluate key lifetimes.
+func WithClock(now func() time.Time) Option {
+	return func(s *Store) {
+		s.now = now
+	}
+}
+
+// New creates an empty key store.
+func New(opts ...Option) *Store {
+	s := &Store{
+		keys: make(map[string]*Key),
+		now:  time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Add stores a private key, replacing any key with the same public key.
+//
+// The store takes ownership of the key material, ed25519 keys are moved into locked memory and
+// the original is wiped.
+func (s *Store) Add(added agent.AddedKey, opts ...KeyOption) (ssh.PublicKey, error) {
+	pubKey, err := addedPublicKey(added.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkCertificate(pubKey, added.Certificate); err != nil {
+		return nil, err
+	}
+
+	key := &Key{
+		PrivateKey:  added.PrivateKey,
+		PublicKey:   pubKey,
+		Comment:     added.Comment,
+		Certificate: added.Certificate,
+	}
+
+	if added.LifetimeSecs > 0 {
+		key.Expires = s.now().Add(time.Duration(added.LifetimeSecs) * time.Second)
+	}
+
+	for _, opt := range opts {
+		opt(key)
+	}
+
+	key.protect()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keyString := string(pubKey.Marshal())
+	if existing, ok := s.keys[keyString]; ok {
+		existing.wipe()
+	}
+
+	s.keys[keyString] = key
+
+	return pubKey, nil
+}
+
+// addedPublicKey returns the SSH public key of a private key being added.
+func addedPublicKey(privateKey any) (ssh.PublicKey, error) {
+	if keyer, ok := privateKey.(sshPublicKeyer); ok {
+		return keyer.SSHPublicKey(), nil
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, ErrNotSigner
+	}
+
+	pubKey, err := ssh.NewPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert public key: %w", err)
+	}
+
+	return pubKey, nil
+}
+
+// Use calls fn with the key matching the public key blob while holding the store read lock.
+// The blob may also be the key's attached certificate.
+// It returns ErrKeyNotFound if the key is not held or has expired.
+func (s *Store) Use(keyBlob []byte, fn func(*Key) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.lookup(keyBlob)
+	if !ok || key.Expired(s.now()) {
+		return ErrKeyNotFound
+	}
+
+	return fn(key)
+}
+
+// lookup finds the key matching a public key or certificate blob, the caller must hold the lock.
+func (s *Store) lookup(keyBlob []byte) (*Key, bool) {
+	if key, ok := s.keys[string(keyBlob)]; ok {
+		return key, true
+	}
+
+	pubKey, err := ssh.ParsePublicKey(keyBlob)
+	if err != nil {
+		return nil, false
+	}
+
+	cert, ok := pubKey.(*ssh.Certificate)
+	if !ok {
+		return nil, false
+	}
+
+	key, ok := s.keys[string(cert.Key.Marshal())]
+	if !ok || key.Certificate == nil || !bytes.Equal(key.Certificate.Marshal(), keyBlob) {
+		return nil, false
+	}
+
+	return key, true
+}
+
+// SetCertificate attaches a certificate to the key it was issued for, replacing any certificate
+// already attached. It returns ErrKeyNotFound if the key is not held.
+func (s *Store) SetCertificate(cert *ssh.Certificate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[string(cert.Key.Marshal())]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	key.Certificate = cert
+
+	return nil
+}
+
+// SwapCertificate attaches a certificate to the key it was issued for if the attached certificate
+// is still current, current is nil when the key had no certificate. It returns ErrKeyNotFound if
+// the key is not held and ErrCertificateChanged if the certificate was replaced or removed.
+func (s *Store) SwapCertificate(current, cert *ssh.Certificate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[string(cert.Key.Marshal())]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	switch {
+	case current == nil && key.Certificate == nil:
+	case current == nil || key.Certificate == nil, !bytes.Equal(current.Marshal(), key.Certificate.Marshal()):
+		return ErrCertificateChanged
+	}
+
+	key.Certificate = cert
+
+	return nil
+}
+
+// Contains reports whether a non-expired key matching the public key blob is held.
+func (s *Store) Contains(keyBlob []byte) bool {
+	return s.Use(keyBlob, func(*Key) error { return nil }) == nil
+}
+
+// List returns the public representation of all non-expired keys and their certificates.
+func (s *Store) List() []*agent.Key {
+	infos := s.ListInfo()
+	keys := make([]*agent.Key, 0, len(infos))
+	for _, info := range infos {
+		keys = append(keys, info.Key)
+		if info.CertificateKey != nil {
+			keys = append(keys, info.CertificateKey)
+		}
+	}
+
+	return keys
+}
+
+// ListInfo returns the public description of all non-expired keys.
+func (s *Store) ListInfo() []Info {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	infos := make([]Info, 0, len(s.keys))
+	for _, key := range s.keys {
+		if key.Expired(now) {
+			continue
+		}
+		infos = append(infos, key.Info(now))
+	}
+
+	return infos
+}
+
+// Lookup returns the public description of the non-expired key matching the public key blob,
+// the blob may also be the key's attached certificate.
+func (s *Store) Lookup(keyBlob []byte) (Info, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	key, ok := s.lookup(keyBlob)
+	if !ok || key.Expired(now) {
+		return Info{}, false
+	}
+
+	return key.Info(now), true
+}
+
+// PublicKeys returns the public keys of all non-expired keys.
+func (s *Store) PublicKeys() []ssh.PublicKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	keys := make([]ssh.PublicKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		if key.Expired(now) {
+			continue
+		}
+		keys = append(keys, key.PublicKey)
+	}
+
+	return keys
+}
+
+// Len returns the number of keys held, including expired keys that have not been removed yet.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.keys)
+}
+
+// Remove wipes and removes the key matching the public key blob.
+// It returns ErrKeyNotFound if the key is not held.
+func (s *Store) Remove(keyBlob []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[string(keyBlob)]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	key.wipe()
+	delete(s.keys, string(keyBlob))
+
+	return nil
+}
+
+// RemoveAll wipes and removes every key, returning the number of keys removed.
+func (s *Store) RemoveAll() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.keys)
+	for _, key := range s.keys {
+		key.wipe()
+	}
+
+	s.keys = make(map[string]*Key)
+
+	return count
+}
+
+// RemoveExpired wipes and removes keys whose lifetime has passed, returning the removed keys' public keys.
+func (s *Store) RemoveExpired() []ssh.PublicKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var expired []ssh.PublicKey
+	for keyString, key := range s.keys {
+		if !key.Expired(now) {
+			continue
+		}
+
+		expired = append(expired, key.PublicKey)
+		key.wipe()
+		delete(s.keys, keyString)
+	}
+
+	return expired
+}
+
+// Close wipes and removes every key.
+func (s *Store) Close() {
+	s.RemoveAll()
+}
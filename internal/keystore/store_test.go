@@ -0,0 +1,253 @@
+package keystore_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/keystore"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func addEd25519Key(t *testing.T, store *keystore.Store, lifetime uint32) (ssh.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	pubKey, err := store.Add(agent.AddedKey{PrivateKey: privateKey, Comment: "test-key", LifetimeSecs: lifetime})
+	if err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	return pubKey, privateKey
+}
+
+func TestAddWipesOriginalKey(t *testing.T) {
+	store := keystore.New()
+	defer store.Close()
+
+	pubKey, privateKey := addEd25519Key(t, store, 0)
+
+	for _, b := range privateKey {
+		if b != 0 {
+			t.Fatal("Expected original private key to be wiped after add")
+		}
+	}
+
+	if err := store.Use(pubKey.Marshal(), func(key *keystore.Key) error {
+		signer, err := ssh.NewSignerFromKey(key.PrivateKey)
+		if err != nil {
+			return err
+		}
+
+		sig, err := signer.Sign(rand.Reader, []byte("data"))
+		if err != nil {
+			return err
+		}
+
+		return pubKey.Verify([]byte("data"), sig)
+	}); err != nil {
+		t.Fatalf("Failed to sign with stored key: %v", err)
+	}
+}
+
+func TestRemovedKeyIsNotReachable(t *testing.T) {
+	store := keystore.New()
+	defer store.Close()
+
+	pubKey, _ := addEd25519Key(t, store, 0)
+
+	var stored ed25519.PrivateKey
+	if err := store.Use(pubKey.Marshal(), func(key *keystore.Key) error {
+		// Retained only to verify the backing memory is wiped, real callers must not do this.
+		stored, _ = key.PrivateKey.(ed25519.PrivateKey)
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to use key: %v", err)
+	}
+
+	if err := store.Remove(pubKey.Marshal()); err != nil {
+		t.Fatalf("Failed to remove key: %v", err)
+	}
+
+	if err := store.Use(pubKey.Marshal(), func(*keystore.Key) error { return nil }); !errors.Is(
+		err, keystore.ErrKeyNotFound,
+	) {
+		t.Errorf("Expected ErrKeyNotFound after removal, got %v", err)
+	}
+
+	if len(store.List()) != 0 {
+		t.Errorf("Expected no keys listed after removal, got %d", len(store.List()))
+	}
+
+	for _, b := range stored {
+		if b != 0 {
+			t.Fatal("Expected stored private key to be wiped after removal")
+		}
+	}
+
+	if err := store.Remove(pubKey.Marshal()); !errors.Is(err, keystore.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound removing twice, got %v", err)
+	}
+}
+
+func TestRemoveAllWipesScalars(t *testing.T) {
+	store := keystore.New()
+	defer store.Close()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if _, err = store.Add(agent.AddedKey{PrivateKey: privateKey}); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	addEd25519Key(t, store, 0)
+
+	if removed := store.RemoveAll(); removed != 2 {
+		t.Errorf("Expected 2 keys removed, got %d", removed)
+	}
+
+	//nolint:staticcheck // the private scalar is inspected to verify it was wiped.
+	if privateKey.D.Sign() != 0 {
+		t.Error("Expected ECDSA private scalar to be wiped")
+	}
+
+	if store.Len() != 0 {
+		t.Errorf("Expected empty store, got %d keys", store.Len())
+	}
+}
+
+func TestExpiredKeys(t *testing.T) {
+	now := time.Now()
+	store := keystore.New(keystore.WithClock(func() time.Time { return now }))
+	defer store.Close()
+
+	expiring, _ := addEd25519Key(t, store, 60)
+	permanent, _ := addEd25519Key(t, store, 0)
+
+	if len(store.List()) != 2 {
+		t.Fatalf("Expected 2 keys before expiry, got %d", len(store.List()))
+	}
+
+	now = now.Add(time.Minute)
+
+	if store.Contains(expiring.Marshal()) {
+		t.Error("Expected expired key to be unreachable")
+	}
+
+	if len(store.List()) != 1 {
+		t.Errorf("Expected 1 key listed after expiry, got %d", len(store.List()))
+	}
+
+	expired := store.RemoveExpired()
+	if len(expired) != 1 || string(expired[0].Marshal()) != string(expiring.Marshal()) {
+		t.Errorf("Expected expiring key to be removed, got %v", expired)
+	}
+
+	if !store.Contains(permanent.Marshal()) {
+		t.Error("Expected permanent key to remain")
+	}
+
+	if store.Len() != 1 {
+		t.Errorf("Expected 1 key held after removing expired keys, got %d", store.Len())
+	}
+}
+
+func TestSwapCertificate(t *testing.T) {
+	store := keystore.New()
+	defer store.Close()
+
+	pubKey, _ := addEd25519Key(t, store, 0)
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA signer: %v", err)
+	}
+
+	newCert := func(serial uint64) *ssh.Certificate {
+		cert := &ssh.Certificate{Key: pubKey, Serial: serial, CertType: ssh.UserCert, ValidBefore: ssh.CertTimeInfinity}
+		if signErr := cert.SignCert(rand.Reader, caSigner); signErr != nil {
+			t.Fatalf("Failed to sign certificate: %v", signErr)
+		}
+		return cert
+	}
+
+	first, second := newCert(1), newCert(2)
+
+	if err = store.SwapCertificate(nil, first); err != nil {
+		t.Fatalf("Failed to attach certificate: %v", err)
+	}
+
+	if err = store.SwapCertificate(nil, second); !errors.Is(err, keystore.ErrCertificateChanged) {
+		t.Errorf("Expected ErrCertificateChanged replacing a certificate that changed, got %v", err)
+	}
+
+	if err = store.SwapCertificate(first, second); err != nil {
+		t.Fatalf("Failed to swap certificate: %v", err)
+	}
+
+	if info, ok := store.Lookup(pubKey.Marshal()); !ok || info.Certificate.Serial != 2 {
+		t.Errorf("Expected the second certificate to be attached, got %v", info.Certificate)
+	}
+}
+
+func TestOnlyEd25519KeysAreMovedToLockedMemory(t *testing.T) {
+	store := keystore.New()
+	defer store.Close()
+
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ed25519 key: %v", err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate rsa key: %v", err)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ecdsa key: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		privateKey any
+		locked     bool
+	}{
+		{"ed25519", ed25519Key, true},
+		{"rsa", rsaKey, false},
+		{"ecdsa", ecdsaKey, false},
+	} {
+		pubKey, addErr := store.Add(agent.AddedKey{PrivateKey: tc.privateKey})
+		if addErr != nil {
+			t.Fatalf("Failed to add %s key: %v", tc.name, addErr)
+		}
+
+		if err = store.Use(pubKey.Marshal(), func(key *keystore.Key) error {
+			if key.InLockedBuffer() != tc.locked {
+				t.Errorf("Expected %s key in locked memory to be %t", tc.name, tc.locked)
+			}
+			return nil
+		}); err != nil {
+			t.Fatalf("Failed to use %s key: %v", tc.name, err)
+		}
+	}
+}
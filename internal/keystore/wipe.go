@@ -0,0 +1,45 @@
+package keystore
+
+import (
+	"crypto/dsa" //nolint:staticcheck // DSA keys are still accepted by agent.AddedKey.
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"math/big"
+)
+
+// wipePrivateKey overwrites the secret material of a private key in place.
+//
+// The standard library keeps its own precomputed copies of some key types, those copies
+// are outside of our control and are only released when the key is garbage collected.
+func wipePrivateKey(key any) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		clear(k)
+	case *ed25519.PrivateKey:
+		clear(*k)
+	case *rsa.PrivateKey:
+		wipeBigInt(k.D)
+		for _, prime := range k.Primes {
+			wipeBigInt(prime)
+		}
+		wipeBigInt(k.Precomputed.Dp)
+		wipeBigInt(k.Precomputed.Dq)
+		wipeBigInt(k.Precomputed.Qinv)
+	case *ecdsa.PrivateKey:
+		wipeBigInt(k.D)
+	case *dsa.PrivateKey:
+		wipeBigInt(k.X)
+	case interface{ Wipe() }:
+		k.Wipe()
+	}
+}
+
+func wipeBigInt(n *big.Int) {
+	if n == nil {
+		return
+	}
+
+	clear(n.Bits())
+	n.SetInt64(0)
+}
@@ -0,0 +1,125 @@
+// Package listener creates the network listeners that frontends of the mux agent are served on.
+package listener
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mdlayher/vsock"
+)
+
+// Listener types.
+const (
+	TypeUnix  = "unix"
+	TypeVsock = "vsock"
+	TypeTLS   = "tcp-tls"
+)
+
+// ErrUnknownType indicates that a listener type is not supported.
+var ErrUnknownType = errors.New("unknown listener type")
+
+// ErrInvalidConfig indicates that a listener configuration is incomplete or malformed.
+var ErrInvalidConfig = errors.New("invalid listener config")
+
+// TLSConfig holds the certificates for a mutual-TLS listener.
+type TLSConfig struct {
+	// Cert is the path to the PEM encoded server certificate.
+	Cert string `mapstructure:"cert"`
+	// Key is the path to the PEM encoded server private key.
+	Key string `mapstructure:"key"`
+	// ClientCA is the path to the PEM encoded CA bundle used to verify client certificates.
+	ClientCA string `mapstructure:"client-ca"`
+}
+
+// Listen creates a listener of the given type.
+//
+// For unix listeners the address is the socket path, for vsock listeners it is "port" or
+// "cid:port" and for tcp-tls listeners it is a TCP host:port address.
+func Listen(ctx context.Context, listenerType, address string, tlsConfig TLSConfig) (net.Listener, error) {
+	switch listenerType {
+	case TypeUnix:
+		return ListenUnix(ctx, address)
+	case TypeVsock:
+		return ListenVsock(address)
+	case TypeTLS:
+		return ListenTLS(ctx, address, tlsConfig)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, listenerType)
+	}
+}
+
+// ListenVsock creates an AF_VSOCK listener for agent access from virtual machines.
+func ListenVsock(address string) (net.Listener, error) {
+	cidString, portString, hasCID := strings.Cut(address, ":")
+	if !hasCID {
+		portString, cidString = cidString, ""
+	}
+
+	port, err := strconv.ParseUint(portString, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: vsock port %q: %w", ErrInvalidConfig, portString, err)
+	}
+
+	if cidString == "" {
+		return vsock.Listen(uint32(port), nil)
+	}
+
+	cid, err := strconv.ParseUint(cidString, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: vsock context id %q: %w", ErrInvalidConfig, cidString, err)
+	}
+
+	return vsock.ListenContextID(uint32(cid), uint32(port), nil)
+}
+
+// ListenTLS creates a TCP listener that requires clients to present a certificate signed by
+// the configured client CA.
+func ListenTLS(ctx context.Context, address string, tlsConfig TLSConfig) (net.Listener, error) {
+	config, err := ServerTLSConfig(tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := (&net.ListenConfig{}).Listen(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return tls.NewListener(inner, config), nil
+}
+
+// ServerTLSConfig builds a mutual-TLS server configuration.
+func ServerTLSConfig(tlsConfig TLSConfig) (*tls.Config, error) {
+	if tlsConfig.Cert == "" || tlsConfig.Key == "" || tlsConfig.ClientCA == "" {
+		return nil, fmt.Errorf("%w: tls cert, key and client-ca are required", ErrInvalidConfig)
+	}
+
+	cert, err := tls.LoadX509KeyPair(tlsConfig.Cert, tlsConfig.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls key pair: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(tlsConfig.ClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tls client-ca: %w", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%w: no certificates found in %s", ErrInvalidConfig, tlsConfig.ClientCA)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	}, nil
+}
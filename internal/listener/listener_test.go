@@ -0,0 +1,196 @@
+package listener_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/listener"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) writePEM(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certPath := filepath.Join(dir, name+".pem")
+	keyPath := filepath.Join(dir, name+".key")
+
+	if err = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err = os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	return certPath, keyPath
+}
+
+func TestListenTLSRequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil, true)
+	server := newTestCert(t, "localhost", ca, false)
+	client := newTestCert(t, "client", ca, false)
+
+	caPath, _ := ca.writePEM(t, dir, "ca")
+	certPath, keyPath := server.writePEM(t, dir, "server")
+
+	l, err := listener.Listen(t.Context(), listener.TypeTLS, "127.0.0.1:0", listener.TLSConfig{
+		Cert:     certPath,
+		Key:      keyPath,
+		ClientCA: caPath,
+	})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, acceptErr := l.Accept()
+			if acceptErr != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("ok"))
+			_ = conn.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	dial := func(certs []tls.Certificate) error {
+		conn, dialErr := tls.Dial("tcp", l.Addr().String(), &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: certs,
+			MinVersion:   tls.VersionTLS13,
+		})
+		if dialErr != nil {
+			return dialErr
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 2)
+		_, dialErr = conn.Read(buf)
+		return dialErr
+	}
+
+	if err = dial([]tls.Certificate{{Certificate: [][]byte{client.der}, PrivateKey: client.key}}); err != nil {
+		t.Errorf("Expected client with certificate to connect, got %v", err)
+	}
+
+	if err = dial(nil); err == nil {
+		t.Error("Expected client without certificate to be rejected")
+	}
+}
+
+func TestListenRejectsInvalidConfig(t *testing.T) {
+	if _, err := listener.Listen(t.Context(), "carrier-pigeon", "", listener.TLSConfig{}); !errors.Is(
+		err, listener.ErrUnknownType,
+	) {
+		t.Errorf("Expected ErrUnknownType, got %v", err)
+	}
+
+	if _, err := listener.Listen(t.Context(), listener.TypeTLS, "127.0.0.1:0", listener.TLSConfig{}); !errors.Is(
+		err, listener.ErrInvalidConfig,
+	) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+
+	if _, err := listener.Listen(t.Context(), listener.TypeVsock, "not-a-port", listener.TLSConfig{}); !errors.Is(
+		err, listener.ErrInvalidConfig,
+	) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestListenUnixReplacesStaleSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "sockets", "work.sock")
+
+	l, err := listener.Listen(t.Context(), listener.TypeUnix, socketPath, listener.TLSConfig{})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("Failed to stat socket: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected socket permissions 0600, got %s", info.Mode().Perm())
+	}
+
+	if _, err = listener.ListenUnix(t.Context(), socketPath); !errors.Is(err, listener.ErrAddressInUse) {
+		t.Errorf("Expected ErrAddressInUse for active socket, got %v", err)
+	}
+
+	// Leave the socket file behind to simulate an instance that did not clean up.
+	if ul, ok := l.(interface{ SetUnlinkOnClose(unlink bool) }); ok {
+		ul.SetUnlinkOnClose(false)
+	}
+	_ = l.Close()
+
+	if l, err = listener.ListenUnix(t.Context(), socketPath); err != nil {
+		t.Fatalf("Expected stale socket to be replaced, got %v", err)
+	}
+	_ = l.Close()
+}
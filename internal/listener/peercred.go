@@ -0,0 +1,59 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrNoPeerCredentials indicates that the peer of a connection cannot be identified, either
+// because it is not a unix socket connection or the platform does not provide the credentials.
+var ErrNoPeerCredentials = errors.New("peer credentials not available")
+
+// ErrNotUnixSocket indicates that a connection has no peer credentials as it is not a unix socket
+// connection, eg. a TLS or vsock connection. It is an ErrNoPeerCredentials.
+var ErrNotUnixSocket = fmt.Errorf("%w: not a unix socket connection", ErrNoPeerCredentials)
+
+// ErrNoProcessName indicates that the name of a process cannot be looked up.
+var ErrNoProcessName = errors.New("process name not available")
+
+// PeerCredentials identifies the process on the other end of a unix socket connection.
+type PeerCredentials struct {
+	UID uint32
+	// PID is the peer process id, zero when the platform does not provide it.
+	PID int
+}
+
+// PeerCredentialsOf returns the credentials of the peer of a unix socket connection.
+func PeerCredentialsOf(conn net.Conn) (PeerCredentials, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return PeerCredentials{}, ErrNotUnixSocket
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return PeerCredentials{}, err
+	}
+
+	var (
+		creds    PeerCredentials
+		credsErr error
+	)
+	if err = rawConn.Control(func(fd uintptr) {
+		creds, credsErr = peerCredentials(int(fd)) //nolint:gosec // file descriptors fit in an int
+	}); err != nil {
+		return PeerCredentials{}, err
+	}
+
+	return creds, credsErr
+}
+
+// ProcessName returns the short name of a process, eg. the peer process of a connection.
+func ProcessName(pid int) (string, error) {
+	if pid <= 0 {
+		return "", ErrNoProcessName
+	}
+
+	return processName(pid)
+}
@@ -0,0 +1,28 @@
+//go:build darwin
+
+package listener
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func peerCredentials(fd int) (PeerCredentials, error) {
+	xucred, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return PeerCredentials{}, err
+	}
+
+	// The peer pid is informational, the uid is enough to authorise the peer.
+	pid, _ := unix.GetsockoptInt(fd, unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+
+	return PeerCredentials{UID: xucred.Uid, PID: pid}, nil
+}
+
+func processName(pid int) (string, error) {
+	proc, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil {
+		return "", err
+	}
+
+	return unix.ByteSliceToString(proc.Proc.P_comm[:]), nil
+}
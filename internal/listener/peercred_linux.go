@@ -0,0 +1,29 @@
+//go:build linux
+
+package listener
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func peerCredentials(fd int) (PeerCredentials, error) {
+	ucred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return PeerCredentials{}, err
+	}
+
+	return PeerCredentials{UID: ucred.Uid, PID: int(ucred.Pid)}, nil
+}
+
+func processName(pid int) (string, error) {
+	comm, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/comm")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(comm)), nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package listener
+
+func peerCredentials(int) (PeerCredentials, error) {
+	return PeerCredentials{}, ErrNoPeerCredentials
+}
+
+func processName(int) (string, error) {
+	return "", ErrNoProcessName
+}
@@ -0,0 +1,67 @@
+//go:build linux || darwin
+
+package listener_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/listener"
+)
+
+func TestPeerCredentialsOf(t *testing.T) {
+	l, err := listener.ListenUnix(t.Context(), filepath.Join(t.TempDir(), "agent.sock"))
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	client, err := net.Dial("unix", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	defer conn.Close()
+
+	creds, err := listener.PeerCredentialsOf(conn)
+	if err != nil {
+		t.Fatalf("Failed to get peer credentials: %v", err)
+	}
+
+	if int(creds.UID) != os.Getuid() || creds.PID != os.Getpid() {
+		t.Errorf("Expected uid %d pid %d, got %+v", os.Getuid(), os.Getpid(), creds)
+	}
+
+	server, pipe := net.Pipe()
+	defer server.Close()
+	defer pipe.Close()
+
+	_, err = listener.PeerCredentialsOf(server)
+	if !errors.Is(err, listener.ErrNoPeerCredentials) || !errors.Is(err, listener.ErrNotUnixSocket) {
+		t.Errorf("Expected ErrNotUnixSocket for a pipe, got %v", err)
+	}
+}
+
+func TestProcessName(t *testing.T) {
+	name, err := listener.ProcessName(os.Getpid())
+	if err != nil {
+		t.Fatalf("Failed to get process name: %v", err)
+	}
+
+	if name == "" || !strings.HasPrefix(filepath.Base(os.Args[0]), name) {
+		t.Errorf("Expected the test binary name %q, got %q", filepath.Base(os.Args[0]), name)
+	}
+
+	if _, err = listener.ProcessName(0); !errors.Is(err, listener.ErrNoProcessName) {
+		t.Errorf("Expected ErrNoProcessName for pid 0, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/na4ma4/go-permbits"
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
+)
+
+// ErrAddressInUse indicates that another process is already serving on a unix socket path.
+var ErrAddressInUse = errors.New("socket is in use")
+
+const staleSocketDialTimeout = 500 * time.Millisecond
+
+// ListenUnix creates a unix socket listener only accessible by the current user, the socket is
+// removed when the listener is closed.
+//
+// The socket directory is created as a private directory if it does not exist, a stale socket
+// left behind by a previous instance is replaced.
+func ListenUnix(ctx context.Context, socketPath string) (net.Listener, error) {
+	if socketPath == "" {
+		return nil, fmt.Errorf("%w: unix socket path is required", ErrInvalidConfig)
+	}
+
+	if err := paths.EnsureDir(filepath.Dir(socketPath)); err != nil {
+		return nil, err
+	}
+
+	if err := removeStaleSocket(ctx, socketPath); err != nil {
+		return nil, err
+	}
+
+	// The socket is created in a private directory and only moved into place once its permissions
+	// are set, so it is never accessible to other users, even in a shared directory like /tmp.
+	tmpDir, err := os.MkdirTemp(filepath.Dir(socketPath), ".socket-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpPath := filepath.Join(tmpDir, filepath.Base(socketPath))
+
+	l, err := (&net.ListenConfig{}).Listen(ctx, "unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	unixListener, ok := l.(*net.UnixListener)
+	if !ok {
+		_ = l.Close()
+		return nil, fmt.Errorf("%w: unexpected listener type %T", ErrInvalidConfig, l)
+	}
+	unixListener.SetUnlinkOnClose(false)
+
+	if err = os.Chmod(tmpPath, permbits.MustString("u=rw,a=")); err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	if err = os.Rename(tmpPath, socketPath); err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("failed to move socket into place: %w", err)
+	}
+
+	return &unixSocketListener{UnixListener: unixListener, socketPath: socketPath, unlink: true}, nil
+}
+
+// unixSocketListener is a unix socket listener at the path ListenUnix moved its socket to.
+type unixSocketListener struct {
+	*net.UnixListener
+
+	socketPath string
+	mu         sync.Mutex
+	unlink     bool
+}
+
+// Addr returns the path the socket was moved to.
+func (l *unixSocketListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.socketPath, Net: "unix"}
+}
+
+// SetUnlinkOnClose sets whether the socket is removed when the listener is closed.
+func (l *unixSocketListener) SetUnlinkOnClose(unlink bool) {
+	l.mu.Lock()
+	l.unlink = unlink
+	l.mu.Unlock()
+}
+
+// Close stops listening and removes the socket unless SetUnlinkOnClose(false) was called.
+func (l *unixSocketListener) Close() error {
+	err := l.UnixListener.Close()
+
+	l.mu.Lock()
+	if l.unlink {
+		l.unlink = false
+		_ = os.Remove(l.socketPath)
+	}
+	l.mu.Unlock()
+
+	return err
+}
+
+// removeStaleSocket removes an existing socket at socketPath if nothing is listening on it.
+func removeStaleSocket(ctx context.Context, socketPath string) error {
+	info, err := os.Lstat(socketPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = paths.CheckOwner(socketPath, info); err != nil {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%w: %s exists and is not a socket", paths.ErrInsecurePath, socketPath)
+	}
+
+	dialer := &net.Dialer{Timeout: staleSocketDialTimeout}
+	if conn, dialErr := dialer.DialContext(ctx, "unix", socketPath); dialErr == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%w: %s", ErrAddressInUse, socketPath)
+	}
+
+	return os.Remove(socketPath)
+}
@@ -0,0 +1,202 @@
+package muxagent
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/api"
+)
+
+// backendCheckInterval is how often the backend agents are checked for going up or down.
+const backendCheckInterval = 10 * time.Second
+
+var (
+	// ErrBackendSocketPath indicates a backend agent without a socket path.
+	ErrBackendSocketPath = errors.New("backend agent socket path is empty")
+	// ErrBackendAction indicates an unknown ManageBackends action.
+	ErrBackendAction = errors.New("unknown backend action")
+)
+
+// backendSocketPaths returns the sockets of the current backend agents.
+func (m *MuxAgent) backendSocketPaths() []string {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+
+	return slices.Clone(m.config.GetBackendSocketPath())
+}
+
+// AddBackend adds a backend agent, it reports whether the backend was not already present.
+func (m *MuxAgent) AddBackend(socketPath string) (bool, error) {
+	if socketPath == "" {
+		return false, ErrBackendSocketPath
+	}
+
+	m.configMu.Lock()
+	backends := m.config.GetBackendSocketPath()
+	added := !slices.Contains(backends, socketPath)
+	if added {
+		m.config.SetBackendSocketPath(append(slices.Clone(backends), socketPath))
+	}
+	m.configMu.Unlock()
+
+	if added {
+		m.backendsChanged(api.EventType_EVENT_TYPE_BACKEND_ADDED, socketPath)
+	}
+
+	return added, nil
+}
+
+// RemoveBackend removes a backend agent, it reports whether the backend was present.
+func (m *MuxAgent) RemoveBackend(socketPath string) bool {
+	m.configMu.Lock()
+	backends := m.config.GetBackendSocketPath()
+	removed := slices.Contains(backends, socketPath)
+	if removed {
+		m.config.SetBackendSocketPath(slices.DeleteFunc(slices.Clone(backends), func(s string) bool {
+			return s == socketPath
+		}))
+	}
+	m.configMu.Unlock()
+
+	if removed {
+		m.backendHealth.forget(socketPath)
+		m.backendsChanged(api.EventType_EVENT_TYPE_BACKEND_REMOVED, socketPath)
+	}
+
+	return removed
+}
+
+// backendsChanged forgets the extension routes of the old backend agents and publishes the change.
+func (m *MuxAgent) backendsChanged(eventType api.EventType, socketPath string) {
+	m.logger.DebugContext(m.ctx, "Backend agents changed",
+		slog.String("event-type", eventType.String()),
+		slog.String("socket-path", socketPath),
+	)
+
+	m.extRoutes.mu.Lock()
+	m.extRoutes.routes = nil
+	m.extRoutes.mu.Unlock()
+
+	m.emitBackendEvent(eventType, socketPath, "")
+}
+
+// Reload replaces the backend agents and sign policies with a reloaded configuration, backend agents
+// that are still configured keep their state.
+func (m *MuxAgent) Reload(backends []string, policies []SignPolicy) {
+	for _, socketPath := range m.backendSocketPaths() {
+		if !slices.Contains(backends, socketPath) {
+			m.RemoveBackend(socketPath)
+		}
+	}
+
+	for _, socketPath := range backends {
+		if socketPath == "" {
+			continue
+		}
+
+		if _, err := m.AddBackend(socketPath); err != nil {
+			m.logger.WarnContext(m.ctx, "Failed to add backend agent on reload",
+				slog.String("socket-path", socketPath),
+				slogtool.ErrorAttr(err),
+			)
+		}
+	}
+
+	m.configMu.Lock()
+	m.signPolicies = slices.Clone(policies)
+	m.configMu.Unlock()
+
+	m.logger.InfoContext(m.ctx, "Configuration reloaded",
+		slog.Any("backend-socket-path", m.backendSocketPaths()),
+		slog.Int("sign-policy-count", len(policies)),
+	)
+
+	m.emitEvent(api.EventType_EVENT_TYPE_CONFIG_RELOADED)
+}
+
+// backendHealth tracks whether each backend agent accepted its last connection.
+type backendHealth struct {
+	mu sync.Mutex
+	up map[string]bool
+}
+
+// record stores the result of connecting to a backend agent and publishes the change when the
+// backend agent went up or down, results are ignored once the mux agent is stopping.
+func (h *backendHealth) record(m *MuxAgent, socketPath string, err error) {
+	if m.ctx.Err() != nil {
+		return
+	}
+
+	up := err == nil
+
+	h.mu.Lock()
+	if h.up == nil {
+		h.up = make(map[string]bool)
+	}
+	was, known := h.up[socketPath]
+	h.up[socketPath] = up
+	h.mu.Unlock()
+
+	if known && was == up {
+		return
+	}
+
+	if up {
+		m.logger.DebugContext(m.ctx, "Backend agent up", slog.String("socket-path", socketPath))
+		m.emitBackendEvent(api.EventType_EVENT_TYPE_BACKEND_UP, socketPath, "")
+
+		return
+	}
+
+	m.logger.DebugContext(m.ctx, "Backend agent down",
+		slog.String("socket-path", socketPath),
+		slogtool.ErrorAttr(err),
+	)
+	m.emitBackendEvent(api.EventType_EVENT_TYPE_BACKEND_DOWN, socketPath, err.Error())
+}
+
+// forget drops the state of a removed backend agent.
+func (h *backendHealth) forget(socketPath string) {
+	h.mu.Lock()
+	delete(h.up, socketPath)
+	h.mu.Unlock()
+}
+
+// monitorBackends periodically connects to the backend agents so that backend agents going up or
+// down are noticed without client requests.
+func (m *MuxAgent) monitorBackends() {
+	ticker := time.NewTicker(backendCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, socketPath := range m.backendSocketPaths() {
+				if _, fbClose, err := m.backendConnect(socketPath); err == nil {
+					fbClose()
+				}
+			}
+		}
+	}
+}
+
+// manageBackends applies a ManageBackends action.
+func (m *MuxAgent) manageBackends(action api.BackendAction, socketPath string) (bool, error) {
+	switch action {
+	case api.BackendAction_BACKEND_ACTION_LIST:
+		return false, nil
+	case api.BackendAction_BACKEND_ACTION_ADD:
+		return m.AddBackend(socketPath)
+	case api.BackendAction_BACKEND_ACTION_REMOVE:
+		return m.RemoveBackend(socketPath), nil
+	default:
+		return false, fmt.Errorf("%w: %s", ErrBackendAction, action)
+	}
+}
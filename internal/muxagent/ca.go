@@ -0,0 +1,287 @@
+package muxagent
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrNoCA indicates that the built-in certificate authority is not configured.
+var ErrNoCA = errors.New("certificate authority is not configured")
+
+// ErrNoMatchingKey indicates that no local key matches a key reference.
+var ErrNoMatchingKey = errors.New("no matching local key")
+
+// ErrAmbiguousKey indicates that more than one local key matches a key reference.
+var ErrAmbiguousKey = errors.New("more than one local key matches")
+
+// ErrInvalidValidity indicates that a requested certificate validity is not allowed.
+var ErrInvalidValidity = errors.New("invalid certificate validity")
+
+// ErrNoPrincipals indicates a certificate request without principals, OpenSSH accepts such a
+// certificate for every user or host.
+var ErrNoPrincipals = errors.New("certificate request has no principals")
+
+// certBackdate allows for clock skew between the mux and the servers checking certificates.
+const certBackdate = time.Minute
+
+// CAConfig configures the built-in SSH certificate authority.
+type CAConfig struct {
+	// KeyFile is an unencrypted private key file, the key is held in the protected key store.
+	KeyFile string `mapstructure:"key-file"`
+	// Fingerprint selects a CA key held by a backend agent (or added to the mux) by its SHA256
+	// fingerprint, used when KeyFile is not set.
+	Fingerprint string `mapstructure:"fingerprint"`
+	// MaxValidity limits the validity of issued certificates, zero means unlimited.
+	MaxValidity time.Duration `mapstructure:"max-validity"`
+	// Renew re-issues certificates from the built-in CA before they expire, it is ignored when a
+	// cert provider is configured.
+	Renew bool `mapstructure:"renew"`
+	// RenewBefore re-issues certificates this long before they expire.
+	RenewBefore time.Duration `mapstructure:"renew-before"`
+}
+
+// WithCertAuthority enables the built-in certificate authority.
+func WithCertAuthority(config CAConfig) Option {
+	return func(m *MuxAgent) {
+		m.caConfig = config
+	}
+}
+
+// loadCAKey loads the CA key file into the CA key store.
+func (m *MuxAgent) loadCAKey() error {
+	if m.caConfig.KeyFile == "" {
+		return nil
+	}
+
+	keyBytes, err := os.ReadFile(m.caConfig.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA key: %w", err)
+	}
+	defer clear(keyBytes)
+
+	privateKey, err := ssh.ParseRawPrivateKey(keyBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse CA key: %w", err)
+	}
+
+	pubKey, err := m.caKeys.Add(agent.AddedKey{PrivateKey: privateKey, Comment: "ca"})
+	if err != nil {
+		return fmt.Errorf("failed to store CA key: %w", err)
+	}
+
+	m.logger.DebugContext(m.ctx, "Loaded CA key",
+		slog.String("key-type", pubKey.Type()),
+		slog.String("key-fingerprint", ssh.FingerprintSHA256(pubKey)),
+	)
+
+	return nil
+}
+
+// caSigner returns the signer for the CA key.
+func (m *MuxAgent) caSigner() (ssh.Signer, error) {
+	if pubKeys := m.caKeys.PublicKeys(); len(pubKeys) > 0 {
+		return &localSigner{ctx: m.ctx, store: m.caKeys, pubKey: pubKeys[0]}, nil
+	}
+
+	if m.caConfig.Fingerprint == "" {
+		return nil, ErrNoCA
+	}
+
+	for _, sourcedKey := range m.listWithSource() {
+		if ssh.FingerprintSHA256(sourcedKey.Key) != m.caConfig.Fingerprint {
+			continue
+		}
+
+		pubKey, err := ssh.ParsePublicKey(sourcedKey.Key.Blob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA public key: %w", err)
+		}
+
+		return &muxSigner{mux: m, pubKey: pubKey}, nil
+	}
+
+	return nil, fmt.Errorf("%w: CA key %s is not available", ErrNoCA, m.caConfig.Fingerprint)
+}
+
+// findLocalKey returns the local key matching a SHA256 fingerprint or comment, an empty
+// reference matches the only local key.
+func (m *MuxAgent) findLocalKey(ref string) (*agent.Key, error) {
+	var matches []*agent.Key
+	for _, info := range m.localKeys.ListInfo() {
+		if ref == "" || ref == ssh.FingerprintSHA256(info.Key) || ref == info.Key.Comment {
+			matches = append(matches, info.Key)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %q", ErrNoMatchingKey, ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrAmbiguousKey, ref)
+	}
+}
+
+// handleCertIssue issues a certificate for a local key and attaches it to the stored key.
+func (m *MuxAgent) handleCertIssue(msg *api.CertIssueRequest) (*api.CertIssueResponse, error) {
+	m.logger.DebugContext(m.ctx, "handleCertIssue called",
+		slog.String("msg-id", msg.GetId()),
+		slog.String("key", msg.GetKey()),
+	)
+
+	localKey, err := m.findLocalKey(msg.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := ssh.ParsePublicKey(localKey.Blob)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := msg.GetKeyId()
+	if keyID == "" {
+		keyID = localKey.Comment
+	}
+
+	cert, err := m.issueCertificate(pubKey, CertRequest{
+		Principals: msg.GetPrincipals(),
+		Validity:   msg.GetValidity().AsDuration(),
+		KeyID:      keyID,
+		Host:       msg.GetHost(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return api.CertIssueResponse_builder{
+		Id:             proto.String(msg.GetId()),
+		Ts:             timestamppb.Now(),
+		Certificate:    proto.String(authorizedKey(cert, cert.KeyId)),
+		Serial:         proto.Uint64(cert.Serial),
+		ValidBefore:    timestamppb.New(time.Unix(int64(cert.ValidBefore), 0)), //nolint:gosec // set from time.Unix
+		KeyFingerprint: proto.String(ssh.FingerprintSHA256(pubKey)),
+		CaFingerprint:  proto.String(ssh.FingerprintSHA256(cert.SignatureKey)),
+	}.Build(), nil
+}
+
+// CertRequest describes a certificate to issue.
+type CertRequest struct {
+	Principals []string
+	Validity   time.Duration
+	KeyID      string
+	Host       bool
+}
+
+// issueCertificate signs a certificate for a local key with the CA key and attaches it.
+func (m *MuxAgent) issueCertificate(pubKey ssh.PublicKey, req CertRequest) (*ssh.Certificate, error) {
+	cert, err := m.signCertificate(pubKey, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.localKeys.SetCertificate(cert); err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+// signCertificate signs a certificate for a public key with the CA key.
+func (m *MuxAgent) signCertificate(pubKey ssh.PublicKey, req CertRequest) (*ssh.Certificate, error) {
+	if len(req.Principals) == 0 || slices.Contains(req.Principals, "") {
+		return nil, ErrNoPrincipals
+	}
+
+	if req.Validity <= 0 {
+		return nil, fmt.Errorf("%w: validity must be positive", ErrInvalidValidity)
+	}
+
+	if m.caConfig.MaxValidity > 0 && req.Validity > m.caConfig.MaxValidity {
+		return nil, fmt.Errorf("%w: %s exceeds maximum of %s", ErrInvalidValidity, req.Validity, m.caConfig.MaxValidity)
+	}
+
+	signer, err := m.caSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	var serial [8]byte
+	if _, err = io.ReadFull(rand.Reader, serial[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate serial: %w", err)
+	}
+
+	now := time.Now()
+	cert := &ssh.Certificate{
+		Key:             pubKey,
+		Serial:          binary.BigEndian.Uint64(serial[:]),
+		CertType:        ssh.UserCert,
+		KeyId:           req.KeyID,
+		ValidPrincipals: req.Principals,
+		ValidAfter:      uint64(now.Add(-certBackdate).Unix()), //nolint:gosec // unix time is positive
+		ValidBefore:     uint64(now.Add(req.Validity).Unix()),  //nolint:gosec // unix time is positive
+	}
+
+	if req.Host {
+		cert.CertType = ssh.HostCert
+	} else {
+		cert.Extensions = map[string]string{
+			"permit-X11-forwarding":   "",
+			"permit-agent-forwarding": "",
+			"permit-port-forwarding":  "",
+			"permit-pty":              "",
+			"permit-user-rc":          "",
+		}
+	}
+
+	if err = cert.SignCert(rand.Reader, signer); err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+
+	m.logger.InfoContext(m.ctx, "Issued certificate for local key",
+		slog.String("key-fingerprint", ssh.FingerprintSHA256(pubKey)),
+		slog.String("ca-fingerprint", ssh.FingerprintSHA256(signer.PublicKey())),
+		slog.Uint64("cert-serial", cert.Serial),
+		slog.String("cert-key-id", cert.KeyId),
+		slog.Any("cert-principals", cert.ValidPrincipals),
+		slog.Time("cert-valid-before", time.Unix(int64(cert.ValidBefore), 0)), //nolint:gosec // set from time.Unix
+	)
+
+	return cert, nil
+}
+
+// muxSigner signs through the mux agent, used for CA keys held by backend agents.
+type muxSigner struct {
+	mux    *MuxAgent
+	pubKey ssh.PublicKey
+}
+
+// PublicKey returns the public key of the signer.
+func (s *muxSigner) PublicKey() ssh.PublicKey {
+	return s.pubKey
+}
+
+// Sign signs data through the mux agent.
+func (s *muxSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.SignWithAlgorithm(rand, data, "")
+}
+
+// SignWithAlgorithm signs data through the mux agent using the given signature algorithm, the
+// signature is internal so it does not count toward the sign limits or notify.
+func (s *muxSigner) SignWithAlgorithm(_ io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	return s.mux.signFor(signCaller{internal: true}, s.pubKey, data, signatureFlags(algorithm))
+}
@@ -0,0 +1,215 @@
+package muxagent_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func writeCAKey(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, "ca")
+	if err != nil {
+		t.Fatalf("Failed to marshal CA key: %v", err)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "ca")
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("Failed to write CA key: %v", err)
+	}
+
+	caPubKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to convert CA public key: %v", err)
+	}
+
+	return keyPath, caPubKey
+}
+
+func TestCertIssueAttachesCertificate(t *testing.T) {
+	keyPath, caPubKey := writeCAKey(t)
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithCertAuthority(muxagent.CAConfig{KeyFile: keyPath, MaxValidity: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	pubKey := addTestKey(t, muxAgent, "deploy")
+
+	issue := func(validity time.Duration, principals ...string) (*api.CertIssueResponse, error) {
+		return muxagent.HandleExtensionProtoInvert[api.CertIssueRequest, api.CertIssueResponse](
+			api.CertIssueRequest_builder{
+				Id:         proto.String("test"),
+				Key:        proto.String("deploy"),
+				Principals: principals,
+				Validity:   durationpb.New(validity),
+			}.Build(),
+			func(inBytes []byte) ([]byte, error) {
+				return muxAgent.Extension("cert-issue", inBytes)
+			},
+		)
+	}
+
+	if _, err = issue(2*time.Hour, "deploy"); !errors.Is(err, muxagent.ErrInvalidValidity) {
+		t.Errorf("Expected validity above the maximum to be rejected, got %v", err)
+	}
+
+	if _, err = issue(15 * time.Minute); !errors.Is(err, muxagent.ErrNoPrincipals) {
+		t.Errorf("Expected a certificate without principals to be rejected, got %v", err)
+	}
+
+	resp, err := issue(15*time.Minute, "deploy")
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(resp.GetCertificate()))
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	cert, ok := parsed.(*ssh.Certificate)
+	if !ok {
+		t.Fatalf("Expected a certificate, got %T", parsed)
+	}
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(caPubKey.Marshal())
+		},
+	}
+	if err = checker.CheckCert("deploy", cert); err != nil {
+		t.Errorf("Certificate does not validate against the CA: %v", err)
+	}
+
+	if cert.KeyId != "deploy" {
+		t.Errorf("Expected key id to default to the key comment, got %q", cert.KeyId)
+	}
+
+	keys, err := muxAgent.List()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+
+	if len(keys) != 2 || string(keys[1].Blob) != string(cert.Marshal()) {
+		t.Fatalf("Expected key and certificate to be listed, got %v", keys)
+	}
+
+	sig, err := muxAgent.Sign(cert, []byte("data"))
+	if err != nil {
+		t.Fatalf("Failed to sign with certificate: %v", err)
+	}
+
+	if err = pubKey.Verify([]byte("data"), sig); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+}
+
+func TestCertIssueWithoutCA(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	addTestKey(t, muxAgent, "deploy")
+
+	if _, err = muxagent.HandleExtensionProtoInvert[api.CertIssueRequest, api.CertIssueResponse](
+		api.CertIssueRequest_builder{Principals: []string{"deploy"}, Validity: durationpb.New(time.Minute)}.Build(),
+		func(inBytes []byte) ([]byte, error) {
+			_, extErr := muxAgent.Extension("cert-issue", inBytes)
+			if !errors.Is(extErr, muxagent.ErrNoCA) {
+				t.Errorf("Expected ErrNoCA, got %v", extErr)
+			}
+			return nil, extErr
+		},
+	); err == nil {
+		t.Error("Expected certificate issue without CA to fail")
+	}
+}
+
+func TestCertIssueWithAgentHeldCASkipsSignLimits(t *testing.T) {
+	_, caPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+
+	caSigner, err := ssh.NewSignerFromKey(caPrivateKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA signer: %v", err)
+	}
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithCertAuthority(muxagent.CAConfig{
+			Fingerprint: ssh.FingerprintSHA256(caSigner.PublicKey()), MaxValidity: time.Hour,
+		}),
+		muxagent.WithSignLimits(muxagent.SignLimits{
+			PerKey: muxagent.SignRateLimit{PerMinute: 0.001, Burst: 1},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	if err = muxAgent.Add(agent.AddedKey{PrivateKey: caPrivateKey, Comment: "ca"}); err != nil {
+		t.Fatalf("Failed to add CA key: %v", err)
+	}
+	addTestKey(t, muxAgent, "deploy")
+
+	events, cancel := muxAgent.SubscribeEvents()
+	defer cancel()
+
+	// Issuing signs with the CA key more often than its limit allows.
+	for i := range 3 {
+		if _, err = muxagent.HandleExtensionProtoInvert[api.CertIssueRequest, api.CertIssueResponse](
+			api.CertIssueRequest_builder{
+				Id:         proto.String("test"),
+				Key:        proto.String("deploy"),
+				Principals: []string{"deploy"},
+				Validity:   durationpb.New(time.Minute),
+			}.Build(),
+			func(inBytes []byte) ([]byte, error) {
+				return muxAgent.Extension("cert-issue", inBytes)
+			},
+		); err != nil {
+			t.Fatalf("Certificate %d signed by the agent held CA failed: %v", i, err)
+		}
+	}
+
+	for {
+		select {
+		case ev := <-events:
+			if ev.GetType() == api.EventType_EVENT_TYPE_SIGN_SERVED || ev.GetType() == api.EventType_EVENT_TYPE_SIGN_DENIED {
+				t.Errorf("Expected CA signatures to publish no sign events, got %v", ev)
+			}
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
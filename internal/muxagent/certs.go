@@ -0,0 +1,295 @@
+package muxagent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/internal/certprovider"
+	"github.com/na4ma4/ssh-agent-mux/internal/keystore"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrStaleCertificate indicates that a provider returned a certificate that expires no later than
+// the one already attached.
+var ErrStaleCertificate = errors.New("certificate does not extend current certificate")
+
+const (
+	defaultCertRenewBefore = 5 * time.Minute
+	certRequestTimeout     = time.Minute
+	certRetryInterval      = 30 * time.Second
+	certRenewMaxSleep      = time.Minute
+)
+
+// CertProviderOptions selects the local keys that certificates are requested for.
+type CertProviderOptions struct {
+	// Keys selects the local keys that get certificates, an empty policy selects every local key.
+	Keys KeyPolicy
+	// RenewBefore requests a new certificate this long before the current one expires.
+	RenewBefore time.Duration
+	// RetryInterval is the minimum time between requests for a key, so a failing provider or one
+	// issuing certificates that expire within RenewBefore is not asked in a tight loop. It
+	// defaults to 30s.
+	RetryInterval time.Duration
+}
+
+// WithCertProvider requests certificates from the provider when local keys are added and renews
+// them before they expire.
+func WithCertProvider(provider certprovider.Provider, opts CertProviderOptions) Option {
+	return func(m *MuxAgent) {
+		if opts.RenewBefore <= 0 {
+			opts.RenewBefore = defaultCertRenewBefore
+		}
+
+		if opts.RetryInterval <= 0 {
+			opts.RetryInterval = certRetryInterval
+		}
+
+		m.certProvider = provider
+		m.certProviderOpts = opts
+	}
+}
+
+// certRequests tracks when certificates may next be requested for each key, so a key only has
+// one request in flight and requests for a key are at least the retry interval apart.
+type certRequests struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// start reports whether a request for the key may start now and blocks further requests until
+// the request finishes.
+func (r *certRequests) start(keyBlob []byte, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next == nil {
+		r.next = make(map[string]time.Time)
+	}
+
+	if next, ok := r.next[string(keyBlob)]; ok && now.Before(next) {
+		return false
+	}
+
+	// Entries of keys that were removed are dropped once they no longer block anything
+	maps.DeleteFunc(r.next, func(_ string, next time.Time) bool { return !now.Before(next) })
+
+	r.next[string(keyBlob)] = now.Add(certRequestTimeout)
+
+	return true
+}
+
+// finish blocks the next request for the key until the given time.
+func (r *certRequests) finish(keyBlob []byte, until time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next[string(keyBlob)] = until
+}
+
+// retryAt returns when a blocked request for the key may be retried.
+func (r *certRequests) retryAt(keyBlob []byte) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, ok := r.next[string(keyBlob)]
+
+	return next, ok
+}
+
+// certRenewAt returns when a certificate should be requested for a local key, ok is false when
+// the key does not get certificates from the provider.
+func (m *MuxAgent) certRenewAt(info keystore.Info) (time.Time, bool) {
+	if m.certProvider == nil {
+		return time.Time{}, false
+	}
+
+	if !m.certProviderOpts.Keys.Allows(SourcedKey{
+		Key:     info.Key,
+		Source:  KeySource{Local: true},
+		Tags:    info.Tags,
+		Expires: info.Expires,
+	}) {
+		return time.Time{}, false
+	}
+
+	if ca, ok := m.certProvider.(*caProvider); ok && !ca.issued(info.Certificate) {
+		return time.Time{}, false
+	}
+
+	if info.Certificate == nil {
+		return time.Time{}, true
+	}
+
+	if info.Certificate.ValidBefore == ssh.CertTimeInfinity {
+		return time.Time{}, false
+	}
+
+	validBefore := time.Unix(int64(info.Certificate.ValidBefore), 0) //nolint:gosec // set from time.Unix
+
+	return validBefore.Add(-m.certProviderOpts.RenewBefore), true
+}
+
+// wakeCertRenewer makes the certificate renewer re-check the local keys.
+func (m *MuxAgent) wakeCertRenewer() {
+	select {
+	case m.certWake <- struct{}{}:
+	default:
+	}
+}
+
+// renewCertificates requests certificates for local keys as they are added and before their
+// certificates expire, until the agent is shut down.
+func (m *MuxAgent) renewCertificates() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.certWake:
+		case <-timer.C:
+		}
+
+		next := m.requestCertificates(time.Now())
+
+		timer.Stop()
+		timer.Reset(time.Until(next))
+	}
+}
+
+// requestCertificates starts certificate requests for local keys that are due one and returns
+// when the renewer should next check.
+func (m *MuxAgent) requestCertificates(now time.Time) time.Time {
+	next := now.Add(certRenewMaxSleep)
+
+	for _, info := range m.localKeys.ListInfo() {
+		renewAt, ok := m.certRenewAt(info)
+		if !ok {
+			continue
+		}
+
+		if now.Before(renewAt) {
+			next = earliest(next, renewAt)
+			continue
+		}
+
+		if !m.requestCertificate(info) {
+			if retryAt, blocked := m.certRequests.retryAt(info.Key.Blob); blocked {
+				next = earliest(next, retryAt)
+			}
+		}
+	}
+
+	return next
+}
+
+// earliest returns the earlier of two times.
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+
+	return a
+}
+
+// requestCertificate obtains a certificate for a local key in the background and swaps it in,
+// it returns false if a request for the key is in flight or failed recently.
+func (m *MuxAgent) requestCertificate(info keystore.Info) bool {
+	pubKey, err := ssh.ParsePublicKey(info.Key.Blob)
+	if err != nil || !m.certRequests.start(info.Key.Blob, time.Now()) {
+		return false
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(m.ctx, certRequestTimeout)
+		defer cancel()
+
+		fingerprint := ssh.FingerprintSHA256(pubKey)
+		m.logger.DebugContext(ctx, "Requesting certificate from provider", slog.String("key-fingerprint", fingerprint))
+
+		cert, err := m.certProvider.IssueCertificate(ctx, certprovider.Request{
+			PublicKey:   pubKey,
+			Comment:     info.Key.Comment,
+			Certificate: info.Certificate,
+		})
+		if err == nil {
+			err = m.swapCertificate(info.Certificate, cert)
+		}
+
+		m.certRequests.finish(info.Key.Blob, time.Now().Add(m.certProviderOpts.RetryInterval))
+		m.wakeCertRenewer()
+
+		if err != nil {
+			m.logger.WarnContext(ctx, "Failed to obtain certificate from provider",
+				slog.String("key-fingerprint", fingerprint),
+				slogtool.ErrorAttr(err),
+			)
+			return
+		}
+
+		m.logger.InfoContext(ctx, "Attached certificate from provider",
+			slog.String("key-fingerprint", fingerprint),
+			slog.Bool("cert-renewed", info.Certificate != nil),
+			slog.Uint64("cert-serial", cert.Serial),
+			slog.Time("cert-valid-before", time.Unix(int64(cert.ValidBefore), 0)), //nolint:gosec // set from time.Unix
+		)
+	}()
+
+	return true
+}
+
+// swapCertificate replaces the certificate of a local key, the key itself is kept.
+// A certificate that does not outlive the current one is rejected so a slow provider cannot
+// replace a newer certificate with an older one, and the swap fails if the certificate was
+// changed while the new one was requested.
+func (m *MuxAgent) swapCertificate(current, cert *ssh.Certificate) error {
+	if current != nil && cert.ValidBefore != ssh.CertTimeInfinity && cert.ValidBefore <= current.ValidBefore {
+		return ErrStaleCertificate
+	}
+
+	return m.localKeys.SwapCertificate(current, cert)
+}
+
+// caProvider renews certificates issued by the built-in certificate authority, keeping their
+// principals, key id, type and validity period.
+type caProvider struct {
+	mux *MuxAgent
+}
+
+// issued reports whether a certificate was issued by the built-in certificate authority.
+func (p *caProvider) issued(cert *ssh.Certificate) bool {
+	if cert == nil {
+		return false
+	}
+
+	signer, err := p.mux.caSigner()
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(cert.SignatureKey.Marshal(), signer.PublicKey().Marshal())
+}
+
+// IssueCertificate re-issues the certificate being renewed.
+func (p *caProvider) IssueCertificate(_ context.Context, req certprovider.Request) (*ssh.Certificate, error) {
+	if !p.issued(req.Certificate) {
+		return nil, ErrNoCA
+	}
+
+	current := req.Certificate
+	period := time.Duration(current.ValidBefore-current.ValidAfter) * time.Second //nolint:gosec // fits in a duration
+
+	return p.mux.signCertificate(req.PublicKey, CertRequest{
+		Principals: current.ValidPrincipals,
+		Validity:   period - certBackdate,
+		KeyID:      current.KeyId,
+		Host:       current.CertType == ssh.HostCert,
+	})
+}
@@ -0,0 +1,154 @@
+package muxagent_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/internal/certprovider"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"golang.org/x/crypto/ssh"
+)
+
+// stubProvider issues certificates signed by an in-memory CA.
+type stubProvider struct {
+	ca       ssh.Signer
+	validity time.Duration
+	issued   atomic.Int32
+}
+
+func newStubProvider(t *testing.T, validity time.Duration) *stubProvider {
+	t.Helper()
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA signer: %v", err)
+	}
+
+	return &stubProvider{ca: caSigner, validity: validity}
+}
+
+// IssueCertificate issues certificates that each outlive the previous one by a second.
+func (p *stubProvider) IssueCertificate(_ context.Context, req certprovider.Request) (*ssh.Certificate, error) {
+	serial := p.issued.Add(1)
+	validBefore := time.Now().Add(p.validity + time.Duration(serial)*time.Second)
+	cert := &ssh.Certificate{
+		Key:         req.PublicKey,
+		Serial:      uint64(serial), //nolint:gosec // test serial
+		CertType:    ssh.UserCert,
+		KeyId:       req.Comment,
+		ValidBefore: uint64(validBefore.Unix()), //nolint:gosec // test value
+	}
+
+	return cert, cert.SignCert(rand.Reader, p.ca)
+}
+
+// waitForCertificate waits until the mux agent lists a certificate with at least the given serial.
+func waitForCertificate(t *testing.T, muxAgent *muxagent.MuxAgent, serial uint64) (*ssh.Certificate, int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		keys, err := muxAgent.List()
+		if err != nil {
+			t.Fatalf("Failed to list keys: %v", err)
+		}
+
+		for _, key := range keys {
+			parsed, parseErr := ssh.ParsePublicKey(key.Blob)
+			if parseErr != nil {
+				t.Fatalf("Failed to parse listed key: %v", parseErr)
+			}
+
+			if cert, ok := parsed.(*ssh.Certificate); ok && cert.Serial >= serial {
+				return cert, len(keys)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for certificate with serial %d", serial)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCertProviderCertifiesAddedKeys(t *testing.T) {
+	provider := newStubProvider(t, time.Hour)
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithCertProvider(provider, muxagent.CertProviderOptions{
+			Keys: muxagent.KeyPolicy{Comments: []string{"deploy"}},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	addTestKey(t, muxAgent, "personal")
+	pubKey := addTestKey(t, muxAgent, "deploy")
+
+	cert, keyCount := waitForCertificate(t, muxAgent, 1)
+	if string(cert.Key.Marshal()) != string(pubKey.Marshal()) {
+		t.Errorf("Expected certificate only for the deploy key, got %s", cert.KeyId)
+	}
+
+	if keyCount != 3 {
+		t.Errorf("Expected 3 listed entries (two keys and one certificate), got %d", keyCount)
+	}
+
+	if issued := provider.issued.Load(); issued != 1 {
+		t.Errorf("Expected one certificate to be issued, got %d", issued)
+	}
+}
+
+func TestCertRenewerSwapsCertificateBeforeExpiry(t *testing.T) {
+	// Certificates are always within RenewBefore of expiring, so each one is renewed as soon as the
+	// retry interval allows.
+	provider := newStubProvider(t, time.Hour)
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithCertProvider(provider, muxagent.CertProviderOptions{
+			RenewBefore:   2 * time.Hour,
+			RetryInterval: 50 * time.Millisecond,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	pubKey := addTestKey(t, muxAgent, "deploy")
+
+	cert, keyCount := waitForCertificate(t, muxAgent, 3)
+	if keyCount != 2 {
+		t.Errorf("Expected the key and only the renewed certificate to be listed, got %d entries", keyCount)
+	}
+
+	sig, err := muxAgent.Sign(cert, []byte("data"))
+	if err != nil {
+		t.Fatalf("Failed to sign with renewed certificate: %v", err)
+	}
+
+	if err = pubKey.Verify([]byte("data"), sig); err != nil {
+		t.Errorf("Expected the private key to be kept across renewals: %v", err)
+	}
+
+	// Without the retry interval the provider would be asked in a tight loop.
+	time.Sleep(200 * time.Millisecond)
+	if issued := provider.issued.Load(); issued > 10 {
+		t.Errorf("Expected renewals to be spaced by the retry interval, got %d certificates", issued)
+	}
+}
@@ -0,0 +1,54 @@
+package muxagent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// ErrConfirmationDenied indicates that the user did not approve use of a key.
+var ErrConfirmationDenied = errors.New("key use was not confirmed")
+
+// confirmTimeout limits how long a confirmation prompt waits for an answer.
+const confirmTimeout = time.Minute
+
+// Confirmer asks the user to approve use of a key.
+type Confirmer interface {
+	Confirm(ctx context.Context, prompt string) (bool, error)
+}
+
+// AskpassConfirmer asks for confirmation with an ssh-askpass program, the same way ssh-agent
+// does for keys added with "ssh-add -c".
+type AskpassConfirmer struct {
+	// Program is the askpass program, it defaults to $SSH_ASKPASS or "ssh-askpass".
+	Program string
+}
+
+// Confirm runs the askpass program in confirm mode, an exit status of zero approves the request.
+func (c AskpassConfirmer) Confirm(ctx context.Context, prompt string) (bool, error) {
+	program := c.Program
+	if program == "" {
+		program = os.Getenv("SSH_ASKPASS")
+	}
+	if program == "" {
+		program = "ssh-askpass"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, program, prompt)
+	cmd.Env = append(os.Environ(), "SSH_ASKPASS_PROMPT=confirm")
+
+	err := cmd.Run()
+	if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,106 @@
+package muxagent
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// ConnTracker tracks active client connections so they can be drained or terminated during shutdown.
+type ConnTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	// idle is closed whenever no connections are active.
+	idle chan struct{}
+}
+
+// NewConnTracker creates an empty connection tracker.
+func NewConnTracker() *ConnTracker {
+	idle := make(chan struct{})
+	close(idle)
+
+	return &ConnTracker{
+		conns: make(map[net.Conn]struct{}),
+		idle:  idle,
+	}
+}
+
+// Add registers a connection as active, it must be paired with a call to Done.
+func (t *ConnTracker) Add(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.conns[conn]; ok {
+		return
+	}
+
+	if len(t.conns) == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.conns[conn] = struct{}{}
+}
+
+// Done marks a connection as finished.
+func (t *ConnTracker) Done(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.conns[conn]; !ok {
+		return
+	}
+
+	delete(t.conns, conn)
+	if len(t.conns) == 0 {
+		close(t.idle)
+	}
+}
+
+// Len returns the number of active connections.
+func (t *ConnTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.conns)
+}
+
+// Interrupt expires the read deadline of every active connection, any request that is
+// currently being processed is still answered but no further requests are read.
+// It returns the number of connections interrupted.
+func (t *ConnTracker) Interrupt() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for conn := range t.conns {
+		_ = conn.SetReadDeadline(now)
+	}
+
+	return len(t.conns)
+}
+
+// CloseAll closes every active connection and returns the number of connections closed.
+func (t *ConnTracker) CloseAll() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for conn := range t.conns {
+		_ = conn.Close()
+	}
+
+	return len(t.conns)
+}
+
+// Wait blocks until all active connections are done or the context is cancelled.
+func (t *ConnTracker) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
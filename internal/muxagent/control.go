@@ -0,0 +1,318 @@
+package muxagent
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/na4ma4/go-permbits"
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
+	"github.com/na4ma4/ssh-agent-mux/internal/listener"
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ExtensionAuth authenticates a connection with the control token, granting it admin access.
+const ExtensionAuth = "auth@" + ExtensionDomain
+
+// ControlClass is the permission class needed to call a control extension.
+type ControlClass int
+
+// Control permission classes.
+const (
+	// ControlPublic extensions, such as query, can be called by any client.
+	ControlPublic ControlClass = iota
+	// ControlReadOnly extensions report on the mux agent (ping, config).
+	ControlReadOnly
+	// ControlAdmin extensions change the mux agent (shutdown, keygen, cert-issue), they need the
+	// control token when one is configured.
+	ControlAdmin
+)
+
+// String returns the name of the permission class.
+func (c ControlClass) String() string {
+	switch c {
+	case ControlPublic:
+		return "public"
+	case ControlReadOnly:
+		return "read-only"
+	case ControlAdmin:
+		return "admin"
+	default:
+		return fmt.Sprintf("class-%d", int(c))
+	}
+}
+
+// ErrControlDenied indicates that a client is not allowed to call a control extension.
+var ErrControlDenied = errors.New("control extension denied")
+
+// ControlPolicy decides which clients may call control extensions.
+type ControlPolicy struct {
+	// AllowedUIDs are the peer user ids allowed to call control extensions, the user running
+	// the mux agent when empty. Peers that cannot be identified (TLS and vsock listeners) are
+	// not checked.
+	AllowedUIDs []uint32 `mapstructure:"allowed-uids"`
+	// TokenFile is where the control token is written, admin extensions need connections to
+	// authenticate with the token when it is set.
+	TokenFile string `mapstructure:"token-file"`
+	// Token is the control token, it is generated when TokenFile is set.
+	Token string `mapstructure:"-"`
+	// SocketPath is the dedicated control socket serving the control service over gRPC, when it
+	// is set admin extensions are not served on the agent sockets.
+	SocketPath string `mapstructure:"socket"`
+}
+
+// allowsUID reports whether a peer user id may call control extensions.
+func (p ControlPolicy) allowsUID(uid uint32) bool {
+	if len(p.AllowedUIDs) == 0 {
+		return uint64(uid) == uint64(os.Getuid()) //nolint:gosec // uids are not negative
+	}
+
+	return slices.Contains(p.AllowedUIDs, uid)
+}
+
+// controlTokenSize is the number of random bytes in a control token.
+const controlTokenSize = 32
+
+// WriteControlToken generates a control token and writes it to path, readable only by the
+// current user. An existing token file is replaced.
+func WriteControlToken(path string) (string, error) {
+	if err := paths.EnsureDir(filepath.Dir(path)); err != nil {
+		return "", err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to remove old control token: %w", err)
+	}
+
+	token := make([]byte, controlTokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate control token: %w", err)
+	}
+	encoded := hex.EncodeToString(token)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, permbits.MustString("u=rw,a="))
+	if err != nil {
+		return "", fmt.Errorf("failed to create control token file: %w", err)
+	}
+
+	if _, err = f.WriteString(encoded + "\n"); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write control token: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write control token: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// WithControlPolicy sets the policy for control extensions.
+func WithControlPolicy(policy ControlPolicy) Option {
+	return func(m *MuxAgent) {
+		m.controlPolicy = policy
+	}
+}
+
+// ErrPeerUnidentified indicates a unix socket connection whose peer credentials cannot be read.
+var ErrPeerUnidentified = errors.New("peer credentials of unix socket connection not available")
+
+// Peer identifies the process on the other end of a client connection.
+type Peer struct {
+	UID uint32
+	PID int
+}
+
+// IdentifyPeer returns the peer of a connection from its unix socket credentials. Connections that
+// are not unix sockets, eg. TLS and vsock, have no peer and rely on their frontend allowing control,
+// for them the peer and error are both nil. A unix socket connection whose credentials cannot be
+// read returns ErrPeerUnidentified, such connections must be served with NewUnidentifiedSession.
+func IdentifyPeer(conn net.Conn) (*Peer, error) {
+	creds, err := listener.PeerCredentialsOf(conn)
+	switch {
+	case err == nil:
+		return &Peer{UID: creds.UID, PID: creds.PID}, nil
+	case errors.Is(err, listener.ErrNotUnixSocket):
+		return nil, nil //nolint:nilnil // connections that are not unix sockets have no peer
+	default:
+		return nil, fmt.Errorf("%w: %w", ErrPeerUnidentified, err)
+	}
+}
+
+// Session is the agent served on a single client connection, it checks the control extension
+// permissions of the connection's peer before passing requests to the frontend agent.
+type Session struct {
+	agent.ExtendedAgent
+
+	mux *MuxAgent
+	// peer is nil when the connection has no peer credentials.
+	peer *Peer
+	// unidentified is set for unix socket connections whose peer credentials cannot be read.
+	unidentified  bool
+	authenticated bool
+}
+
+// NewSession returns the agent for a client connection to a frontend agent, peer is nil when the
+// connection has no peer credentials.
+func (m *MuxAgent) NewSession(frontend agent.ExtendedAgent, peer *Peer) *Session {
+	return &Session{ExtendedAgent: frontend, mux: m, peer: peer}
+}
+
+// NewUnidentifiedSession returns the agent for a unix socket connection whose peer credentials
+// cannot be read, it may only call control extensions once authenticated with the control token.
+func (m *MuxAgent) NewUnidentifiedSession(frontend agent.ExtendedAgent) *Session {
+	return &Session{ExtendedAgent: frontend, mux: m, unidentified: true}
+}
+
+// callerSigner is implemented by the agents that sign on behalf of an identified caller.
+type callerSigner interface {
+	signFor(caller signCaller, key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error)
+}
+
+// Sign signs data on behalf of the connection's peer.
+func (s *Session) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return s.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data on behalf of the connection's peer.
+func (s *Session) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	if signer, ok := s.ExtendedAgent.(callerSigner); ok {
+		return signer.signFor(signCaller{Peer: s.peer}, key, data, flags)
+	}
+
+	return s.ExtendedAgent.SignWithFlags(key, data, flags)
+}
+
+// Extension authorises mux control extensions before passing them to the frontend agent.
+func (s *Session) Extension(extensionType string, contents []byte) ([]byte, error) {
+	if extensionType == ExtensionAuth {
+		return nil, s.authenticate(contents)
+	}
+
+	if ext, ok := s.mux.extensions.lookup(extensionType); ok {
+		if err := s.authorize(extensionType, ext.class); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.ExtendedAgent.Extension(extensionType, contents)
+}
+
+// authenticate grants the connection admin access when the token matches the control token.
+func (s *Session) authenticate(token []byte) error {
+	policy := s.mux.controlPolicy
+	if policy.Token == "" {
+		return agent.ErrExtensionUnsupported
+	}
+
+	if subtle.ConstantTimeCompare(token, []byte(policy.Token)) != 1 {
+		s.mux.logDenied(s.peer, ExtensionAuth, ControlAdmin)
+		return fmt.Errorf("%w: invalid control token", ErrControlDenied)
+	}
+
+	s.authenticated = true
+
+	return nil
+}
+
+// authorize checks that the connection may call an extension of the permission class.
+func (s *Session) authorize(extensionType string, class ControlClass) error {
+	if class == ControlAdmin && s.mux.controlPolicy.SocketPath != "" {
+		s.mux.logDenied(s.peer, extensionType, class)
+		return fmt.Errorf("%w: %s is only served on the control socket", ErrControlDenied, extensionType)
+	}
+
+	return s.mux.authorizeControl(controlCaller{
+		peer:          s.peer,
+		unidentified:  s.unidentified,
+		authenticated: s.authenticated,
+	}, extensionType, class)
+}
+
+// controlCaller identifies the caller of a control extension or method.
+type controlCaller struct {
+	// peer is nil when the connection has no peer credentials.
+	peer *Peer
+	// unidentified is set for unix socket connections whose peer credentials cannot be read.
+	unidentified bool
+	// authenticated is set once the caller presented the control token.
+	authenticated bool
+}
+
+// authorizeControl checks that a caller may call a control extension or method of the permission
+// class. Unix socket callers whose peer credentials cannot be read fail closed, they are only
+// allowed with the control token.
+func (m *MuxAgent) authorizeControl(caller controlCaller, name string, class ControlClass) error {
+	if class == ControlPublic {
+		return nil
+	}
+
+	policy, peer, authenticated := m.controlPolicy, caller.peer, caller.authenticated
+	if caller.unidentified && !authenticated {
+		m.logDenied(peer, name, class)
+		return fmt.Errorf("%w: %s needs the control token without peer credentials", ErrControlDenied, name)
+	}
+
+	if peer != nil && !policy.allowsUID(peer.UID) {
+		m.logDenied(peer, name, class)
+		return fmt.Errorf("%w: uid %d", ErrControlDenied, peer.UID)
+	}
+
+	if class == ControlAdmin && policy.Token != "" && !authenticated {
+		m.logDenied(peer, name, class)
+		return fmt.Errorf("%w: %s needs the control token", ErrControlDenied, name)
+	}
+
+	return nil
+}
+
+func (m *MuxAgent) logDenied(peer *Peer, name string, class ControlClass) {
+	attrs := []any{
+		slog.String("control-name", name),
+		slog.String("control-class", class.String()),
+	}
+	if peer != nil {
+		attrs = append(attrs, slog.Int64("peer-uid", int64(peer.UID)), slog.Int("peer-pid", peer.PID))
+	}
+
+	m.logger.WarnContext(m.ctx, "Control call denied", attrs...)
+}
+
+// AddSmartcardKey loads the keys of a PKCS#11 module through the frontend agent.
+func (s *Session) AddSmartcardKey(provider, pin string, lifetime uint32) error {
+	smartcard, ok := s.ExtendedAgent.(agentproto.SmartcardAgent)
+	if !ok {
+		return agentproto.ErrUnsupportedMessage
+	}
+
+	return smartcard.AddSmartcardKey(provider, pin, lifetime)
+}
+
+// RemoveSmartcardKey unloads a PKCS#11 module through the frontend agent.
+func (s *Session) RemoveSmartcardKey(provider string) error {
+	smartcard, ok := s.ExtendedAgent.(agentproto.SmartcardAgent)
+	if !ok {
+		return agentproto.ErrUnsupportedMessage
+	}
+
+	return smartcard.RemoveSmartcardKey(provider)
+}
+
+// Passthrough forwards an unrecognised request through the frontend agent.
+func (s *Session) Passthrough(req []byte) ([]byte, error) {
+	passthrough, ok := s.ExtendedAgent.(agentproto.PassthroughAgent)
+	if !ok {
+		return nil, agentproto.ErrUnsupportedMessage
+	}
+
+	return passthrough.Passthrough(req)
+}
@@ -0,0 +1,220 @@
+package muxagent_test
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxclient"
+	"golang.org/x/crypto/ssh/agent"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSessionAuthorisesControlExtensions(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "control", "default.token")
+	token, err := muxagent.WriteControlToken(tokenFile)
+	if err != nil {
+		t.Fatalf("Failed to write control token: %v", err)
+	}
+
+	if info, statErr := os.Stat(tokenFile); statErr != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("Expected a 0600 token file, got %v, %v", info, statErr)
+	}
+
+	if written, _ := os.ReadFile(tokenFile); strings.TrimSpace(string(written)) != token {
+		t.Errorf("Expected the token file to hold the token")
+	}
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithControlPolicy(muxagent.ControlPolicy{AllowedUIDs: []uint32{1000}, Token: token}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	if err = muxAgent.RegisterExtension("rotate@example.com", muxagent.ControlAdmin, func([]byte) ([]byte, error) {
+		return []byte("rotated"), nil
+	}); err != nil {
+		t.Fatalf("Failed to register extension: %v", err)
+	}
+
+	other := muxAgent.NewSession(muxAgent, &muxagent.Peer{UID: 2000})
+	if _, err = other.Extension(muxagent.ExtensionConfig, nil); !errors.Is(err, muxagent.ErrControlDenied) {
+		t.Errorf("Expected a peer with another uid to be denied, got %v", err)
+	}
+
+	if _, err = other.Extension(muxagent.ExtensionQuery, nil); err != nil {
+		t.Errorf("Expected query to be public, got %v", err)
+	}
+
+	session := muxAgent.NewSession(muxAgent, &muxagent.Peer{UID: 1000})
+	if _, err = session.Extension(muxagent.ExtensionConfig, nil); err != nil {
+		t.Errorf("Expected read-only extensions without the token, got %v", err)
+	}
+
+	if _, err = session.Extension("rotate@example.com", nil); !errors.Is(err, muxagent.ErrControlDenied) {
+		t.Errorf("Expected admin extensions to need the token, got %v", err)
+	}
+
+	if _, err = session.Extension(muxagent.ExtensionAuth, []byte("wrong")); !errors.Is(err, muxagent.ErrControlDenied) {
+		t.Errorf("Expected a wrong token to be rejected, got %v", err)
+	}
+
+	if _, err = session.Extension(muxagent.ExtensionAuth, []byte(token)); err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+
+	if reply, extErr := session.Extension("rotate@example.com", nil); extErr != nil || string(reply) != "rotated" {
+		t.Errorf("Expected authenticated session to call admin extensions, got %q, %v", reply, extErr)
+	}
+
+	// The token authenticates a single connection.
+	if _, err = muxAgent.NewSession(muxAgent, nil).Extension("rotate@example.com", nil); err == nil {
+		t.Error("Expected a new session to need the token again")
+	}
+}
+
+func TestSessionWithoutTokenAllowsOwnUser(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	session := muxAgent.NewSession(muxAgent, &muxagent.Peer{UID: uint32(os.Getuid())}) //nolint:gosec // test uid
+	if _, err = session.Extension(muxagent.ExtensionConfig, nil); err != nil {
+		t.Errorf("Expected the mux user to call control extensions, got %v", err)
+	}
+
+	if _, err = session.Extension(muxagent.ExtensionAuth, nil); !errors.Is(err, agent.ErrExtensionUnsupported) {
+		t.Errorf("Expected auth to be unsupported without a token, got %v", err)
+	}
+}
+
+func TestUnidentifiedSessionNeedsToken(t *testing.T) {
+	withoutToken, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer withoutToken.Close()
+
+	unidentified := withoutToken.NewUnidentifiedSession(withoutToken)
+	if _, err = unidentified.Extension(muxagent.ExtensionConfig, nil); !errors.Is(err, muxagent.ErrControlDenied) {
+		t.Errorf("Expected a peer without credentials to be denied without a token, got %v", err)
+	}
+
+	if _, err = unidentified.Extension(muxagent.ExtensionQuery, nil); err != nil {
+		t.Errorf("Expected query to stay public, got %v", err)
+	}
+
+	token := "secret"
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithControlPolicy(muxagent.ControlPolicy{Token: token}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	unidentified = muxAgent.NewUnidentifiedSession(muxAgent)
+	if _, err = unidentified.Extension(muxagent.ExtensionAuth, []byte(token)); err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+
+	if _, err = unidentified.Extension(muxagent.ExtensionConfig, nil); err != nil {
+		t.Errorf("Expected an authenticated peer without credentials to be allowed, got %v", err)
+	}
+}
+
+func TestIdentifyPeer(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	if peer, err := muxagent.IdentifyPeer(server); peer != nil || err != nil {
+		t.Errorf("Expected no peer and no error for a connection that is not a unix socket, got %v, %v", peer, err)
+	}
+}
+
+func TestControlSocketServesControlService(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "default.token")
+	token, err := muxagent.WriteControlToken(tokenFile)
+	if err != nil {
+		t.Fatalf("Failed to write control token: %v", err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "control.sock")
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithControlPolicy(muxagent.ControlPolicy{SocketPath: socketPath, Token: token}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	// Admin extensions are only served on the control socket.
+	session := muxAgent.NewSession(muxAgent, nil)
+	if _, err = session.Extension(muxagent.ExtensionShutdown, nil); !errors.Is(err, muxagent.ErrControlDenied) {
+		t.Errorf("Expected admin extensions to be denied on the agent socket, got %v", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := muxAgent.NewControlServer()
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	logger := slog.New(slog.DiscardHandler)
+	anonymous, _ := muxclient.NewMuxClient(logger, "", muxclient.WithControlSocket(socketPath))
+	if _, err = anonymous.Ping(t.Context()); err != nil {
+		t.Errorf("Expected read-only calls without the token, got %v", err)
+	}
+
+	_, err = anonymous.ManageBackends(t.Context(), api.BackendAction_BACKEND_ACTION_ADD, "/tmp/backend.sock")
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected admin calls to need the token, got %v", err)
+	}
+
+	events, cancel := muxAgent.SubscribeEvents()
+	defer cancel()
+
+	client, _ := muxclient.NewMuxClient(logger, "",
+		muxclient.WithControlSocket(socketPath), muxclient.WithTokenFile(tokenFile),
+	)
+
+	resp, err := client.ManageBackends(t.Context(), api.BackendAction_BACKEND_ACTION_ADD, "/tmp/backend.sock")
+	if err != nil {
+		t.Fatalf("Failed to add backend: %v", err)
+	}
+
+	if !resp.GetChanged() || !slices.Contains(resp.GetBackendSocketPath(), "/tmp/backend.sock") {
+		t.Errorf("Expected the backend to be added, got %v", resp)
+	}
+
+	if ev := <-events; ev.GetType() != api.EventType_EVENT_TYPE_BACKEND_ADDED ||
+		ev.GetBackendSocketPath() != "/tmp/backend.sock" {
+		t.Errorf("Expected a backend added event, got %v", ev)
+	}
+
+	if _, err = client.ListKeys(t.Context()); err != nil {
+		t.Errorf("Failed to list keys: %v", err)
+	}
+}
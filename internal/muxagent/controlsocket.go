@@ -0,0 +1,146 @@
+package muxagent
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ControlTokenMetadata is the gRPC metadata key carrying the control token.
+const ControlTokenMetadata = "x-ssh-agent-mux-token"
+
+// controlMethodClasses are the permission classes needed to call the control service methods.
+var controlMethodClasses = map[string]ControlClass{
+	api.MuxControl_Ping_FullMethodName:           ControlReadOnly,
+	api.MuxControl_GetConfig_FullMethodName:      ControlReadOnly,
+	api.MuxControl_ListKeys_FullMethodName:       ControlReadOnly,
+	api.MuxControl_StreamEvents_FullMethodName:   ControlReadOnly,
+	api.MuxControl_GetStatus_FullMethodName:      ControlReadOnly,
+	api.MuxControl_Shutdown_FullMethodName:       ControlAdmin,
+	api.MuxControl_Keygen_FullMethodName:         ControlAdmin,
+	api.MuxControl_CertIssue_FullMethodName:      ControlAdmin,
+	api.MuxControl_ManageBackends_FullMethodName: ControlAdmin,
+	api.MuxControl_Unlock_FullMethodName:         ControlAdmin,
+}
+
+// NewControlServer returns a gRPC server for the control socket serving the control service,
+// calls are authorised with the control policy like the control extensions.
+func (m *MuxAgent) NewControlServer() *grpc.Server {
+	server := grpc.NewServer(
+		grpc.Creds(peerCredentials{}),
+		grpc.ChainUnaryInterceptor(func(
+			ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+		) (any, error) {
+			if err := m.authorizeCall(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(
+			srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+		) error {
+			if err := m.authorizeCall(stream.Context(), info.FullMethod); err != nil {
+				return err
+			}
+
+			return handler(srv, stream)
+		}),
+	)
+
+	api.RegisterMuxControlServer(server, m.service)
+
+	return server
+}
+
+// authorizeCall checks that the caller of a control service method is allowed to use it.
+func (m *MuxAgent) authorizeCall(ctx context.Context, method string) error {
+	class, ok := controlMethodClasses[method]
+	if !ok {
+		class = ControlAdmin
+	}
+
+	// Callers without auth info did not pass the peer credentials handshake, they are treated as
+	// unidentified.
+	caller := controlCaller{unidentified: true}
+	if p, found := peer.FromContext(ctx); found {
+		if info, isPeer := p.AuthInfo.(PeerAuthInfo); isPeer {
+			caller.peer, caller.unidentified = info.Peer, info.Unidentified
+		}
+	}
+
+	if token := m.controlPolicy.Token; token != "" {
+		for _, value := range metadata.ValueFromIncomingContext(ctx, ControlTokenMetadata) {
+			if subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1 {
+				caller.authenticated = true
+			}
+		}
+	}
+
+	if err := m.authorizeControl(caller, method, class); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return nil
+}
+
+// PeerAuthInfo is the gRPC auth info of a control socket connection, Peer is nil when the
+// connection has no peer credentials.
+type PeerAuthInfo struct {
+	credentials.CommonAuthInfo
+
+	Peer *Peer
+	// Unidentified is set for unix socket connections whose peer credentials cannot be read, they
+	// are only allowed with the control token.
+	Unidentified bool
+}
+
+// AuthType returns the name of the peer credentials auth type.
+func (PeerAuthInfo) AuthType() string {
+	return "peercred"
+}
+
+// peerCredentials are the gRPC transport credentials of the control socket, the socket is only
+// accessible to the current user so the connection is not encrypted and the peer is identified
+// with its Unix socket credentials.
+type peerCredentials struct{}
+
+// ClientHandshake leaves the connection as is, the client trusts the socket permissions.
+func (peerCredentials) ClientHandshake(
+	_ context.Context, _ string, conn net.Conn,
+) (net.Conn, credentials.AuthInfo, error) {
+	return conn, PeerAuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}}, nil
+}
+
+// ServerHandshake identifies the peer of a connection with its Unix socket credentials.
+func (peerCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	info := PeerAuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}}
+
+	peer, err := IdentifyPeer(conn)
+	info.Peer, info.Unidentified = peer, err != nil
+
+	return conn, info, nil
+}
+
+// Info returns the protocol info of the peer credentials.
+func (peerCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "peercred"}
+}
+
+// Clone returns a copy of the credentials.
+func (c peerCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+// OverrideServerName is a no-op, Unix sockets have no server name.
+func (peerCredentials) OverrideServerName(string) error {
+	return nil
+}
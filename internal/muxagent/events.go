@@ -0,0 +1,273 @@
+package muxagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// eventBufferSize is the number of events buffered for each subscriber.
+const eventBufferSize = 64
+
+// eventQueueLimit is the number of events held for each queue subscriber, events beyond it are
+// dropped.
+const eventQueueLimit = 1024
+
+// eventTypePrefix is the prefix of the EventType enum values, event names drop it.
+const eventTypePrefix = "EVENT_TYPE_"
+
+// ErrUnknownEventType indicates an event name that is not an event type.
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// EventName returns the name of an event type as used in the config file and by the watch
+// command, eg. "key-added".
+func EventName(eventType api.EventType) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(eventType.String(), eventTypePrefix), "_", "-"))
+}
+
+// ParseEventTypes parses event names.
+func ParseEventTypes(names ...string) ([]api.EventType, error) {
+	types := make([]api.EventType, 0, len(names))
+	for _, name := range names {
+		value, ok := api.EventType_value[eventTypePrefix+strings.ToUpper(strings.ReplaceAll(name, "-", "_"))]
+		if !ok || value == int32(api.EventType_EVENT_TYPE_UNSPECIFIED) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, name)
+		}
+
+		types = append(types, api.EventType(value))
+	}
+
+	return types, nil
+}
+
+// eventBroker fans the events of the mux agent out to subscribers. A channel subscriber that falls
+// behind misses events rather than blocking the agent, queue subscribers receive every event.
+type eventBroker struct {
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	subs   map[chan *api.Event]struct{}
+	queues map[*eventQueue]struct{}
+}
+
+// subscribe returns a channel receiving every event published until cancel is called.
+func (b *eventBroker) subscribe() (<-chan *api.Event, func()) {
+	ch := make(chan *api.Event, eventBufferSize)
+
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[chan *api.Event]struct{})
+	}
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+// subscribeQueue returns a queue receiving the events of the given types published until cancel
+// is called, no event is dropped unless the subscriber falls eventQueueLimit events behind.
+func (b *eventBroker) subscribeQueue(types []api.EventType) (*eventQueue, func()) {
+	q := &eventQueue{types: types, ready: make(chan struct{}, 1)}
+
+	b.mu.Lock()
+	if b.queues == nil {
+		b.queues = make(map[*eventQueue]struct{})
+	}
+	b.queues[q] = struct{}{}
+	b.mu.Unlock()
+
+	return q, func() {
+		b.mu.Lock()
+		delete(b.queues, q)
+		b.mu.Unlock()
+	}
+}
+
+// publish sends an event to every queue of its type and to every subscriber with room for it,
+// events dropped for subscribers that fell behind are logged.
+func (b *eventBroker) publish(ev *api.Event) {
+	b.mu.Lock()
+
+	dropped := 0
+	for q := range b.queues {
+		if !slices.Contains(q.types, ev.GetType()) {
+			continue
+		}
+		if !q.push(ev) {
+			dropped++
+		}
+	}
+
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			dropped++
+		}
+	}
+
+	b.mu.Unlock()
+
+	if dropped > 0 && b.logger != nil {
+		b.logger.Warn("Dropped event for subscribers that fell behind",
+			slog.String("event-type", EventName(ev.GetType())),
+			slog.String("event-id", ev.GetId()),
+			slog.Int("subscriber-count", dropped),
+		)
+	}
+}
+
+// eventQueue is an event subscription for a set of event types, events are held in memory until
+// the subscriber takes them.
+type eventQueue struct {
+	types  []api.EventType
+	mu     sync.Mutex
+	events []*api.Event
+	ready  chan struct{}
+}
+
+// push appends an event to the queue and wakes the subscriber, it returns false when the queue is
+// full and the event was dropped.
+func (q *eventQueue) push(ev *api.Event) bool {
+	q.mu.Lock()
+	if len(q.events) >= eventQueueLimit {
+		q.mu.Unlock()
+		return false
+	}
+	q.events = append(q.events, ev)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// len returns the number of queued events.
+func (q *eventQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.events)
+}
+
+// next waits for the oldest queued event, it returns false once ctx is done.
+func (q *eventQueue) next(ctx context.Context) (*api.Event, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.events) > 0 {
+			ev := q.events[0]
+			q.events[0] = nil
+			q.events = q.events[1:]
+			q.mu.Unlock()
+
+			return ev, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-q.ready:
+		}
+	}
+}
+
+// SubscribeEvents returns a channel receiving the events of the mux agent until cancel is called.
+func (m *MuxAgent) SubscribeEvents() (<-chan *api.Event, func()) {
+	return m.events.subscribe()
+}
+
+// newEvent returns an event of the given type with a fresh id and timestamp.
+func newEvent(eventType api.EventType) api.Event_builder {
+	return api.Event_builder{
+		Id:   proto.String(uuid.NewString()),
+		Ts:   timestamppb.Now(),
+		Type: eventType.Enum(),
+	}
+}
+
+// emitKeyEvent publishes an event about a local key.
+func (m *MuxAgent) emitKeyEvent(eventType api.EventType, pubKey ssh.PublicKey, comment string) {
+	ev := newEvent(eventType)
+	ev.KeyFingerprint = proto.String(ssh.FingerprintSHA256(pubKey))
+	ev.KeyType = proto.String(pubKey.Type())
+	ev.KeyComment = proto.String(comment)
+
+	m.events.publish(ev.Build())
+}
+
+// signEventDetails are the optional fields of a sign event.
+type signEventDetails struct {
+	// Source is where the key that served the request came from.
+	Source string
+	// Reason is why the request was denied.
+	Reason string
+	// Frontend is the listener that denied the request.
+	Frontend string
+}
+
+// emitSignEvent publishes an event about a sign request.
+func (m *MuxAgent) emitSignEvent(eventType api.EventType, pubKey ssh.PublicKey, details signEventDetails) {
+	ev := newEvent(eventType)
+	ev.KeyFingerprint = proto.String(ssh.FingerprintSHA256(pubKey))
+	ev.KeyType = proto.String(pubKey.Type())
+	if details.Source != "" {
+		ev.Source = proto.String(details.Source)
+	}
+	if details.Reason != "" {
+		ev.Reason = proto.String(details.Reason)
+	}
+	if details.Frontend != "" {
+		ev.Frontend = proto.String(details.Frontend)
+	}
+
+	m.events.publish(ev.Build())
+}
+
+// emitBackendEvent publishes an event about a backend agent, reason is empty unless the backend
+// agent is down.
+func (m *MuxAgent) emitBackendEvent(eventType api.EventType, socketPath, reason string) {
+	ev := newEvent(eventType)
+	ev.BackendSocketPath = proto.String(socketPath)
+	if reason != "" {
+		ev.Reason = proto.String(reason)
+	}
+
+	m.events.publish(ev.Build())
+}
+
+// emitEvent publishes an event without details.
+func (m *MuxAgent) emitEvent(eventType api.EventType) {
+	ev := newEvent(eventType)
+
+	m.events.publish(ev.Build())
+}
+
+// eventFilter reports whether an event has one of the given types, every event matches an empty
+// list of types.
+func eventFilter(types []api.EventType) func(*api.Event) bool {
+	if len(types) == 0 {
+		return func(*api.Event) bool { return true }
+	}
+
+	return func(ev *api.Event) bool {
+		return slices.Contains(types, ev.GetType())
+	}
+}
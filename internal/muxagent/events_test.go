@@ -0,0 +1,97 @@
+package muxagent_test
+
+import (
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"golang.org/x/crypto/ssh"
+)
+
+func nextEvent(t *testing.T, events <-chan *api.Event, eventType api.EventType) *api.Event {
+	t.Helper()
+
+	select {
+	case ev := <-events:
+		if ev.GetType() != eventType {
+			t.Fatalf("Expected %s event, got %s", eventType, ev.GetType())
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for %s event", eventType)
+		return nil
+	}
+}
+
+func TestEventsForSignsBackendsAndReload(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSignPolicies(muxagent.SignPolicy{
+			Keys:           muxagent.KeyPolicy{Comments: []string{"deploy"}},
+			DenyNamespaces: []string{"git"},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	events, cancel := muxAgent.SubscribeEvents()
+	defer cancel()
+
+	deployKey := addTestKey(t, muxAgent, "deploy")
+	if ev := nextEvent(t, events, api.EventType_EVENT_TYPE_KEY_ADDED); ev.GetKeyComment() != "deploy" {
+		t.Errorf("Expected key added event for the deploy key, got %v", ev)
+	}
+
+	if _, err = muxAgent.Sign(deployKey, []byte("authentication request")); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	served := nextEvent(t, events, api.EventType_EVENT_TYPE_SIGN_SERVED)
+	if served.GetKeyFingerprint() != ssh.FingerprintSHA256(deployKey) || served.GetSource() != muxagent.KeySourceLocal {
+		t.Errorf("Expected sign served by the local deploy key, got %v", served)
+	}
+
+	gitData := sshsigBlob("git", []byte("tree 1234"))
+	if _, err = muxAgent.Sign(deployKey, gitData); err == nil {
+		t.Fatal("Expected git signature to be denied")
+	}
+	if ev := nextEvent(t, events, api.EventType_EVENT_TYPE_SIGN_DENIED); ev.GetReason() == "" {
+		t.Errorf("Expected sign denied event with a reason, got %v", ev)
+	}
+
+	backend := filepath.Join(t.TempDir(), "missing.sock")
+	if _, err = muxAgent.AddBackend(backend); err != nil {
+		t.Fatalf("Failed to add backend: %v", err)
+	}
+	nextEvent(t, events, api.EventType_EVENT_TYPE_BACKEND_ADDED)
+
+	if _, err = muxAgent.List(); err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if ev := nextEvent(t, events, api.EventType_EVENT_TYPE_BACKEND_DOWN); ev.GetBackendSocketPath() != backend {
+		t.Errorf("Expected backend down event for %s, got %v", backend, ev)
+	}
+
+	if _, err = muxAgent.List(); err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+
+	muxAgent.Reload(nil, nil)
+	nextEvent(t, events, api.EventType_EVENT_TYPE_BACKEND_REMOVED)
+	nextEvent(t, events, api.EventType_EVENT_TYPE_CONFIG_RELOADED)
+
+	if _, err = muxAgent.Sign(deployKey, gitData); err != nil {
+		t.Errorf("Expected git signature to be allowed after reload, got %v", err)
+	}
+	nextEvent(t, events, api.EventType_EVENT_TYPE_SIGN_SERVED)
+
+	if err = muxAgent.Remove(deployKey); err != nil {
+		t.Fatalf("Failed to remove key: %v", err)
+	}
+	nextEvent(t, events, api.EventType_EVENT_TYPE_KEY_REMOVED)
+}
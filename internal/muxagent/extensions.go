@@ -0,0 +1,294 @@
+package muxagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ExtensionDomain is the domain the mux control extensions are named under, following the
+// name@domain convention for vendor extensions of draft-miller-ssh-agent.
+const ExtensionDomain = "ssh-agent-mux"
+
+// Mux control extensions.
+const (
+	ExtensionPing      = "ping@" + ExtensionDomain
+	ExtensionConfig    = "config@" + ExtensionDomain
+	ExtensionShutdown  = "shutdown@" + ExtensionDomain
+	ExtensionKeygen    = "keygen@" + ExtensionDomain
+	ExtensionCertIssue = "cert-issue@" + ExtensionDomain
+	ExtensionListKeys  = "list-keys@" + ExtensionDomain
+	ExtensionBackends  = "backends@" + ExtensionDomain
+	ExtensionStatus    = "status@" + ExtensionDomain
+	ExtensionUnlock    = "unlock@" + ExtensionDomain
+
+	// ExtensionQuery is the standard extension listing the supported extensions.
+	ExtensionQuery = "query"
+)
+
+var (
+	// ErrExtensionName indicates an extension name that does not follow the name@domain convention.
+	ErrExtensionName = errors.New("extension name must be of the form name@domain")
+	// ErrExtensionRegistered indicates an extension name or alias that is already registered.
+	ErrExtensionRegistered = errors.New("extension already registered")
+)
+
+// ExtensionHandler answers an extension request with the raw reply contents.
+type ExtensionHandler func(contents []byte) ([]byte, error)
+
+// registeredExtension is an extension handler and the permission class needed to call it.
+type registeredExtension struct {
+	handler ExtensionHandler
+	class   ControlClass
+}
+
+// extensionRegistry holds the mux extension handlers by name, aliases map legacy names to
+// registered names.
+type extensionRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]registeredExtension
+	aliases  map[string]string
+}
+
+func newExtensionRegistry() *extensionRegistry {
+	return &extensionRegistry{
+		handlers: make(map[string]registeredExtension),
+		aliases:  make(map[string]string),
+	}
+}
+
+func (r *extensionRegistry) register(name string, class ControlClass, h ExtensionHandler, aliases ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, n := range append([]string{name}, aliases...) {
+		if _, ok := r.handlers[n]; ok {
+			return fmt.Errorf("%w: %s", ErrExtensionRegistered, n)
+		}
+		if _, ok := r.aliases[n]; ok {
+			return fmt.Errorf("%w: %s", ErrExtensionRegistered, n)
+		}
+	}
+
+	r.handlers[name] = registeredExtension{handler: h, class: class}
+	for _, alias := range aliases {
+		r.aliases[alias] = name
+	}
+
+	return nil
+}
+
+// lookup returns the extension registered under name or an alias of it.
+func (r *extensionRegistry) lookup(name string) (registeredExtension, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if target, ok := r.aliases[name]; ok {
+		name = target
+	}
+
+	ext, ok := r.handlers[name]
+
+	return ext, ok
+}
+
+// resolve returns the registered name of an alias, other names are returned unchanged.
+func (r *extensionRegistry) resolve(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if target, ok := r.aliases[name]; ok {
+		return target
+	}
+
+	return name
+}
+
+// names returns the sorted registered names, aliases are not included.
+func (r *extensionRegistry) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// RegisterExtension registers a control extension handler under a name@domain name, callers need
+// the permission class to use it. The aliases are also answered but not listed by the query
+// extension.
+func (m *MuxAgent) RegisterExtension(name string, class ControlClass, h ExtensionHandler, aliases ...string) error {
+	if local, domain, ok := strings.Cut(name, "@"); !ok || local == "" || domain == "" {
+		return fmt.Errorf("%w: %q", ErrExtensionName, name)
+	}
+
+	return m.extensions.register(name, class, h, aliases...)
+}
+
+// registerExtensions registers the built-in control extensions as adapters over the control
+// service, the bare names used by earlier versions of muxclient are kept as aliases.
+func (m *MuxAgent) registerExtensions() {
+	builtin := []struct {
+		name    string
+		aliases []string
+		class   ControlClass
+		handler ExtensionHandler
+	}{
+		{ExtensionPing, []string{"ping"}, ControlReadOnly, serviceExtension(m.ctx, m.service.Ping)},
+		{ExtensionConfig, []string{"config"}, ControlReadOnly, serviceExtension(m.ctx, m.service.GetConfig)},
+		{ExtensionShutdown, []string{"shutdown"}, ControlAdmin, serviceExtension(m.ctx, m.service.Shutdown)},
+		{ExtensionKeygen, []string{"keygen"}, ControlAdmin, serviceExtension(m.ctx, m.service.Keygen)},
+		{ExtensionCertIssue, []string{"cert-issue"}, ControlAdmin, serviceExtension(m.ctx, m.service.CertIssue)},
+		{ExtensionListKeys, nil, ControlReadOnly, serviceExtension(m.ctx, m.service.ListKeys)},
+		{ExtensionBackends, nil, ControlAdmin, serviceExtension(m.ctx, m.service.ManageBackends)},
+		{ExtensionStatus, nil, ControlReadOnly, serviceExtension(m.ctx, m.service.GetStatus)},
+		{ExtensionUnlock, nil, ControlAdmin, serviceExtension(m.ctx, m.service.Unlock)},
+	}
+
+	for _, ext := range builtin {
+		_ = m.extensions.register(ext.name, ext.class, ext.handler, ext.aliases...)
+	}
+
+	// query is a standard extension so it is not namespaced.
+	_ = m.extensions.register(ExtensionQuery, ControlPublic, m.handleQuery)
+}
+
+// serviceExtension adapts a unary control service method to an extension handler, the request
+// and reply are the marshalled protobuf messages.
+func serviceExtension[T, P any, TT genericProtoMessage[T], PP genericProtoMessage[P]](
+	ctx context.Context, method func(context.Context, TT) (PP, error),
+) ExtensionHandler {
+	return func(contents []byte) ([]byte, error) {
+		return HandleExtensionProto[T, P, TT, PP](contents, func(msg *T) (*P, error) {
+			reply, err := method(ctx, TT(msg))
+			return (*P)(reply), err
+		})
+	}
+}
+
+// extensionRoutes maps the extensions advertised by backend agents through the query extension
+// to the backend that advertised them, a nil map means the backends have not been queried yet.
+type extensionRoutes struct {
+	mu     sync.RWMutex
+	routes map[string]string
+}
+
+// handleQuery answers the query extension with the registered extensions and the extensions of
+// every backend agent.
+func (m *MuxAgent) handleQuery([]byte) ([]byte, error) {
+	names := m.extensions.names()
+	for _, name := range m.queryBackends() {
+		if _, ok := m.extensions.lookup(name); !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return marshalQueryReply(names), nil
+}
+
+// queryBackends asks every backend agent for its extensions, records which backend advertised
+// each one and returns the sorted names. The first backend advertising an extension receives it.
+func (m *MuxAgent) queryBackends() []string {
+	routes := make(map[string]string)
+
+	_ = m.runAgainstBackends(func(socketPath string, fb agent.ExtendedAgent) error {
+		reply, err := fb.Extension(ExtensionQuery, nil)
+		if err == nil {
+			var names []string
+			if names, err = ParseQueryReply(reply); err == nil {
+				for _, name := range names {
+					if _, ok := routes[name]; !ok && name != ExtensionQuery {
+						routes[name] = socketPath
+					}
+				}
+			}
+		}
+
+		if err != nil {
+			m.logger.DebugContext(m.ctx, "Backend agent did not answer query extension",
+				slog.String("socket-path", socketPath),
+				slogtool.ErrorAttr(err),
+			)
+		}
+
+		return nil
+	})
+
+	m.extRoutes.mu.Lock()
+	m.extRoutes.routes = routes
+	m.extRoutes.mu.Unlock()
+
+	names := make([]string, 0, len(routes))
+	for name := range routes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// extensionRoute returns the backend agent that advertised an extension, the backends are
+// queried the first time a route is needed and again on every query extension.
+func (m *MuxAgent) extensionRoute(extensionType string) (string, bool) {
+	m.extRoutes.mu.RLock()
+	routes := m.extRoutes.routes
+	m.extRoutes.mu.RUnlock()
+
+	if routes == nil {
+		m.queryBackends()
+
+		m.extRoutes.mu.RLock()
+		routes = m.extRoutes.routes
+		m.extRoutes.mu.RUnlock()
+	}
+
+	socketPath, ok := routes[extensionType]
+
+	return socketPath, ok
+}
+
+// marshalQueryReply returns the query extension reply listing names.
+func marshalQueryReply(names []string) []byte {
+	reply := []byte{agentproto.MsgSuccess}
+	for _, name := range names {
+		reply = append(reply, ssh.Marshal(struct{ Name string }{name})...)
+	}
+
+	return reply
+}
+
+// ParseQueryReply returns the extension names listed in a query extension reply.
+func ParseQueryReply(reply []byte) ([]string, error) {
+	if len(reply) == 0 || reply[0] != agentproto.MsgSuccess {
+		return nil, fmt.Errorf("%w: query reply", agentproto.ErrMalformedMessage)
+	}
+
+	var names []string
+	for rest := reply[1:]; len(rest) > 0; {
+		var msg struct {
+			Name string
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(rest, &msg); err != nil {
+			return nil, fmt.Errorf("%w: query reply: %w", agentproto.ErrMalformedMessage, err)
+		}
+
+		names, rest = append(names, msg.Name), msg.Rest
+	}
+
+	return names, nil
+}
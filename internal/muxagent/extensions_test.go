@@ -0,0 +1,240 @@
+package muxagent_test
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExtensionRegistry(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	// The namespaced name and the legacy alias reach the same handler.
+	for _, name := range []string{muxagent.ExtensionPing, "ping"} {
+		pong, pingErr := muxagent.HandleExtensionProtoInvert[api.Ping, api.Pong](
+			api.Ping_builder{Id: proto.String(name)}.Build(),
+			func(inBytes []byte) ([]byte, error) { return muxAgent.Extension(name, inBytes) },
+		)
+		if pingErr != nil {
+			t.Fatalf("Failed to ping with %q: %v", name, pingErr)
+		}
+
+		if pong.GetId() != name {
+			t.Errorf("Expected pong %q, got %q", name, pong.GetId())
+		}
+	}
+
+	err = muxAgent.RegisterExtension("hello", muxagent.ControlReadOnly, nil)
+	if !errors.Is(err, muxagent.ErrExtensionName) {
+		t.Errorf("Expected ErrExtensionName for a bare name, got %v", err)
+	}
+
+	err = muxAgent.RegisterExtension(muxagent.ExtensionPing, muxagent.ControlReadOnly, nil)
+	if !errors.Is(err, muxagent.ErrExtensionRegistered) {
+		t.Errorf("Expected ErrExtensionRegistered for a duplicate name, got %v", err)
+	}
+
+	err = muxAgent.RegisterExtension("hello@example.com", muxagent.ControlReadOnly, func([]byte) ([]byte, error) {
+		return []byte("hi"), nil
+	}, "hello")
+	if err != nil {
+		t.Fatalf("Failed to register extension: %v", err)
+	}
+
+	if reply, extErr := muxAgent.Extension("hello", nil); extErr != nil || string(reply) != "hi" {
+		t.Errorf("Expected the alias to reach the registered handler, got %q, %v", reply, extErr)
+	}
+
+	reply, err := muxAgent.Extension(muxagent.ExtensionQuery, nil)
+	if err != nil {
+		t.Fatalf("Failed to query extensions: %v", err)
+	}
+
+	names, err := muxagent.ParseQueryReply(reply)
+	if err != nil {
+		t.Fatalf("Failed to parse query reply: %v", err)
+	}
+
+	for _, name := range []string{muxagent.ExtensionQuery, muxagent.ExtensionShutdown, "hello@example.com"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("Expected query to list %q, got %v", name, names)
+		}
+	}
+
+	if slices.Contains(names, "ping") || slices.Contains(names, "hello") {
+		t.Errorf("Expected query not to list aliases, got %v", names)
+	}
+}
+
+// extensionBackend is a backend agent that advertises extensions with the query extension and
+// answers every other extension with its name.
+type extensionBackend struct {
+	agent.ExtendedAgent
+
+	name       string
+	advertised []string
+}
+
+func (b extensionBackend) Extension(extensionType string, _ []byte) ([]byte, error) {
+	if extensionType != muxagent.ExtensionQuery {
+		return []byte(b.name), nil
+	}
+
+	reply := []byte{agentproto.MsgSuccess}
+	for _, name := range b.advertised {
+		reply = append(reply, ssh.Marshal(struct{ Name string }{name})...)
+	}
+
+	return reply, nil
+}
+
+// startExtensionBackend serves an extensionBackend on a Unix socket and returns its path.
+func startExtensionBackend(t *testing.T, name string, advertised ...string) string {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), name+".sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	backend := extensionBackend{
+		ExtendedAgent: agent.NewKeyring().(agent.ExtendedAgent), //nolint:forcetypeassert // keyring is extended
+		name:          name,
+		advertised:    advertised,
+	}
+
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_ = agentproto.NewServer(backend).Serve(conn)
+			}()
+		}
+	}()
+
+	return socketPath
+}
+
+func TestQueryAggregatesBackendExtensions(t *testing.T) {
+	config := defaultConfig()
+	config.SetBackendSocketPath([]string{
+		startExtensionBackend(t, "first", "first@example.com", "shared@example.com"),
+		startExtensionBackend(t, "second", "second@example.com", "shared@example.com"),
+	})
+
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), config)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	reply, err := muxAgent.Extension(muxagent.ExtensionQuery, nil)
+	if err != nil {
+		t.Fatalf("Failed to query extensions: %v", err)
+	}
+
+	names, err := muxagent.ParseQueryReply(reply)
+	if err != nil {
+		t.Fatalf("Failed to parse query reply: %v", err)
+	}
+
+	expected := []string{muxagent.ExtensionPing, "first@example.com", "second@example.com", "shared@example.com"}
+	for _, name := range expected {
+		if !slices.Contains(names, name) {
+			t.Errorf("Expected query to list %q, got %v", name, names)
+		}
+	}
+
+	// Extensions are routed to the backend that advertised them, the first one for shared names.
+	for extension, backend := range map[string]string{
+		"first@example.com":  "first",
+		"second@example.com": "second",
+		"shared@example.com": "first",
+	} {
+		if reply, err = muxAgent.Extension(extension, nil); err != nil || string(reply) != backend {
+			t.Errorf("Expected %s to be answered by %s, got %q, %v", extension, backend, reply, err)
+		}
+	}
+
+	// Views without control access only list the backend extensions.
+	view := muxAgent.NewView(muxagent.ViewOptions{Name: "project"})
+	if reply, err = view.Extension(muxagent.ExtensionQuery, nil); err != nil {
+		t.Fatalf("Failed to query extensions through view: %v", err)
+	}
+
+	if names, _ = muxagent.ParseQueryReply(reply); slices.Contains(names, muxagent.ExtensionPing) ||
+		!slices.Contains(names, "second@example.com") {
+		t.Errorf("Expected view query to list only backend extensions, got %v", names)
+	}
+}
+
+func TestFilteredViewsOnlyPassAllowedExtensions(t *testing.T) {
+	config := defaultConfig()
+	config.SetBackendSocketPath([]string{
+		startExtensionBackend(t, "backend", "allowed@example.com", "other@example.com"),
+	})
+
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), config)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	views := map[string]*muxagent.View{
+		"filtered": muxAgent.NewView(muxagent.ViewOptions{
+			Name: "filtered", Keys: muxagent.KeyPolicy{Comments: []string{"work"}},
+			Extensions: []string{"allowed@example.com"},
+		}),
+		"forwarding": muxAgent.NewView(muxagent.ViewOptions{
+			Name: "forwarding", Forwarding: muxagent.ForwardingOptions{Enabled: true},
+			Extensions: []string{"allowed@example.com"},
+		}),
+	}
+
+	for name, view := range views {
+		if reply, extErr := view.Extension("allowed@example.com", nil); extErr != nil || string(reply) != "backend" {
+			t.Errorf("Expected %s view to pass the allowed extension on, got %q, %v", name, reply, extErr)
+		}
+
+		if _, extErr := view.Extension("other@example.com", nil); !errors.Is(extErr, muxagent.ErrExtensionFiltered) {
+			t.Errorf("Expected ErrExtensionFiltered for %s view, got %v", name, extErr)
+		}
+
+		reply, extErr := view.Extension(muxagent.ExtensionQuery, nil)
+		if extErr != nil {
+			t.Fatalf("Failed to query extensions through %s view: %v", name, extErr)
+		}
+
+		if names, _ := muxagent.ParseQueryReply(reply); !slices.Equal(names, []string{"allowed@example.com"}) {
+			t.Errorf("Expected %s view query to list only the allowed extension, got %v", name, names)
+		}
+	}
+
+	// Views without a key policy pass every extension on.
+	open := muxAgent.NewView(muxagent.ViewOptions{Name: "open"})
+	if reply, extErr := open.Extension("other@example.com", nil); extErr != nil || string(reply) != "backend" {
+		t.Errorf("Expected unfiltered view to pass the extension on, got %q, %v", reply, extErr)
+	}
+}
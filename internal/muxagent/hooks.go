@@ -0,0 +1,129 @@
+package muxagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/hookexec"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// defaultHookTimeout limits each hook run when the hook has no timeout.
+const defaultHookTimeout = 10 * time.Second
+
+// ErrInvalidEventHook indicates an event hook that cannot be run.
+var ErrInvalidEventHook = errors.New("invalid event hook")
+
+// EventHook runs a command for events of the mux agent, eg. to update a status file or revoke a
+// temporary key upstream.
+//
+// The event is written to the command's stdin as JSON and its name is in the SSH_AGENT_MUX_EVENT
+// environment variable. Runs of a hook are sequential and in event order, events are queued while
+// the hook runs and none are dropped.
+type EventHook struct {
+	// Events are the names of the event types the hook runs for, eg. "key-added" or "backend-down".
+	Events []string `mapstructure:"events"`
+	// Command is the program and arguments to run.
+	Command []string `mapstructure:"command"`
+	// Timeout limits each run, it defaults to 10s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// WithEventHooks runs commands for events of the mux agent.
+func WithEventHooks(hooks ...EventHook) Option {
+	return func(m *MuxAgent) {
+		m.eventHooks = append(m.eventHooks, hooks...)
+	}
+}
+
+// eventHookRunner runs a hook for the events queued for it.
+type eventHookRunner struct {
+	hook  EventHook
+	types []api.EventType
+	queue *eventQueue
+}
+
+// startEventHooks checks the event hooks and runs them for the events of the mux agent until it
+// stops, hooks run in the background so they never delay the request that caused the event.
+func (m *MuxAgent) startEventHooks() error {
+	if len(m.eventHooks) == 0 {
+		return nil
+	}
+
+	runners := make([]*eventHookRunner, 0, len(m.eventHooks))
+	for i, hook := range m.eventHooks {
+		if len(hook.Command) == 0 {
+			return fmt.Errorf("%w: hook %d has no command", ErrInvalidEventHook, i)
+		}
+
+		types, err := ParseEventTypes(hook.Events...)
+		if err != nil {
+			return fmt.Errorf("%w: hook %d: %w", ErrInvalidEventHook, i, err)
+		}
+		if len(types) == 0 {
+			return fmt.Errorf("%w: hook %d has no events", ErrInvalidEventHook, i)
+		}
+
+		if hook.Timeout <= 0 {
+			hook.Timeout = defaultHookTimeout
+		}
+
+		runners = append(runners, &eventHookRunner{hook: hook, types: types})
+	}
+
+	for _, runner := range runners {
+		queue, cancel := m.events.subscribeQueue(runner.types)
+		runner.queue = queue
+
+		go func() {
+			defer cancel()
+			m.runEventHook(runner)
+		}()
+	}
+
+	return nil
+}
+
+// runEventHook runs a hook for each event queued for it until the mux agent stops, the queue only
+// receives events of the hook's types.
+func (m *MuxAgent) runEventHook(runner *eventHookRunner) {
+	for {
+		ev, ok := runner.queue.next(m.ctx)
+		if !ok {
+			return
+		}
+
+		if err := runner.run(m.ctx, ev); err != nil {
+			m.logger.WarnContext(m.ctx, "Event hook failed",
+				slog.String("hook-command", runner.hook.Command[0]),
+				slog.String("event-type", EventName(ev.GetType())),
+				slog.String("event-id", ev.GetId()),
+				slogtool.ErrorAttr(err),
+			)
+		}
+	}
+}
+
+// run runs the hook command with the event on stdin.
+func (r *eventHookRunner) run(ctx context.Context, ev *api.Event) error {
+	payload, err := protojson.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	if _, err = (hookexec.Command{
+		Args:    r.hook.Command,
+		Stdin:   append(payload, '\n'),
+		Env:     []string{"SSH_AGENT_MUX_EVENT=" + EventName(ev.GetType())},
+		Timeout: r.hook.Timeout,
+	}).Run(ctx); err != nil {
+		return fmt.Errorf("hook command failed: %w", err)
+	}
+
+	return nil
+}
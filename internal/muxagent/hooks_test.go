@@ -0,0 +1,135 @@
+package muxagent_test
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestEventHooksRunForKeyLifecycle(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "events")
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithEventHooks(muxagent.EventHook{
+			Events:  []string{"key-added", "key-removed"},
+			Command: []string{"/bin/sh", "-c", `cat >> "$0"`, out},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	key := addTestKey(t, muxAgent, "deploy")
+	if err = muxAgent.RemoveAll(); err != nil {
+		t.Fatalf("Failed to remove keys: %v", err)
+	}
+
+	var lines []string
+	for deadline := time.Now().Add(5 * time.Second); len(lines) < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		content, _ := os.ReadFile(out)
+		lines = strings.FieldsFunc(string(content), func(r rune) bool { return r == '\n' })
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected the hook to run for 2 events, got %q", lines)
+	}
+
+	for i, want := range []api.EventType{api.EventType_EVENT_TYPE_KEY_ADDED, api.EventType_EVENT_TYPE_KEY_REMOVED} {
+		ev := &api.Event{}
+		if err = protojson.Unmarshal([]byte(lines[i]), ev); err != nil {
+			t.Fatalf("Failed to parse event payload %q: %v", lines[i], err)
+		}
+
+		if ev.GetType() != want || ev.GetKeyComment() != "deploy" || ev.GetKeyType() != key.Type() {
+			t.Errorf("Expected %s event for the deploy key, got %v", want, ev)
+		}
+	}
+}
+
+func TestEventHooksRejectUnknownEvents(t *testing.T) {
+	_, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithEventHooks(muxagent.EventHook{Events: []string{"key-lost"}, Command: []string{"true"}}),
+	)
+	if !errors.Is(err, muxagent.ErrUnknownEventType) {
+		t.Errorf("Expected ErrUnknownEventType, got %v", err)
+	}
+}
+
+func TestEventHooksDoNotDropEvents(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "events")
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithEventHooks(muxagent.EventHook{
+			Events:  []string{"key-added"},
+			Command: []string{"/bin/sh", "-c", `cat >> "$0"`, out},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	// More events than a lossy subscriber buffers arrive while the hook is still running.
+	const added = 100
+	for range added {
+		addTestKey(t, muxAgent, "burst")
+	}
+
+	var lines []string
+	for deadline := time.Now().Add(20 * time.Second); len(lines) < added && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+		content, _ := os.ReadFile(out)
+		lines = strings.FieldsFunc(string(content), func(r rune) bool { return r == '\n' })
+	}
+
+	if len(lines) != added {
+		t.Errorf("Expected the hook to run for all %d events, got %d", added, len(lines))
+	}
+}
+
+func TestEventHooksQueueOnlyTheirEvents(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithEventHooks(muxagent.EventHook{
+			Events:  []string{"key-removed"},
+			Command: []string{"sleep", "30"},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	const added = muxagent.EventQueueLimit + 10
+	for range added {
+		addTestKey(t, muxAgent, "burst")
+	}
+
+	if backlog := muxAgent.EventHookBacklog(); len(backlog) != 1 || backlog[0] != 0 {
+		t.Fatalf("Expected events of other types not to be queued for the hook, got %v", backlog)
+	}
+
+	// The hook is stuck on the first removal, the rest queue up to the limit.
+	if err = muxAgent.RemoveAll(); err != nil {
+		t.Fatalf("Failed to remove keys: %v", err)
+	}
+
+	backlog := muxAgent.EventHookBacklog()[0]
+	if backlog < muxagent.EventQueueLimit-1 || backlog > muxagent.EventQueueLimit {
+		t.Errorf("Expected the hook queue to be capped at %d events, got %d", muxagent.EventQueueLimit, backlog)
+	}
+}
@@ -1,19 +1,36 @@
 package muxagent
 
-import "golang.org/x/crypto/ssh/agent"
-
-func (m *MuxAgent) GetLocalKeys() map[string]*agent.AddedKey {
-	m.keysMutex.RLock()
-	defer m.keysMutex.RUnlock()
+import "golang.org/x/crypto/ssh"
 
+func (m *MuxAgent) GetLocalKeys() map[string]ssh.PublicKey {
 	if m.localKeys == nil {
 		return nil
 	}
 
-	// Create a copy to prevent external modification
-	keysCopy := make(map[string]*agent.AddedKey)
-	for k, v := range m.localKeys {
-		keysCopy[k] = v
+	// Only expose public keys, private key material must stay in the key store
+	keys := make(map[string]ssh.PublicKey)
+	for _, pubKey := range m.localKeys.PublicKeys() {
+		keys[string(pubKey.Marshal())] = pubKey
+	}
+	return keys
+}
+
+// MatchProviderPattern exposes the PKCS#11 provider pattern matcher.
+func MatchProviderPattern(pattern, path string) bool {
+	return matchProviderPattern(pattern, path)
+}
+
+// EventQueueLimit exposes the number of events held for each event hook.
+const EventQueueLimit = eventQueueLimit
+
+// EventHookBacklog returns the number of events queued for each event hook.
+func (m *MuxAgent) EventHookBacklog() []int {
+	m.events.mu.Lock()
+	defer m.events.mu.Unlock()
+
+	var backlog []int
+	for q := range m.events.queues {
+		backlog = append(backlog, q.len())
 	}
-	return keysCopy
+	return backlog
 }
@@ -0,0 +1,154 @@
+package muxagent
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/keystore"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrUnsupportedKeyType indicates that a key type or size cannot be generated.
+var ErrUnsupportedKeyType = errors.New("unsupported key type")
+
+// Key types that can be generated inside the mux agent.
+const (
+	KeyTypeEd25519 = "ed25519"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeRSA     = "rsa"
+)
+
+const (
+	defaultECDSABits = 256
+	defaultRSABits   = 3072
+	minRSABits       = 2048
+	// maxRSABits keeps a key request from pinning a CPU for minutes generating a huge key.
+	maxRSABits = 16384
+)
+
+// GenerateKey generates a private key of the given type, bits is ignored for ed25519 keys and a
+// default size is used when it is zero.
+func GenerateKey(keyType string, bits int) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeEd25519, "":
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	case KeyTypeECDSA:
+		var curve elliptic.Curve
+		switch bits {
+		case 0, defaultECDSABits:
+			curve = elliptic.P256()
+		case 384: //nolint:mnd // P-384 curve size
+			curve = elliptic.P384()
+		case 521: //nolint:mnd // P-521 curve size
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: ecdsa keys must be 256, 384 or 521 bits", ErrUnsupportedKeyType)
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case KeyTypeRSA:
+		if bits == 0 {
+			bits = defaultRSABits
+		}
+		if bits < minRSABits || bits > maxRSABits {
+			return nil, fmt.Errorf("%w: rsa keys must be %d to %d bits", ErrUnsupportedKeyType, minRSABits, maxRSABits)
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyType, keyType)
+	}
+}
+
+// keygen generates a key through the view unless the view is read-only, the key gets the tags of
+// the view like keys added through it.
+func (v *View) keygen(contents []byte) ([]byte, error) {
+	if v.opts.ReadOnly {
+		return nil, fmt.Errorf("generating key: %w", ErrReadOnly)
+	}
+
+	return HandleExtensionProto[api.KeygenRequest, api.KeygenResponse](
+		contents, func(msg *api.KeygenRequest) (*api.KeygenResponse, error) {
+			msg.SetTags(append(msg.GetTags(), v.opts.Tags...))
+			return v.mux.handleKeygen(msg)
+		},
+	)
+}
+
+// handleKeygen generates a key inside the agent, only the public key leaves the process. The key is
+// added like any other key, so it raises a key added event and wakes the certificate renewer.
+func (m *MuxAgent) handleKeygen(msg *api.KeygenRequest) (*api.KeygenResponse, error) {
+	m.logger.DebugContext(m.ctx, "handleKeygen called",
+		slog.String("msg-id", msg.GetId()),
+		slog.String("key-type", msg.GetType()),
+		slog.String("key-comment", msg.GetComment()),
+	)
+
+	lifetime := msg.GetLifetime().AsDuration()
+	if lifetime < 0 || lifetime.Seconds() > math.MaxUint32 {
+		return nil, fmt.Errorf("invalid key lifetime: %s", lifetime)
+	}
+
+	privateKey, err := GenerateKey(msg.GetType(), int(msg.GetBits()))
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := ssh.NewPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err = m.AddWithOptions(agent.AddedKey{
+		PrivateKey:   privateKey,
+		Comment:      msg.GetComment(),
+		LifetimeSecs: uint32(math.Ceil(lifetime.Seconds())),
+	}, keystore.WithTags(msg.GetTags()...)); err != nil {
+		return nil, err
+	}
+
+	m.logger.InfoContext(m.ctx, "Generated local key",
+		slog.String("key-fingerprint", ssh.FingerprintSHA256(pubKey)),
+		slog.String("key-type", pubKey.Type()),
+		slog.String("key-comment", msg.GetComment()),
+		slog.Duration("key-lifetime", lifetime),
+	)
+
+	resp := api.KeygenResponse_builder{
+		Id:          proto.String(msg.GetId()),
+		Ts:          timestamppb.Now(),
+		PublicKey:   proto.String(authorizedKey(pubKey, msg.GetComment())),
+		Fingerprint: proto.String(ssh.FingerprintSHA256(pubKey)),
+	}.Build()
+
+	if lifetime > 0 {
+		resp.SetExpires(timestamppb.New(now.Add(lifetime)))
+	}
+
+	return resp, nil
+}
+
+// authorizedKey formats a public key as an authorized_keys line.
+func authorizedKey(pubKey ssh.PublicKey, comment string) string {
+	line := string(ssh.MarshalAuthorizedKey(pubKey))
+	line = line[:len(line)-1]
+
+	if comment != "" {
+		line += " " + comment
+	}
+
+	return line
+}
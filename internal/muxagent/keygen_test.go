@@ -0,0 +1,124 @@
+package muxagent_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestKeygenExtension(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	events, cancel := muxAgent.SubscribeEvents()
+	defer cancel()
+
+	keygen := func(keyType string) (*api.KeygenResponse, error) {
+		return muxagent.HandleExtensionProtoInvert[api.KeygenRequest, api.KeygenResponse](
+			api.KeygenRequest_builder{
+				Id:       proto.String("test"),
+				Type:     proto.String(keyType),
+				Lifetime: durationpb.New(time.Hour),
+				Comment:  proto.String("deploy"),
+			}.Build(),
+			func(inBytes []byte) ([]byte, error) {
+				return muxAgent.Extension("keygen", inBytes)
+			},
+		)
+	}
+
+	resp, err := keygen(muxagent.KeyTypeEd25519)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	pubKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(resp.GetPublicKey()))
+	if err != nil {
+		t.Fatalf("Failed to parse returned public key %q: %v", resp.GetPublicKey(), err)
+	}
+
+	if comment != "deploy" || pubKey.Type() != ssh.KeyAlgoED25519 {
+		t.Errorf("Expected ed25519 key with comment deploy, got %s %q", pubKey.Type(), comment)
+	}
+
+	if ev := nextEvent(t, events, api.EventType_EVENT_TYPE_KEY_ADDED); ev.GetKeyFingerprint() != resp.GetFingerprint() {
+		t.Errorf("Expected key added event for the generated key, got %v", ev)
+	}
+
+	if !resp.HasExpires() {
+		t.Error("Expected expiry to be returned for key with a lifetime")
+	}
+
+	keys, err := muxAgent.List()
+	if err != nil || len(keys) != 1 || string(keys[0].Blob) != string(pubKey.Marshal()) {
+		t.Fatalf("Expected generated key to be listed, got %v (%v)", keys, err)
+	}
+
+	sig, err := muxAgent.Sign(pubKey, []byte("data"))
+	if err != nil {
+		t.Fatalf("Failed to sign with generated key: %v", err)
+	}
+
+	if err = pubKey.Verify([]byte("data"), sig); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+
+	if _, err = keygen("dsa"); err == nil {
+		t.Error("Expected unsupported key type to fail")
+	}
+
+	if _, err = muxagent.GenerateKey("dsa", 0); !errors.Is(err, muxagent.ErrUnsupportedKeyType) {
+		t.Errorf("Expected ErrUnsupportedKeyType, got %v", err)
+	}
+
+	if _, err = muxagent.GenerateKey(muxagent.KeyTypeRSA, 1<<20); !errors.Is(err, muxagent.ErrUnsupportedKeyType) {
+		t.Errorf("Expected ErrUnsupportedKeyType for an oversized rsa key, got %v", err)
+	}
+}
+
+func TestKeygenThroughView(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	keygen := func(view *muxagent.View) error {
+		_, keygenErr := muxagent.HandleExtensionProtoInvert[api.KeygenRequest, api.KeygenResponse](
+			api.KeygenRequest_builder{Id: proto.String("test"), Comment: proto.String("vm")}.Build(),
+			func(inBytes []byte) ([]byte, error) {
+				return view.Extension(muxagent.ExtensionKeygen, inBytes)
+			},
+		)
+		return keygenErr
+	}
+
+	readOnly := muxAgent.NewView(muxagent.ViewOptions{Name: "ro", ReadOnly: true, AllowControl: true})
+	if err = keygen(readOnly); !errors.Is(err, muxagent.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly generating a key through a read-only view, got %v", err)
+	}
+
+	tagged := muxAgent.NewView(muxagent.ViewOptions{
+		Name: "vm", AllowControl: true, Tags: []string{"vm"},
+		Keys: muxagent.KeyPolicy{Tags: []string{"vm"}},
+	})
+	if err = keygen(tagged); err != nil {
+		t.Fatalf("Failed to generate key through view: %v", err)
+	}
+
+	keys, err := tagged.List()
+	if err != nil || len(keys) != 1 || keys[0].Comment != "vm" {
+		t.Errorf("Expected the generated key to carry the view's tags, got %v (%v)", keys, err)
+	}
+}
@@ -0,0 +1,152 @@
+package muxagent
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
+	"github.com/na4ma4/ssh-agent-mux/internal/keystore"
+	"github.com/na4ma4/ssh-agent-mux/internal/securitykey"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const (
+	keyExpiryInterval = time.Second
+	// skProviderInternal is the sk-provider constraint naming the built-in security key middleware,
+	// the only one the agent signs with.
+	skProviderInternal = "internal"
+)
+
+// ErrUnsupportedConstraint indicates a key constraint the agent cannot enforce.
+var ErrUnsupportedConstraint = errors.New("unsupported key constraint")
+
+// checkAddConstraints refuses keys with constraints that are not enforced, accepting them would
+// silently drop a restriction the client asked for. Only the lifetime and, for security keys, the
+// built-in sk-provider are supported.
+func checkAddConstraints(key agent.AddedKey) error {
+	if key.ConfirmBeforeUse {
+		return fmt.Errorf("%w: confirmation before use", ErrUnsupportedConstraint)
+	}
+
+	for _, ext := range key.ConstraintExtensions {
+		if ext.ExtensionName != agentproto.ExtensionSKProvider {
+			return fmt.Errorf("%w: %s", ErrUnsupportedConstraint, ext.ExtensionName)
+		}
+
+		if _, ok := key.PrivateKey.(*securitykey.PrivateKey); !ok || string(ext.ExtensionDetails) != skProviderInternal {
+			return fmt.Errorf("%w: %s %q", ErrUnsupportedConstraint, ext.ExtensionName, ext.ExtensionDetails)
+		}
+	}
+
+	return nil
+}
+
+// WithSecurityKeyAuthenticator sets the authenticator that signs with security key backed local
+// keys (sk-ssh-ed25519@openssh.com and sk-ecdsa-sha2-nistp256@openssh.com).
+func WithSecurityKeyAuthenticator(auth securitykey.Authenticator) Option {
+	return func(m *MuxAgent) {
+		m.skAuthenticator = auth
+	}
+}
+
+// signWithStore signs data with the key in store matching keyBlob. Security keys sign through
+// auth once the store lock is released, as the authenticator may wait for the user to touch the key.
+func signWithStore(
+	ctx context.Context, store *keystore.Store, auth securitykey.Authenticator,
+	keyBlob, data []byte, flags agent.SignatureFlags,
+) (*ssh.Signature, error) {
+	var (
+		sig   *ssh.Signature
+		skKey *securitykey.PrivateKey
+	)
+
+	if err := store.Use(keyBlob, func(localKey *keystore.Key) error {
+		if key, ok := localKey.PrivateKey.(*securitykey.PrivateKey); ok {
+			skKey = key.Clone()
+			return nil
+		}
+
+		var err error
+		sig, err = signWithLocalKey(localKey, data, flags)
+		return err
+	}); err != nil || skKey == nil {
+		return sig, err
+	}
+	defer skKey.Wipe()
+
+	return securitykey.Sign(ctx, auth, skKey, data)
+}
+
+// signWithLocalKey signs data with a key held in the local key store.
+func signWithLocalKey(localKey *keystore.Key, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	signer, err := ssh.NewSignerFromKey(localKey.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer from local key: %w", err)
+	}
+
+	return signWithSigner(signer, data, flags)
+}
+
+// signWithSigner signs data with the signature algorithm requested by flags.
+func signWithSigner(signer ssh.Signer, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	// Handle signature flags
+	if flags != 0 {
+		if algoSigner, ok := signer.(ssh.AlgorithmSigner); ok {
+			var algorithm string
+			switch flags {
+			case agent.SignatureFlagRsaSha256:
+				algorithm = ssh.KeyAlgoRSASHA256
+			case agent.SignatureFlagRsaSha512:
+				algorithm = ssh.KeyAlgoRSASHA512
+			case agent.SignatureFlagReserved:
+				algorithm = signer.PublicKey().Type()
+			default:
+				algorithm = signer.PublicKey().Type()
+			}
+			return algoSigner.SignWithAlgorithm(rand.Reader, data, algorithm)
+		}
+	}
+
+	return signer.Sign(rand.Reader, data)
+}
+
+// signatureFlags returns the agent signature flags that request a signature algorithm.
+func signatureFlags(algorithm string) agent.SignatureFlags {
+	switch algorithm {
+	case ssh.KeyAlgoRSASHA256:
+		return agent.SignatureFlagRsaSha256
+	case ssh.KeyAlgoRSASHA512:
+		return agent.SignatureFlagRsaSha512
+	default:
+		return 0
+	}
+}
+
+// localSigner signs through the local key store so callers never hold the private key,
+// signing fails once the key has been removed.
+type localSigner struct {
+	ctx    context.Context //nolint:containedctx // signers have no context parameter
+	store  *keystore.Store
+	auth   securitykey.Authenticator
+	pubKey ssh.PublicKey
+}
+
+// PublicKey returns the public key of the local key.
+func (s *localSigner) PublicKey() ssh.PublicKey {
+	return s.pubKey
+}
+
+// Sign signs data with the local key.
+func (s *localSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.SignWithAlgorithm(rand, data, "")
+}
+
+// SignWithAlgorithm signs data with the local key using the given signature algorithm.
+func (s *localSigner) SignWithAlgorithm(_ io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	return signWithStore(s.ctx, s.store, s.auth, s.pubKey.Marshal(), data, signatureFlags(algorithm))
+}
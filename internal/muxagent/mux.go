@@ -1,27 +1,29 @@
 package muxagent
 
 import (
-	"crypto"
-	"crypto/rand"
+	"bytes"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
-	"os"
 	"sync"
-	"syscall"
+	"time"
 
-	"github.com/dosquad/go-cliversion"
-	"github.com/google/uuid"
 	"github.com/na4ma4/go-contextual"
 	"github.com/na4ma4/go-slogtool"
 	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/certprovider"
+	"github.com/na4ma4/ssh-agent-mux/internal/keystore"
+	"github.com/na4ma4/ssh-agent-mux/internal/notify"
+	"github.com/na4ma4/ssh-agent-mux/internal/securitykey"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
-	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// backendRequestTimeout bounds a connection to a backend agent, it allows for backends such as
+// password managers that wait for the user to approve a signature.
+const backendRequestTimeout = time.Minute
+
 // ErrUnimplemented indicates that a method is not implemented.
 var ErrUnimplemented = errors.New("not implemented")
 
@@ -29,13 +31,54 @@ var ErrUnimplemented = errors.New("not implemented")
 type MuxAgent struct {
 	ctx       contextual.Context
 	logger    *slog.Logger
-	localKeys map[string]*agent.AddedKey
-	keysMutex sync.RWMutex
+	localKeys *keystore.Store
 	config    *api.Config
+	configMu  sync.RWMutex
+	conns     *ConnTracker
+	events    eventBroker
+	service   *ControlService
+
+	signPolicies []SignPolicy
+	caConfig     CAConfig
+	caKeys       *keystore.Store
+
+	certProvider     certprovider.Provider
+	certProviderOpts CertProviderOptions
+	certRequests     certRequests
+	certWake         chan struct{}
+
+	pkcs11Allowed []string
+	tokens        tokenSet
+
+	skAuthenticator securitykey.Authenticator
+
+	extensions    *extensionRegistry
+	extRoutes     extensionRoutes
+	controlPolicy ControlPolicy
+
+	backendHealth backendHealth
+
+	signNotifier      notify.Notifier
+	signNotifyOpts    SignNotifyOptions
+	signNotifications signNotifications
+
+	eventHooks []EventHook
+
+	signLimits signLimiter
+}
+
+// Option configures a MuxAgent.
+type Option func(*MuxAgent)
+
+// WithSignPolicies restricts what keys may sign, every policy matching a key must allow the request.
+func WithSignPolicies(policies ...SignPolicy) Option {
+	return func(m *MuxAgent) {
+		m.signPolicies = append(m.signPolicies, policies...)
+	}
 }
 
 // NewMuxAgent creates a new multiplexing SSH agent.
-func NewMuxAgent(ctx contextual.Context, logger *slog.Logger, config *api.Config) (*MuxAgent, error) {
+func NewMuxAgent(ctx contextual.Context, logger *slog.Logger, config *api.Config, opts ...Option) (*MuxAgent, error) {
 	logger.DebugContext(ctx, "Creating new MuxAgent",
 		slog.Any("backend-socket-path", config.GetBackendSocketPath()),
 	)
@@ -43,17 +86,80 @@ func NewMuxAgent(ctx contextual.Context, logger *slog.Logger, config *api.Config
 	m := &MuxAgent{
 		ctx:       ctx,
 		logger:    logger,
-		localKeys: make(map[string]*agent.AddedKey),
+		localKeys: keystore.New(),
 		config:    config,
+		conns:     NewConnTracker(),
+		events:    eventBroker{logger: logger},
+		caKeys:    keystore.New(),
+		certWake:  make(chan struct{}, 1),
+
+		pkcs11Allowed: DefaultPKCS11Providers,
+		tokens:        tokenSet{modules: make(map[string]*tokenModule)},
+
+		extensions: newExtensionRegistry(),
+	}
+
+	m.service = &ControlService{mux: m}
+	m.registerExtensions()
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if err := m.loadCAKey(); err != nil {
+		return nil, err
+	}
+
+	if m.certProvider == nil && m.caConfig.Renew {
+		WithCertProvider(&caProvider{mux: m}, CertProviderOptions{RenewBefore: m.caConfig.RenewBefore})(m)
+	}
+
+	if err := m.startEventHooks(); err != nil {
+		return nil, err
+	}
+
+	go m.expireLocalKeys()
+	go m.monitorBackends()
+
+	if m.certProvider != nil {
+		go m.renewCertificates()
 	}
 
 	return m, nil
 }
 
+// expireLocalKeys periodically wipes local keys and unloads PKCS#11 modules whose lifetime has passed.
+func (m *MuxAgent) expireLocalKeys() {
+	ticker := time.NewTicker(keyExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.removeExpiredTokens(now)
+
+			for _, pubKey := range m.localKeys.RemoveExpired() {
+				m.logger.DebugContext(m.ctx, "Local key expired",
+					slog.String("key-type", pubKey.Type()),
+					slog.String("key-fingerprint", ssh.FingerprintSHA256(pubKey)),
+				)
+				m.emitKeyEvent(api.EventType_EVENT_TYPE_KEY_EXPIRED, pubKey, "")
+			}
+		}
+	}
+}
+
+// Connections returns the tracker for client connections served by this agent.
+func (m *MuxAgent) Connections() *ConnTracker {
+	return m.conns
+}
+
 var errExitBackendLoop = errors.New("exit backend loop")
 
-func (m *MuxAgent) runAgainstBackends(f func(agent.ExtendedAgent) error) error {
-	for _, socketPath := range m.config.GetBackendSocketPath() {
+func (m *MuxAgent) runAgainstBackends(f func(string, agent.ExtendedAgent) error) error {
+	for _, socketPath := range m.backendSocketPaths() {
 		fb, fbClose, err := m.backendConnect(socketPath)
 		if err != nil {
 			m.logger.DebugContext(m.ctx, "Failed to connect to backend agent",
@@ -64,7 +170,7 @@ func (m *MuxAgent) runAgainstBackends(f func(agent.ExtendedAgent) error) error {
 		}
 
 		// Call function and ensure connection is closed
-		err = f(fb)
+		err = f(socketPath, fb)
 		fbClose()
 
 		if err != nil {
@@ -86,79 +192,130 @@ func (m *MuxAgent) backendConnect(socketPath string) (agent.ExtendedAgent, func(
 	}
 
 	conn, err := (&net.Dialer{}).DialContext(m.ctx, "unix", socketPath)
+	m.backendHealth.record(m, socketPath, err)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to connect to backend agent: %w", err)
 	}
 
+	// A backend that stops answering must not hold the client connection open forever.
+	_ = conn.SetDeadline(time.Now().Add(backendRequestTimeout))
+
 	return agent.NewClient(conn), func() { _ = conn.Close() }, nil
 }
 
-// Close closes the connection to the backend agent.
+// Close wipes the local key material held by the agent.
 func (m *MuxAgent) Close() error {
 	m.logger.DebugContext(m.ctx, "Close called")
 
+	m.localKeys.Close()
+	m.caKeys.Close()
+	m.removeAllTokens()
+
 	return nil
 }
 
-// func (m *MuxAgent) cleanupLocalKeys() {
-// 	m.keysMutex.Lock()
-// 	defer m.keysMutex.Unlock()
-
-// 	newLocalKeys := make(map[string]*agent.AddedKey)
-// 	for keyString, addedKey := range m.localKeys {
-// 		newLocalKeys[keyString] = addedKey
-// 	}
-
-// 	m.localKeys = newLocalKeys
-// }
-
 // List returns the identities known to the agent.
 func (m *MuxAgent) List() ([]*agent.Key, error) {
 	m.logger.DebugContext(m.ctx, "List called")
 
-	m.keysMutex.RLock()
-	defer m.keysMutex.RUnlock()
+	sourcedKeys := m.listWithSource()
 
-	keys := make([]*agent.Key, 0, len(m.localKeys))
-	m.logger.DebugContext(m.ctx, "Listing local keys", slog.Int("local-key-count", len(m.localKeys)))
-
-	// Add local keys first
-	for _, addedKey := range m.localKeys {
-		m.logger.DebugContext(m.ctx, "Processing local key with comment", slog.String("key-comment", addedKey.Comment))
-		// Convert PrivateKey interface{} to crypto.Signer
-		signer, ok := addedKey.PrivateKey.(crypto.Signer)
-		if !ok {
-			continue
-		}
+	keys := make([]*agent.Key, 0, len(sourcedKeys))
+	for _, sourcedKey := range sourcedKeys {
+		keys = append(keys, sourcedKey.Key)
+	}
 
-		pubKey := signer.Public()
-		sshPubKey, err := ssh.NewPublicKey(pubKey)
-		if err != nil {
-			continue
-		}
+	return keys, nil
+}
 
-		key := &agent.Key{
-			Format:  sshPubKey.Type(),
-			Blob:    sshPubKey.Marshal(),
-			Comment: addedKey.Comment,
+// listWithSource returns the identities known to the agent along with where each one came from.
+func (m *MuxAgent) listWithSource() []SourcedKey {
+	// Add local keys first
+	localKeys := m.localKeys.ListInfo()
+	m.logger.DebugContext(m.ctx, "Listing local keys", slog.Int("local-key-count", len(localKeys)))
+
+	keys := make([]SourcedKey, 0, len(localKeys))
+	for _, info := range localKeys {
+		keys = append(keys, SourcedKey{
+			Key:     info.Key,
+			Source:  KeySource{Local: true},
+			Tags:    info.Tags,
+			Expires: info.Expires,
+		})
+
+		if info.CertificateKey != nil {
+			keys = append(keys, SourcedKey{
+				Key:     info.CertificateKey,
+				Source:  KeySource{Local: true},
+				Tags:    info.Tags,
+				Expires: info.Expires,
+			})
 		}
-		keys = append(keys, key)
 	}
 
-	if err := m.runAgainstBackends(func(fb agent.ExtendedAgent) error {
+	keys = append(keys, m.listTokenKeys()...)
+
+	if err := m.runAgainstBackends(func(socketPath string, fb agent.ExtendedAgent) error {
 		// Add keys from backend agent
 		backendKeys, err := fb.List()
 		if err != nil {
 			return fmt.Errorf("failed to list keys from backend agent: %w", err)
 		}
 		m.logger.DebugContext(m.ctx, "Listing backend keys", slog.Int("backend-key-count", len(backendKeys)))
-		keys = append(keys, backendKeys...)
+		for _, key := range backendKeys {
+			keys = append(keys, SourcedKey{Key: key, Source: KeySource{Backend: socketPath}})
+		}
 		return nil
 	}); err != nil {
 		m.logger.DebugContext(m.ctx, "Failed to list keys from backend agents", slogtool.ErrorAttr(err))
 	}
 
-	return keys, nil
+	return keys
+}
+
+// lookupSourcedKey finds a key and where it came from, the backend agents are only asked when the
+// key is neither local nor on a token, and only until one of them holds it.
+func (m *MuxAgent) lookupSourcedKey(key ssh.PublicKey) (SourcedKey, error) {
+	blob := key.Marshal()
+
+	if info, ok := m.localKeys.Lookup(blob); ok {
+		sourcedKey := SourcedKey{Key: info.Key, Source: KeySource{Local: true}, Tags: info.Tags, Expires: info.Expires}
+		if info.CertificateKey != nil && bytes.Equal(info.CertificateKey.Blob, blob) {
+			sourcedKey.Key = info.CertificateKey
+		}
+
+		return sourcedKey, nil
+	}
+
+	for _, tokenKey := range m.listTokenKeys() {
+		if bytes.Equal(tokenKey.Key.Blob, blob) {
+			return tokenKey, nil
+		}
+	}
+
+	errs := []error{keystore.ErrKeyNotFound}
+	for _, socketPath := range m.backendSocketPaths() {
+		fb, fbClose, err := m.backendConnect(socketPath)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		backendKeys, err := fb.List()
+		fbClose()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list keys from backend agent %s: %w", socketPath, err))
+			continue
+		}
+
+		for _, backendKey := range backendKeys {
+			if bytes.Equal(backendKey.Blob, blob) {
+				return SourcedKey{Key: backendKey, Source: KeySource{Backend: socketPath}}, nil
+			}
+		}
+	}
+
+	return SourcedKey{}, errors.Join(errs...)
 }
 
 // Sign signs data with the key identified by the given public key.
@@ -173,42 +330,71 @@ func (m *MuxAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.Sig
 		slog.String("key-type", key.Type()),
 		slog.Int("flags", int(flags)),
 	)
-	keyBlob := key.Marshal()
 
-	// Try local keys first
-	m.keysMutex.RLock()
-	addedKey, found := m.localKeys[string(keyBlob)]
-	m.keysMutex.RUnlock()
+	return m.signFor(signCaller{}, key, data, flags)
+}
 
-	if found {
-		signer, err := ssh.NewSignerFromKey(addedKey.PrivateKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create signer from local key: %w", err)
+// signFor signs data for a caller, the request is checked against the sign limits and policies
+// and the outcome is published and notified. Internal callers are only checked against the
+// policies.
+func (m *MuxAgent) signFor(
+	caller signCaller, key ssh.PublicKey, data []byte, flags agent.SignatureFlags,
+) (*ssh.Signature, error) {
+	if caller.internal {
+		if err := m.checkSignRequest(key, data); err != nil {
+			return nil, err
 		}
 
-		// Handle signature flags
-		if flags != 0 {
-			if algoSigner, ok := signer.(ssh.AlgorithmSigner); ok {
-				var algorithm string
-				switch flags {
-				case agent.SignatureFlagRsaSha256:
-					algorithm = ssh.KeyAlgoRSASHA256
-				case agent.SignatureFlagRsaSha512:
-					algorithm = ssh.KeyAlgoRSASHA512
-				case agent.SignatureFlagReserved:
-					algorithm = key.Type()
-				default:
-					algorithm = key.Type()
-				}
-				return algoSigner.SignWithAlgorithm(rand.Reader, data, algorithm)
-			}
+		sig, _, err := m.signWithSource(key, data, flags)
+		return sig, err
+	}
+
+	if !caller.limitsChecked {
+		if err := m.checkSignLimits(caller, key); err != nil {
+			m.signDenied(caller, key, err)
+			return nil, err
 		}
+	}
 
-		return signer.Sign(rand.Reader, data)
+	if err := m.checkSignRequest(key, data); err != nil {
+		m.signDenied(caller, key, err)
+		return nil, err
 	}
 
-	var returnedSig *ssh.Signature
-	if err := m.runAgainstBackends(func(fb agent.ExtendedAgent) error {
+	sig, source, err := m.signWithSource(key, data, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	m.emitSignEvent(api.EventType_EVENT_TYPE_SIGN_SERVED, key, signEventDetails{Source: source.String()})
+	m.notifySign(caller, key, source)
+
+	return sig, nil
+}
+
+// signWithSource signs data with the first of the local keys, the token keys and the backend agents
+// holding the key, it returns where the key was found.
+func (m *MuxAgent) signWithSource(
+	key ssh.PublicKey, data []byte, flags agent.SignatureFlags,
+) (*ssh.Signature, KeySource, error) {
+	// Try local keys first
+	if localSig, err := signWithStore(m.ctx, m.localKeys, m.skAuthenticator, key.Marshal(), data, flags); err == nil {
+		return localSig, KeySource{Local: true}, nil
+	} else if !errors.Is(err, keystore.ErrKeyNotFound) {
+		return nil, KeySource{}, err
+	}
+
+	if tokenSig, provider, err := m.signWithToken(key, data, flags); err == nil {
+		return tokenSig, KeySource{Token: provider}, nil
+	} else if !errors.Is(err, keystore.ErrKeyNotFound) {
+		return nil, KeySource{}, err
+	}
+
+	var (
+		returnedSig *ssh.Signature
+		source      KeySource
+	)
+	if err := m.runAgainstBackends(func(socketPath string, fb agent.ExtendedAgent) error {
 		sig, err := fb.SignWithFlags(key, data, flags)
 		if err != nil {
 			return err
@@ -218,45 +404,43 @@ func (m *MuxAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.Sig
 				slog.String("key-type", key.Type()),
 			)
 			returnedSig = sig
+			source = KeySource{Backend: socketPath}
 			return errExitBackendLoop
 		}
 		return errors.New("key not found in backend agent")
 	}); errors.Is(err, errExitBackendLoop) {
-		return returnedSig, nil
+		return returnedSig, source, nil
 	}
 
-	return nil, errors.New("key not found")
+	return nil, KeySource{}, errors.New("key not found")
 }
 
 // Add adds a private key to the local agent.
 func (m *MuxAgent) Add(key agent.AddedKey) error {
-	m.logger.DebugContext(m.ctx, "Add called with key comment", slog.String("key-comment", key.Comment))
+	return m.AddWithOptions(key)
+}
 
-	m.keysMutex.Lock()
-	defer m.keysMutex.Unlock()
+// AddWithOptions adds a private key to the local agent with additional key properties.
+func (m *MuxAgent) AddWithOptions(key agent.AddedKey, opts ...keystore.KeyOption) error {
+	m.logger.DebugContext(m.ctx, "Add called with key comment", slog.String("key-comment", key.Comment))
 
-	// Convert PrivateKey interface{} to crypto.Signer to get public key
-	signer, ok := key.PrivateKey.(crypto.Signer)
-	if !ok {
-		return errors.New("private key does not implement crypto.Signer")
+	if err := checkAddConstraints(key); err != nil {
+		return err
 	}
 
-	pubKey := signer.Public()
-	sshPubKey, err := ssh.NewPublicKey(pubKey)
+	sshPubKey, err := m.localKeys.Add(key, opts...)
 	if err != nil {
-		return fmt.Errorf("failed to convert public key: %w", err)
+		return err
 	}
 
-	keyBlob := sshPubKey.Marshal()
-	keyString := string(keyBlob)
-
-	m.logger.DebugContext(m.ctx, "Storing local key with comment",
-		slog.String("key-string", keyString),
+	m.logger.DebugContext(m.ctx, "Stored local key with comment",
+		slog.String("key-fingerprint", ssh.FingerprintSHA256(sshPubKey)),
 		slog.String("key-type", sshPubKey.Type()),
 		slog.String("key-comment", key.Comment),
 	)
 
-	m.localKeys[keyString] = &key
+	m.emitKeyEvent(api.EventType_EVENT_TYPE_KEY_ADDED, sshPubKey, key.Comment)
+	m.wakeCertRenewer()
 
 	return nil
 }
@@ -265,25 +449,27 @@ func (m *MuxAgent) Add(key agent.AddedKey) error {
 func (m *MuxAgent) Remove(key ssh.PublicKey) error {
 	m.logger.DebugContext(m.ctx, "Remove called with key", slog.String("key-type", key.Type()))
 
-	m.keysMutex.Lock()
-	defer m.keysMutex.Unlock()
-
-	keyBlob := key.Marshal()
-	keyString := string(keyBlob)
+	if err := m.localKeys.Remove(key.Marshal()); err != nil {
+		m.logger.DebugContext(m.ctx, "Key to remove not found in local keys", slog.String("key-type", key.Type()))
+		return nil
+	}
 
-	delete(m.localKeys, keyString)
+	m.emitKeyEvent(api.EventType_EVENT_TYPE_KEY_REMOVED, key, "")
 
 	return nil
 }
 
-// RemoveAll removes all keys from the local agent.
+// RemoveAll removes all keys from the local agent and unloads all PKCS#11 modules, like ssh-agent.
 func (m *MuxAgent) RemoveAll() error {
 	m.logger.DebugContext(m.ctx, "RemoveAll called")
 
-	m.keysMutex.Lock()
-	defer m.keysMutex.Unlock()
+	removed := m.localKeys.ListInfo()
+	m.localKeys.RemoveAll()
+	m.removeAllTokens()
 
-	m.localKeys = make(map[string]*agent.AddedKey)
+	for _, info := range removed {
+		m.emitKeyEvent(api.EventType_EVENT_TYPE_KEY_REMOVED, info.Key, info.Key.Comment)
+	}
 
 	return nil
 }
@@ -306,19 +492,17 @@ func (m *MuxAgent) Unlock(_ []byte) error {
 func (m *MuxAgent) Signers() ([]ssh.Signer, error) {
 	m.logger.DebugContext(m.ctx, "Signers called")
 
-	m.keysMutex.RLock()
-	defer m.keysMutex.RUnlock()
-
-	signers := make([]ssh.Signer, 0, len(m.localKeys))
-	for _, addedKey := range m.localKeys {
-		signer, err := ssh.NewSignerFromKey(addedKey.PrivateKey)
-		if err != nil {
-			continue
-		}
-		signers = append(signers, signer)
+	localKeys := m.localKeys.PublicKeys()
+	signers := make([]ssh.Signer, 0, len(localKeys))
+	for _, pubKey := range localKeys {
+		signers = append(signers, &localSigner{
+			ctx: m.ctx, store: m.localKeys, auth: m.skAuthenticator, pubKey: pubKey,
+		})
 	}
 
-	if err := m.runAgainstBackends(func(fb agent.ExtendedAgent) error {
+	signers = append(signers, m.tokenSigners()...)
+
+	if err := m.runAgainstBackends(func(_ string, fb agent.ExtendedAgent) error {
 		// Add signers from backend agent
 		backendSigners, err := fb.Signers()
 		if err != nil {
@@ -333,7 +517,7 @@ func (m *MuxAgent) Signers() ([]ssh.Signer, error) {
 	return signers, nil
 }
 
-// Extension processes extension requests (not implemented).
+// Extension answers the registered mux extensions and forwards all others to the backend agents.
 func (m *MuxAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
 	m.logger.DebugContext(m.ctx, "Extension called with type", slog.String("extension-type", extensionType))
 
@@ -344,7 +528,26 @@ func (m *MuxAgent) Extension(extensionType string, contents []byte) ([]byte, err
 		}
 	}
 
-	if err := m.runAgainstBackends(func(fb agent.ExtendedAgent) error {
+	return m.backendExtension(extensionType, contents)
+}
+
+// backendExtension forwards an extension request to the backend agent that advertised it with the
+// query extension, or otherwise to the first backend agent that supports it.
+func (m *MuxAgent) backendExtension(extensionType string, contents []byte) ([]byte, error) {
+	if socketPath, ok := m.extensionRoute(extensionType); ok {
+		if fb, fbClose, err := m.backendConnect(socketPath); err == nil {
+			defer fbClose()
+
+			m.logger.DebugContext(m.ctx, "Routing extension to advertising backend agent",
+				slog.String("extension-type", extensionType),
+				slog.String("socket-path", socketPath),
+			)
+
+			return fb.Extension(extensionType, contents)
+		}
+	}
+
+	if err := m.runAgainstBackends(func(_ string, fb agent.ExtendedAgent) error {
 		resp, err := fb.Extension(extensionType, contents)
 		if err != nil {
 			return err
@@ -367,64 +570,9 @@ func (m *MuxAgent) Extension(extensionType string, contents []byte) ([]byte, err
 func (m *MuxAgent) handleMuxExtension(extensionType string, contents []byte) ([]byte, error) {
 	m.logger.DebugContext(m.ctx, "handleMuxExtension called with type", slog.String("extension-type", extensionType))
 
-	switch extensionType {
-	case "ping":
-		return HandleExtensionProto(contents, m.handlePing)
-	case "config":
-		return HandleExtensionProto(contents, m.handleConfig)
-	case "shutdown":
-		defer m.ctx.Cancel()
-		return HandleExtensionProto(contents, m.handleShutdown)
+	if ext, ok := m.extensions.lookup(extensionType); ok {
+		return ext.handler(contents)
 	}
 
-	// Handle custom extensions here
 	return nil, agent.ErrExtensionUnsupported
 }
-
-func (m *MuxAgent) handlePing(msg *api.Ping) (*api.Pong, error) {
-	m.logger.DebugContext(m.ctx, "handlePing called", slog.String("msg-id", msg.GetId()))
-
-	pong := api.Pong_builder{
-		Id:        proto.String(msg.GetId()),
-		PingTs:    msg.GetTs(),
-		Ts:        timestamppb.Now(),
-		Pid:       proto.Int64(int64(syscall.Getpid())),
-		StartTime: m.config.GetStartTime(),
-		Version:   proto.String(cliversion.Get().VersionString()),
-	}.Build()
-
-	return pong, nil
-}
-
-func (m *MuxAgent) handleShutdown(msg *api.ShutdownRequest) (*api.CommandResponse, error) {
-	m.logger.DebugContext(m.ctx, "handleShutdown called", slog.String("msg-id", msg.GetId()))
-
-	resp := api.CommandResponse_builder{
-		Id:      proto.String(msg.GetId()),
-		Ts:      msg.GetTs(),
-		Success: proto.Bool(true),
-		Message: proto.String(
-			fmt.Sprintf("shutdown received for pid %d", os.Getpid()),
-		),
-	}.Build()
-
-	return resp, nil
-}
-
-func (m *MuxAgent) handleConfig(msg *api.ConfigRequest) (*api.Config, error) {
-	m.logger.DebugContext(m.ctx, "handleConfig called", slog.String("msg-id", msg.GetId()))
-
-	var cfg *api.Config
-	{
-		var ok bool
-		cfg, ok = proto.Clone(m.config).(*api.Config)
-		if !ok {
-			return nil, errors.New("failed to clone config")
-		}
-	}
-
-	cfg.SetId(uuid.NewString())
-	cfg.SetTs(timestamppb.Now())
-
-	return cfg, nil
-}
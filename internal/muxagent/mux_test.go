@@ -3,13 +3,18 @@ package muxagent_test
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"errors"
+	"io"
 	"log/slog"
+	"net"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/na4ma4/go-contextual"
 	"github.com/na4ma4/go-permbits"
 	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
 	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
@@ -262,3 +267,105 @@ func TestSignWithNonExistentKey(t *testing.T) {
 		t.Error("Expected error when signing with non-existent key, got nil")
 	}
 }
+
+func TestForceShutdownInterruptsConnections(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	conns := muxAgent.Connections()
+	conns.Add(server)
+
+	// A connection whose handler never returns, like one stuck in a backend sign, must be closed.
+	stuckServer, stuckClient := net.Pipe()
+	defer stuckClient.Close()
+	conns.Add(stuckServer)
+
+	stuckClosed := make(chan error, 1)
+	go func() {
+		_, readErr := stuckClient.Read(make([]byte, 1))
+		stuckClosed <- readErr
+		conns.Done(stuckServer)
+	}()
+
+	served := make(chan error, 1)
+	go func() {
+		defer conns.Done(server)
+		defer server.Close()
+		served <- agentproto.NewServer(muxAgent).Serve(server)
+	}()
+
+	resp, err := muxagent.HandleExtensionProtoInvert[api.ShutdownRequest, api.CommandResponse](
+		api.ShutdownRequest_builder{
+			Id:    proto.String("test"),
+			Force: proto.Bool(true),
+		}.Build(),
+		func(inBytes []byte) ([]byte, error) {
+			return agent.NewClient(client).Extension("shutdown", inBytes)
+		},
+	)
+	if err != nil {
+		t.Fatalf("Failed to send shutdown: %v", err)
+	}
+
+	if resp.GetConnectionsTerminated() != 2 {
+		t.Errorf("Expected 2 connections terminated, got %d", resp.GetConnectionsTerminated())
+	}
+
+	select {
+	case err = <-served:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Connection was not interrupted by forced shutdown")
+	}
+
+	select {
+	case err = <-stuckClosed:
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("Expected the stuck connection to be closed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stuck connection was not closed by forced shutdown")
+	}
+
+	if err = conns.Wait(t.Context()); err != nil {
+		t.Errorf("Failed waiting for connections to drain: %v", err)
+	}
+}
+
+func TestCloseWipesLocalKeys(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if err = muxAgent.Add(agent.AddedKey{PrivateKey: privateKey, Comment: "test-key"}); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	if err = muxAgent.Close(); err != nil {
+		t.Fatalf("Failed to close mux agent: %v", err)
+	}
+
+	if len(muxAgent.GetLocalKeys()) != 0 {
+		t.Errorf("Expected no local keys after close, got %d", len(muxAgent.GetLocalKeys()))
+	}
+
+	for _, b := range privateKey {
+		if b != 0 {
+			t.Fatal("Expected private key material to be wiped after close")
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package muxagent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/internal/keystore"
+	"github.com/na4ma4/ssh-agent-mux/internal/listener"
+	"github.com/na4ma4/ssh-agent-mux/internal/notify"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// defaultSignNotifyThrottle is the default minimum time between notifications for a key.
+	defaultSignNotifyThrottle = 10 * time.Second
+	// signNotifyTimeout limits how long a notification may take.
+	signNotifyTimeout = 10 * time.Second
+)
+
+// SignNotifyOptions configures the notifications shown when keys sign requests.
+type SignNotifyOptions struct {
+	// ForwardedOnly limits the notifications to sign requests served through forwarding frontends.
+	ForwardedOnly bool
+	// Throttle is the minimum time between notifications for the same key, signatures in between
+	// are not notified. It defaults to 10s.
+	Throttle time.Duration
+}
+
+// WithSignNotifier shows a notification when a local, token or backend key signs a request.
+func WithSignNotifier(notifier notify.Notifier, opts SignNotifyOptions) Option {
+	return func(m *MuxAgent) {
+		if opts.Throttle <= 0 {
+			opts.Throttle = defaultSignNotifyThrottle
+		}
+
+		m.signNotifier = notifier
+		m.signNotifyOpts = opts
+	}
+}
+
+// signCaller identifies where a sign request came from, the zero value is a request made
+// directly on the mux agent.
+type signCaller struct {
+	// Frontend is the name of the listener the request came in on.
+	Frontend string
+	// Forwarded is set for requests served through a forwarding frontend.
+	Forwarded bool
+	// Peer is the requesting process, nil when it is unknown.
+	Peer *Peer
+
+	// limitsChecked is set once the sign limits were applied to the request.
+	limitsChecked bool
+	// internal is set for signatures the agent makes for itself, like certificate renewals, they
+	// skip the sign limits, events and notifications.
+	internal bool
+}
+
+// signNotifications tracks when each key last notified, so a key used in a burst notifies once.
+type signNotifications struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// allow reports whether a key may notify now and records the notification.
+func (n *signNotifications) allow(fingerprint string, now time.Time, throttle time.Duration) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if last, ok := n.last[fingerprint]; ok && now.Sub(last) < throttle {
+		return false
+	}
+
+	if n.last == nil {
+		n.last = make(map[string]time.Time)
+	}
+	n.last[fingerprint] = now
+
+	return true
+}
+
+// notifySign shows a notification for a served sign request, the notification is sent in the
+// background so a slow notification server never delays the signature.
+func (m *MuxAgent) notifySign(caller signCaller, key ssh.PublicKey, source KeySource) {
+	if m.signNotifier == nil || (m.signNotifyOpts.ForwardedOnly && !caller.Forwarded) {
+		return
+	}
+
+	fingerprint := ssh.FingerprintSHA256(key)
+	if !m.signNotifications.allow(fingerprint, time.Now(), m.signNotifyOpts.Throttle) {
+		m.logger.DebugContext(m.ctx, "Sign notification throttled", slog.String("key-fingerprint", fingerprint))
+		return
+	}
+
+	go func() {
+		n := m.signNotification(caller, key, source)
+
+		ctx, cancel := context.WithTimeout(m.ctx, signNotifyTimeout)
+		defer cancel()
+
+		if err := m.signNotifier.Notify(ctx, n); err != nil {
+			m.logger.WarnContext(m.ctx, "Failed to send sign notification",
+				slog.String("key-fingerprint", fingerprint),
+				slogtool.ErrorAttr(err),
+			)
+		}
+	}()
+}
+
+// signNotification describes a served sign request.
+func (m *MuxAgent) signNotification(caller signCaller, key ssh.PublicKey, source KeySource) notify.Notification {
+	n := notify.Notification{
+		Summary:        "SSH key used",
+		KeyFingerprint: ssh.FingerprintSHA256(key),
+		KeySource:      source.String(),
+		Frontend:       caller.Frontend,
+	}
+
+	keyName := n.KeyFingerprint
+	if source.Local {
+		_ = m.localKeys.Use(key.Marshal(), func(k *keystore.Key) error {
+			if k.Comment != "" {
+				keyName = k.Comment
+			}
+			return nil
+		})
+	}
+
+	requester := "a client"
+	if caller.Peer != nil && caller.Peer.PID > 0 {
+		n.PeerPID = caller.Peer.PID
+		requester = fmt.Sprintf("pid %d", caller.Peer.PID)
+
+		if name, err := listener.ProcessName(caller.Peer.PID); err == nil {
+			n.PeerProcess = name
+			requester = fmt.Sprintf("%s (pid %d)", name, caller.Peer.PID)
+		}
+	}
+
+	n.Body = fmt.Sprintf("%s signed a request from %s", keyName, requester)
+	if !source.Local {
+		n.Body += " with " + n.KeySource
+	}
+	if caller.Forwarded {
+		n.Summary = "SSH key used through forwarded agent"
+		n.Body += fmt.Sprintf(" on %q", caller.Frontend)
+	}
+
+	return n
+}
@@ -0,0 +1,115 @@
+package muxagent_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/internal/listener"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/na4ma4/ssh-agent-mux/internal/notify"
+)
+
+type chanNotifier chan notify.Notification
+
+func (c chanNotifier) Notify(_ context.Context, n notify.Notification) error {
+	c <- n
+	return nil
+}
+
+func nextNotification(t *testing.T, notifications chanNotifier) notify.Notification {
+	t.Helper()
+
+	select {
+	case n := <-notifications:
+		return n
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for notification")
+		return notify.Notification{}
+	}
+}
+
+func TestSignNotificationsAreThrottledPerKey(t *testing.T) {
+	notifications := make(chanNotifier, 10)
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSignNotifier(notifications, muxagent.SignNotifyOptions{Throttle: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	deployKey := addTestKey(t, muxAgent, "deploy")
+	otherKey := addTestKey(t, muxAgent, "other")
+
+	session := muxAgent.NewSession(
+		muxAgent.NewView(muxagent.ViewOptions{Name: "main"}),
+		&muxagent.Peer{UID: uint32(os.Getuid()), PID: os.Getpid()}, //nolint:gosec // uids are not negative
+	)
+
+	if _, err = session.Sign(deployKey, []byte("request")); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	n := nextNotification(t, notifications)
+	if !strings.Contains(n.Body, "deploy") || n.KeySource != muxagent.KeySourceLocal || n.Frontend != "main" {
+		t.Errorf("Expected notification for the local deploy key on main, got %+v", n)
+	}
+
+	if name, _ := listener.ProcessName(os.Getpid()); n.PeerPID != os.Getpid() || n.PeerProcess != name {
+		t.Errorf("Expected requesting process %s (%d), got %q (%d)", name, os.Getpid(), n.PeerProcess, n.PeerPID)
+	}
+
+	// The second signature with the deploy key is throttled, the next notification is for the other key.
+	if _, err = session.Sign(deployKey, []byte("request")); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if _, err = session.Sign(otherKey, []byte("request")); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	if n = nextNotification(t, notifications); !strings.Contains(n.Body, "other") {
+		t.Errorf("Expected notification for the other key, got %+v", n)
+	}
+}
+
+func TestSignNotificationsForwardedOnly(t *testing.T) {
+	notifications := make(chanNotifier, 10)
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSignNotifier(notifications, muxagent.SignNotifyOptions{ForwardedOnly: true}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	deployKey := addTestKey(t, muxAgent, "deploy")
+
+	if _, err = muxAgent.NewView(muxagent.ViewOptions{Name: "main"}).Sign(deployKey, []byte("request")); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	forwarded := muxAgent.NewView(muxagent.ViewOptions{
+		Name: "forwarded",
+		Forwarding: muxagent.ForwardingOptions{
+			Enabled:     true,
+			Forwardable: muxagent.KeyPolicy{Comments: []string{"deploy"}},
+			Confirmer:   &stubConfirmer{approve: true},
+		},
+	})
+	if _, err = forwarded.Sign(deployKey, []byte("request")); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	if n := nextNotification(t, notifications); n.Frontend != "forwarded" {
+		t.Errorf("Expected only the forwarded signature to notify, got %+v", n)
+	}
+}
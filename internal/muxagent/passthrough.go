@@ -0,0 +1,69 @@
+package muxagent
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
+)
+
+var (
+	// ErrNoPrimaryBackend indicates that unrecognised requests are not passed through as no
+	// primary backend agent is configured.
+	ErrNoPrimaryBackend = errors.New("no primary backend agent configured")
+	// ErrPassthroughFiltered indicates that a frontend restricting keys does not pass through
+	// unrecognised requests, as they cannot be checked against its key policy.
+	ErrPassthroughFiltered = errors.New("unrecognised requests are not passed through filtered frontends")
+)
+
+// Passthrough forwards a request the mux does not recognise verbatim to the primary backend agent
+// and returns its raw reply, so vendor specific agent messages keep working through the mux.
+func (m *MuxAgent) Passthrough(req []byte) ([]byte, error) {
+	socketPath := m.config.GetPrimaryBackendSocketPath()
+	if socketPath == "" {
+		return nil, ErrNoPrimaryBackend
+	}
+
+	m.logger.DebugContext(m.ctx, "Passing request through to primary backend agent",
+		slog.String("socket-path", socketPath),
+		slog.Int("message-type", int(req[0])),
+	)
+
+	conn, err := (&net.Dialer{}).DialContext(m.ctx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to primary backend agent: %w", err)
+	}
+	defer conn.Close()
+
+	if err = conn.SetDeadline(time.Now().Add(backendRequestTimeout)); err != nil {
+		return nil, fmt.Errorf("failed to set primary backend agent deadline: %w", err)
+	}
+
+	if err = agentproto.WriteMessage(conn, req); err != nil {
+		return nil, fmt.Errorf("failed to forward request to primary backend agent: %w", err)
+	}
+
+	reply, err := agentproto.ReadMessage(conn, agentproto.DefaultMaxMessageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reply from primary backend agent: %w", err)
+	}
+
+	return reply, nil
+}
+
+// Passthrough forwards an unrecognised request to the primary backend agent, only writable views
+// without a key policy pass requests through.
+func (v *View) Passthrough(req []byte) ([]byte, error) {
+	if v.opts.ReadOnly {
+		return nil, fmt.Errorf("passing request through: %w", ErrReadOnly)
+	}
+
+	if !v.opts.Keys.empty() {
+		return nil, ErrPassthroughFiltered
+	}
+
+	return v.mux.Passthrough(req)
+}
@@ -0,0 +1,103 @@
+package muxagent_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+)
+
+// startVendorBackend starts an agent that answers every request with the message type incremented
+// and the rest of the request echoed.
+func startVendorBackend(t *testing.T) string {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "backend.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				for {
+					req, readErr := agentproto.ReadMessage(conn, agentproto.DefaultMaxMessageSize)
+					if readErr != nil {
+						return
+					}
+
+					_ = agentproto.WriteMessage(conn, append([]byte{req[0] + 1}, req[1:]...))
+				}
+			}()
+		}
+	}()
+
+	return socketPath
+}
+
+func TestServerPassesUnknownRequestsToPrimaryBackend(t *testing.T) {
+	config := defaultConfig()
+	config.SetPrimaryBackendSocketPath(startVendorBackend(t))
+
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), config)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	req := []byte{240, 'v', 'e', 'n', 'd', 'o', 'r'}
+	server := agentproto.NewServer(muxAgent)
+
+	if reply := server.ServeMessage(req); !bytes.Equal(reply, []byte{241, 'v', 'e', 'n', 'd', 'o', 'r'}) {
+		t.Errorf("Expected the backend reply to be relayed, got %v", reply)
+	}
+
+	// Recognised requests are still answered by the mux.
+	if reply := server.ServeMessage([]byte{agentproto.MsgRequestIdentities}); reply[0] != agentproto.MsgIdentitiesAnswer {
+		t.Errorf("Expected the mux to answer the identities request, got %d", reply[0])
+	}
+
+	filtered := muxAgent.NewView(muxagent.ViewOptions{
+		Name: "filtered",
+		Keys: muxagent.KeyPolicy{Comments: []string{"work-*"}},
+	})
+	if _, err = filtered.Passthrough(req); !errors.Is(err, muxagent.ErrPassthroughFiltered) {
+		t.Errorf("Expected ErrPassthroughFiltered, got %v", err)
+	}
+
+	readOnly := muxAgent.NewView(muxagent.ViewOptions{Name: "ro", ReadOnly: true})
+	if reply := agentproto.NewServer(readOnly).ServeMessage(req); reply[0] != agentproto.MsgFailure {
+		t.Errorf("Expected a read-only view to fail the request, got %d", reply[0])
+	}
+}
+
+func TestServerFailsUnknownRequestsWithoutPrimaryBackend(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	if _, err = muxAgent.Passthrough([]byte{240}); !errors.Is(err, muxagent.ErrNoPrimaryBackend) {
+		t.Errorf("Expected ErrNoPrimaryBackend, got %v", err)
+	}
+
+	if reply := agentproto.NewServer(muxAgent).ServeMessage([]byte{240}); reply[0] != agentproto.MsgFailure {
+		t.Errorf("Expected SSH_AGENT_FAILURE, got %d", reply[0])
+	}
+}
@@ -0,0 +1,391 @@
+package muxagent
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/listener"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// defaultSignLockWindow is the default window in which denied sign requests count toward the lockout.
+	defaultSignLockWindow = time.Minute
+	// signLimitMaxEntries is the number of keys or peers tracked before idle entries are pruned.
+	signLimitMaxEntries = 1024
+	// signLimitIdle is how long an entry without requests is kept once pruning starts.
+	signLimitIdle = 10 * time.Minute
+)
+
+// defaultForwardedSignRateLimit limits the sign requests through each forwarding frontend when
+// SignLimits.Forwarded is not set.
+//
+//nolint:gochecknoglobals // default limit
+var defaultForwardedSignRateLimit = SignRateLimit{PerMinute: 10, Burst: 3}
+
+// ErrSigningLocked indicates that sign requests are refused after too many rate limited requests.
+var ErrSigningLocked = errors.New("signing is locked after too many rate limited sign requests")
+
+// SignRateLimit is a token bucket limit on sign requests.
+type SignRateLimit struct {
+	// PerMinute is the sustained request rate, zero disables the limit.
+	PerMinute float64 `mapstructure:"per-minute"`
+	// Burst is the number of requests allowed at once, it defaults to PerMinute rounded up.
+	Burst int `mapstructure:"burst"`
+}
+
+// enabled reports whether the limit applies.
+func (l SignRateLimit) enabled() bool {
+	return l.PerMinute > 0
+}
+
+// newLimiter returns a token bucket for the limit.
+func (l SignRateLimit) newLimiter() *rate.Limiter {
+	burst := l.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(l.PerMinute))
+	}
+
+	return rate.NewLimiter(rate.Limit(l.PerMinute/time.Minute.Seconds()), burst)
+}
+
+// SignLimits protects the keys from clients making too many sign requests, eg. a compromised
+// forwarded session.
+type SignLimits struct {
+	// PerKey limits the sign requests for each key.
+	PerKey SignRateLimit `mapstructure:"per-key"`
+	// PerPeer limits the sign requests of each client process, clients without peer credentials
+	// share a limit per frontend.
+	PerPeer SignRateLimit `mapstructure:"per-peer"`
+	// Forwarded limits the sign requests through each forwarding frontend, shared by all of its
+	// clients. Forwarding frontends are always limited, it defaults to 10 per minute with a burst
+	// of 3.
+	Forwarded SignRateLimit `mapstructure:"forwarded"`
+	// LockAfter locks signing after this many rate limited requests within LockWindow, zero
+	// disables the lockout. Requests refused by a sign policy, a frontend or the user do not
+	// count, so a client cannot lock signing by asking for keys it may not use. The lockout is
+	// global: a single client exceeding its rate limits locks signing for every frontend.
+	LockAfter int `mapstructure:"lock-after"`
+	// LockWindow is the window rate limited requests are counted in, it defaults to 1m.
+	LockWindow time.Duration `mapstructure:"lock-window"`
+	// LockDuration is how long signing stays locked, zero keeps it locked until it is unlocked
+	// with the Unlock control method.
+	LockDuration time.Duration `mapstructure:"lock-duration"`
+}
+
+// WithSignLimits rate limits sign requests and locks signing after repeated denied requests.
+func WithSignLimits(limits SignLimits) Option {
+	return func(m *MuxAgent) {
+		if limits.LockWindow <= 0 {
+			limits.LockWindow = defaultSignLockWindow
+		}
+
+		m.signLimits.limits = limits
+	}
+}
+
+// signPeerState is the rate limit and denial history of a client.
+type signPeerState struct {
+	limiter     *rate.Limiter
+	peer        *Peer
+	frontend    string
+	process     string
+	rateLimited int64
+	denied      int64
+	lastDenied  time.Time
+	lastSeen    time.Time
+}
+
+// signBucket is the rate limit of a key or a forwarding frontend.
+type signBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// signLimiter applies the sign limits and tracks the lockout.
+type signLimiter struct {
+	limits SignLimits
+
+	mu        sync.Mutex
+	keys      map[string]*signBucket
+	frontends map[string]*signBucket
+	peers     map[string]*signPeerState
+	denials   []time.Time
+
+	locked      bool
+	lockedUntil time.Time
+	lockReason  string
+}
+
+// peerID identifies the client of a sign request for the per-peer limits.
+func (c signCaller) peerID() string {
+	if c.Peer != nil && c.Peer.PID > 0 {
+		return fmt.Sprintf("pid %d", c.Peer.PID)
+	}
+
+	if c.Frontend != "" {
+		return c.Frontend
+	}
+
+	return "direct"
+}
+
+// peerState returns the state of the caller, the caller must hold the lock.
+func (l *signLimiter) peerState(caller signCaller, now time.Time) *signPeerState {
+	id := caller.peerID()
+
+	state, ok := l.peers[id]
+	if !ok {
+		if l.peers == nil {
+			l.peers = make(map[string]*signPeerState)
+		}
+		if len(l.peers) >= signLimitMaxEntries {
+			maps.DeleteFunc(l.peers, func(_ string, s *signPeerState) bool {
+				return now.Sub(s.lastSeen) > signLimitIdle
+			})
+		}
+
+		state = &signPeerState{peer: caller.Peer, frontend: caller.Frontend}
+		if l.limits.PerPeer.enabled() {
+			state.limiter = l.limits.PerPeer.newLimiter()
+		}
+		l.peers[id] = state
+	}
+	state.lastSeen = now
+
+	return state
+}
+
+// bucket returns the rate limit of id in buckets, creating it with limit, the caller must hold
+// the lock.
+func bucket(buckets *map[string]*signBucket, id string, limit SignRateLimit, now time.Time) *signBucket {
+	state, ok := (*buckets)[id]
+	if !ok {
+		if *buckets == nil {
+			*buckets = make(map[string]*signBucket)
+		}
+		if len(*buckets) >= signLimitMaxEntries {
+			maps.DeleteFunc(*buckets, func(_ string, s *signBucket) bool {
+				return now.Sub(s.lastSeen) > signLimitIdle
+			})
+		}
+
+		state = &signBucket{limiter: limit.newLimiter()}
+		(*buckets)[id] = state
+	}
+	state.lastSeen = now
+
+	return state
+}
+
+// forwardedLimit returns the limit of the forwarding frontends.
+func (l *signLimiter) forwardedLimit() SignRateLimit {
+	if l.limits.Forwarded.enabled() {
+		return l.limits.Forwarded
+	}
+
+	return defaultForwardedSignRateLimit
+}
+
+// checkSignLimits refuses sign requests while signing is locked and applies the per-peer,
+// forwarding frontend and per-key rate limits.
+func (m *MuxAgent) checkSignLimits(caller signCaller, key ssh.PublicKey) error {
+	l := &m.signLimits
+	now := time.Now()
+
+	l.mu.Lock()
+
+	if l.locked && !l.lockedUntil.IsZero() && now.After(l.lockedUntil) {
+		l.unlockLocked()
+		l.mu.Unlock()
+		m.signingUnlocked("lock expired")
+		l.mu.Lock()
+	}
+
+	if l.locked {
+		l.mu.Unlock()
+		return ErrSigningLocked
+	}
+
+	peer := l.peerState(caller, now)
+	peerLimited := peer.limiter != nil && !peer.limiter.AllowN(now, 1)
+
+	frontendLimited := false
+	if !peerLimited && caller.Forwarded {
+		frontendLimited = !bucket(&l.frontends, caller.Frontend, l.forwardedLimit(), now).limiter.AllowN(now, 1)
+	}
+
+	keyLimited := false
+	if !peerLimited && !frontendLimited && l.limits.PerKey.enabled() {
+		keyLimited = !bucket(&l.keys, ssh.FingerprintSHA256(key), l.limits.PerKey, now).limiter.AllowN(now, 1)
+	}
+
+	if peerLimited || frontendLimited || keyLimited {
+		peer.rateLimited++
+	}
+
+	l.mu.Unlock()
+
+	switch {
+	case peerLimited:
+		return fmt.Errorf("%w for %s", ErrRateLimited, caller.peerID())
+	case frontendLimited:
+		return fmt.Errorf("%w for forwarding frontend %s", ErrRateLimited, caller.Frontend)
+	case keyLimited:
+		return fmt.Errorf("%w for key %s", ErrRateLimited, ssh.FingerprintSHA256(key))
+	default:
+		return nil
+	}
+}
+
+// signDenied publishes a denied sign request and locks signing when too many requests were rate
+// limited within the lock window. Other denials are only recorded against the peer, counting
+// them would let any restricted frontend lock signing for all of them.
+func (m *MuxAgent) signDenied(caller signCaller, key ssh.PublicKey, err error) {
+	m.emitSignEvent(api.EventType_EVENT_TYPE_SIGN_DENIED, key, signEventDetails{
+		Reason:   err.Error(),
+		Frontend: caller.Frontend,
+	})
+
+	if errors.Is(err, ErrSigningLocked) {
+		return
+	}
+
+	// The process name is read from the system, look it up before taking the lock.
+	var process string
+	if caller.Peer != nil {
+		process, _ = listener.ProcessName(caller.Peer.PID)
+	}
+
+	l := &m.signLimits
+	now := time.Now()
+
+	l.mu.Lock()
+
+	peer := l.peerState(caller, now)
+	peer.denied++
+	peer.lastDenied = now
+	if peer.process == "" {
+		peer.process = process
+	}
+
+	lockNow := false
+	if l.limits.LockAfter > 0 && !l.locked && errors.Is(err, ErrRateLimited) {
+		l.denials = append(slices.DeleteFunc(l.denials, func(t time.Time) bool {
+			return now.Sub(t) > l.limits.LockWindow
+		}), now)
+
+		if len(l.denials) >= l.limits.LockAfter {
+			lockNow = true
+			l.locked = true
+			l.lockReason = fmt.Sprintf("%d rate limited sign requests within %s", len(l.denials), l.limits.LockWindow)
+			l.denials = nil
+			if l.limits.LockDuration > 0 {
+				l.lockedUntil = now.Add(l.limits.LockDuration)
+			}
+		}
+	}
+
+	reason, until := l.lockReason, l.lockedUntil
+
+	l.mu.Unlock()
+
+	if lockNow {
+		m.logger.WarnContext(m.ctx, "Signing locked",
+			slog.String("lock-reason", reason),
+			slog.Time("locked-until", until),
+			slog.String("peer", caller.peerID()),
+		)
+
+		ev := newEvent(api.EventType_EVENT_TYPE_SIGNING_LOCKED)
+		ev.Reason = proto.String(reason)
+		m.events.publish(ev.Build())
+	}
+}
+
+// unlockLocked clears the lockout, the caller must hold the lock.
+func (l *signLimiter) unlockLocked() {
+	l.locked = false
+	l.lockedUntil = time.Time{}
+	l.lockReason = ""
+	l.denials = nil
+}
+
+// UnlockSigning clears the sign lockout, it reports whether signing was locked.
+func (m *MuxAgent) UnlockSigning() bool {
+	l := &m.signLimits
+
+	l.mu.Lock()
+	wasLocked := l.locked
+	l.unlockLocked()
+	l.mu.Unlock()
+
+	if wasLocked {
+		m.signingUnlocked("unlocked by control request")
+	}
+
+	return wasLocked
+}
+
+// signingUnlocked publishes the end of the lockout.
+func (m *MuxAgent) signingUnlocked(reason string) {
+	m.logger.InfoContext(m.ctx, "Signing unlocked", slog.String("reason", reason))
+
+	ev := newEvent(api.EventType_EVENT_TYPE_SIGNING_UNLOCKED)
+	ev.Reason = proto.String(reason)
+	m.events.publish(ev.Build())
+}
+
+// signLimitStatus returns the lockout state and the peers with denied or rate limited requests.
+func (m *MuxAgent) signLimitStatus() api.StatusResponse_builder {
+	l := &m.signLimits
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := api.StatusResponse_builder{
+		Locked: proto.Bool(l.locked),
+	}
+	if l.locked {
+		status.LockReason = proto.String(l.lockReason)
+		if !l.lockedUntil.IsZero() {
+			status.LockedUntil = timestamppb.New(l.lockedUntil)
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(l.peers)) {
+		state := l.peers[id]
+		if state.rateLimited == 0 && state.denied == 0 {
+			continue
+		}
+
+		peer := api.ThrottledPeer_builder{
+			Peer:        proto.String(id),
+			Process:     proto.String(state.process),
+			Frontend:    proto.String(state.frontend),
+			RateLimited: proto.Int64(state.rateLimited),
+			Denied:      proto.Int64(state.denied),
+		}
+		if state.peer != nil {
+			peer.Pid = proto.Int64(int64(state.peer.PID))
+			peer.Uid = proto.Int64(int64(state.peer.UID))
+		}
+		if !state.lastDenied.IsZero() {
+			peer.LastDenied = timestamppb.New(state.lastDenied)
+		}
+
+		status.ThrottledPeers = append(status.ThrottledPeers, peer.Build())
+	}
+
+	return status
+}
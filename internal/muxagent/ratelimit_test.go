@@ -0,0 +1,158 @@
+package muxagent_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+)
+
+func TestSignLimitsPerPeer(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSignLimits(muxagent.SignLimits{
+			PerPeer: muxagent.SignRateLimit{PerMinute: 1, Burst: 2},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	key := addTestKey(t, muxAgent, "deploy")
+	view := muxAgent.NewView(muxagent.ViewOptions{Name: "forwarded"})
+	noisy := muxAgent.NewSession(view, &muxagent.Peer{UID: 1000, PID: 4242})
+	quiet := muxAgent.NewSession(view, &muxagent.Peer{UID: 1000, PID: 4343})
+
+	for i := range 2 {
+		if _, err = noisy.Sign(key, []byte("request")); err != nil {
+			t.Fatalf("Sign %d within the burst failed: %v", i, err)
+		}
+	}
+
+	if _, err = noisy.Sign(key, []byte("request")); !errors.Is(err, muxagent.ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited after the burst, got %v", err)
+	}
+
+	if _, err = quiet.Sign(key, []byte("request")); err != nil {
+		t.Fatalf("Expected the other peer to be unaffected, got %v", err)
+	}
+
+	status, err := muxAgent.ControlService().GetStatus(t.Context(), &api.StatusRequest{})
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+
+	if status.GetLocked() {
+		t.Error("Expected signing to stay unlocked without a lockout")
+	}
+
+	peers := status.GetThrottledPeers()
+	if len(peers) != 1 {
+		t.Fatalf("Expected 1 throttled peer, got %d", len(peers))
+	}
+	if peers[0].GetPid() != 4242 || peers[0].GetFrontend() != "forwarded" || peers[0].GetRateLimited() != 1 {
+		t.Errorf("Expected pid 4242 on forwarded rate limited once, got %v", peers[0])
+	}
+}
+
+func TestSignLimitsLockAfterDenials(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSignLimits(muxagent.SignLimits{
+			PerKey:    muxagent.SignRateLimit{PerMinute: 1, Burst: 1},
+			LockAfter: 2,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	key := addTestKey(t, muxAgent, "deploy")
+	otherKey := addTestKey(t, muxAgent, "other")
+
+	events, cancel := muxAgent.SubscribeEvents()
+	defer cancel()
+
+	if _, err = muxAgent.Sign(key, []byte("request")); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	nextEvent(t, events, api.EventType_EVENT_TYPE_SIGN_SERVED)
+
+	for range 2 {
+		if _, err = muxAgent.Sign(key, []byte("request")); !errors.Is(err, muxagent.ErrRateLimited) {
+			t.Fatalf("Expected ErrRateLimited, got %v", err)
+		}
+		nextEvent(t, events, api.EventType_EVENT_TYPE_SIGN_DENIED)
+	}
+
+	if ev := nextEvent(t, events, api.EventType_EVENT_TYPE_SIGNING_LOCKED); ev.GetReason() == "" {
+		t.Error("Expected a reason for the lockout")
+	}
+
+	// Once locked no key signs, even one that is within its rate limit.
+	if _, err = muxAgent.Sign(otherKey, []byte("request")); !errors.Is(err, muxagent.ErrSigningLocked) {
+		t.Fatalf("Expected ErrSigningLocked, got %v", err)
+	}
+	nextEvent(t, events, api.EventType_EVENT_TYPE_SIGN_DENIED)
+
+	status, err := muxAgent.ControlService().GetStatus(t.Context(), &api.StatusRequest{})
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if !status.GetLocked() || status.HasLockedUntil() {
+		t.Errorf("Expected signing locked until unlocked, got %v", status)
+	}
+
+	resp, err := muxAgent.ControlService().Unlock(t.Context(), &api.UnlockRequest{})
+	if err != nil || !resp.GetSuccess() {
+		t.Fatalf("Failed to unlock: %v", err)
+	}
+	nextEvent(t, events, api.EventType_EVENT_TYPE_SIGNING_UNLOCKED)
+
+	if _, err = muxAgent.Sign(otherKey, []byte("request")); err != nil {
+		t.Fatalf("Expected signing to work after unlock, got %v", err)
+	}
+}
+
+func TestSignLimitsLockOnlyOnRateLimits(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSignLimits(muxagent.SignLimits{LockAfter: 1}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	hidden := addTestKey(t, muxAgent, "personal")
+	key := addTestKey(t, muxAgent, "deploy")
+
+	// A restricted frontend asking for keys it cannot see must not lock signing for the others.
+	restricted := muxAgent.NewView(muxagent.ViewOptions{
+		Name: "restricted",
+		Keys: muxagent.KeyPolicy{Comments: []string{"deploy"}},
+	})
+	for range 3 {
+		if _, err = restricted.Sign(hidden, []byte("request")); !errors.Is(err, muxagent.ErrKeyNotVisible) {
+			t.Fatalf("Expected ErrKeyNotVisible, got %v", err)
+		}
+	}
+
+	if _, err = muxAgent.Sign(key, []byte("request")); err != nil {
+		t.Fatalf("Expected signing to stay unlocked after visibility denials, got %v", err)
+	}
+
+	status, err := muxAgent.ControlService().GetStatus(t.Context(), &api.StatusRequest{})
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+
+	if status.GetLocked() || len(status.GetThrottledPeers()) != 1 || status.GetThrottledPeers()[0].GetDenied() != 3 {
+		t.Errorf("Expected the denials recorded against the peer without a lockout, got %v", status)
+	}
+}
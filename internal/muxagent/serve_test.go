@@ -0,0 +1,290 @@
+package muxagent_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/internal/agentproto"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"github.com/na4ma4/ssh-agent-mux/internal/securitykey"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// serveAgent serves a over an in-memory connection and returns the client end.
+func serveAgent(t *testing.T, a agent.ExtendedAgent) net.Conn {
+	t.Helper()
+
+	server, client := net.Pipe()
+	t.Cleanup(func() { _ = client.Close() })
+
+	go func() {
+		defer server.Close()
+		_ = agentproto.NewServer(a).Serve(server)
+	}()
+
+	return client
+}
+
+// rawRequest sends a raw agent request and reports whether the agent replied with success.
+func rawRequest(t *testing.T, conn net.Conn, req []byte) bool {
+	t.Helper()
+
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(req))) //nolint:gosec // test request
+	if _, err := conn.Write(append(frame, req...)); err != nil {
+		t.Fatalf("Failed to write request: %v", err)
+	}
+
+	reply := make([]byte, 5) //nolint:mnd // length and message type
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("Failed to read reply: %v", err)
+	}
+
+	return reply[4] == 6 //nolint:mnd // SSH_AGENT_SUCCESS
+}
+
+// smartcardRequest sends a smartcard request and reports whether the agent replied with success.
+func smartcardRequest(t *testing.T, conn net.Conn, msgType byte, provider string, constraints ...byte) bool {
+	t.Helper()
+
+	req := append([]byte{msgType}, ssh.Marshal(struct{ Provider, PIN string }{provider, ""})...)
+
+	return rawRequest(t, conn, append(req, constraints...))
+}
+
+func TestServerHandlesSmartcardRequests(t *testing.T) {
+	dir := t.TempDir()
+	notModule := filepath.Join(dir, "libnot-a-module.so")
+	if err := os.WriteFile(notModule, []byte("not a module"), 0o600); err != nil {
+		t.Fatalf("Failed to write module: %v", err)
+	}
+
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithPKCS11Providers(filepath.Join(dir, "*")),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	conn := serveAgent(t, muxAgent)
+	addTestKey(t, muxAgent, "local")
+
+	if smartcardRequest(t, conn, 20, "/usr/lib/libdisallowed.so") {
+		t.Error("Expected a provider outside the allowed list to be rejected")
+	}
+
+	if smartcardRequest(t, conn, 20, notModule) {
+		t.Error("Expected a file that is not a PKCS#11 module to be rejected")
+	}
+
+	// Confirmation constraints are not supported for smartcard keys.
+	if smartcardRequest(t, conn, 26, notModule, 2) {
+		t.Error("Expected an unsupported constraint to be rejected")
+	}
+
+	if smartcardRequest(t, conn, 21, notModule) {
+		t.Error("Expected removing a provider that is not loaded to fail")
+	}
+
+	// Other requests are still served on the same connection.
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+
+	if len(keys) != 1 {
+		t.Errorf("Expected one key, got %d", len(keys))
+	}
+}
+
+func TestProviderPatternsMatchLikeSSHAgent(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		match         bool
+	}{
+		{"/usr/lib*/*", "/usr/lib/x86_64-linux-gnu/opensc-pkcs11.so", true},
+		{"/usr/lib*/*", "/usr/lib/softhsm/libsofthsm2.so", true},
+		{"/usr/lib*/*", "/usr/lib64/libykcs11.so", true},
+		{"/usr/local/lib*/*", "/usr/local/lib/libykcs11.so", true},
+		{"/usr/lib*/*", "/usr/local/lib/libykcs11.so", false},
+		{"/usr/lib*/*", "/usr/lib", false},
+		{"/opt/lib?.so", "/opt/lib1.so", true},
+		{"/opt/lib?.so", "/opt/lib12.so", false},
+	}
+
+	for _, tt := range tests {
+		if got := muxagent.MatchProviderPattern(tt.pattern, tt.path); got != tt.match {
+			t.Errorf("Expected pattern %q matching %q to be %t, got %t", tt.pattern, tt.path, tt.match, got)
+		}
+	}
+}
+
+func TestViewRejectsSmartcardKeysWhenReadOnly(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	view := muxAgent.NewView(muxagent.ViewOptions{Name: "ro", ReadOnly: true})
+	if err = view.AddSmartcardKey("/usr/lib/opensc-pkcs11.so", "", 0); !errors.Is(err, muxagent.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+
+	if smartcardRequest(t, serveAgent(t, view), 21, "/usr/lib/opensc-pkcs11.so") {
+		t.Error("Expected read-only view to reject smartcard removal")
+	}
+}
+
+// addSecurityKeyRequest returns the SSH_AGENTC_ADD_IDENTITY request ssh-add sends for an sk-ed25519 key.
+func addSecurityKeyRequest(t *testing.T, key *securitykey.PrivateKey, comment string) []byte {
+	t.Helper()
+
+	var pubKey struct {
+		Type        string
+		PubKey      []byte
+		Application string
+	}
+	if err := ssh.Unmarshal(key.PublicKey.Marshal(), &pubKey); err != nil {
+		t.Fatalf("Failed to decode public key: %v", err)
+	}
+
+	return append([]byte{17}, ssh.Marshal(struct {
+		Type        string
+		PubKey      []byte
+		Application string
+		Flags       byte
+		KeyHandle   []byte
+		Reserved    []byte
+		Comment     string
+	}{pubKey.Type, pubKey.PubKey, key.Application, key.Flags, key.KeyHandle, nil, comment})...)
+}
+
+func TestServerAddsSecurityKeys(t *testing.T) {
+	auth := securitykey.NewSoftAuthenticator()
+	key, err := auth.Generate(ssh.KeyAlgoSKED25519, "ssh:", securitykey.FlagUserPresenceRequired)
+	if err != nil {
+		t.Fatalf("Failed to generate security key: %v", err)
+	}
+
+	data := []byte("data to sign")
+
+	for _, tc := range []struct {
+		name    string
+		auth    securitykey.Authenticator
+		canSign bool
+	}{
+		{name: "authenticator", auth: auth, canSign: true},
+		{name: "no authenticator"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []muxagent.Option
+			if tc.auth != nil {
+				opts = append(opts, muxagent.WithSecurityKeyAuthenticator(tc.auth))
+			}
+
+			muxAgent, err := muxagent.NewMuxAgent(
+				contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(), opts...,
+			)
+			if err != nil {
+				t.Fatalf("Failed to create mux agent: %v", err)
+			}
+			defer muxAgent.Close()
+
+			conn := serveAgent(t, muxAgent)
+			if !rawRequest(t, conn, addSecurityKeyRequest(t, key, "yubikey")) {
+				t.Fatal("Expected security key to be added")
+			}
+
+			client := agent.NewClient(conn)
+			keys, err := client.List()
+			if err != nil {
+				t.Fatalf("Failed to list keys: %v", err)
+			}
+
+			if len(keys) != 1 || keys[0].Format != ssh.KeyAlgoSKED25519 || keys[0].Comment != "yubikey" {
+				t.Fatalf("Expected the security key to be listed, got %v", keys)
+			}
+
+			sig, err := client.Sign(key.PublicKey, data)
+			if !tc.canSign {
+				if err == nil {
+					t.Error("Expected signing to fail without an authenticator")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Failed to sign with security key: %v", err)
+			}
+
+			if err = key.PublicKey.Verify(data, sig); err != nil {
+				t.Errorf("Security key signature did not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestServerRejectsUnenforcedConstraints(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSecurityKeyAuthenticator(securitykey.NewSoftAuthenticator()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	conn := serveAgent(t, muxAgent)
+	client := agent.NewClient(conn)
+
+	_, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if err = client.Add(agent.AddedKey{PrivateKey: privKey, ConfirmBeforeUse: true}); err == nil {
+		t.Error("Expected a key requiring confirmation to be rejected")
+	}
+
+	skKey, err := securitykey.NewSoftAuthenticator().Generate(ssh.KeyAlgoSKED25519, "ssh:", 0)
+	if err != nil {
+		t.Fatalf("Failed to generate security key: %v", err)
+	}
+
+	addWithExtension := func(name, details string) bool {
+		req := addSecurityKeyRequest(t, skKey, "yubikey")
+		req[0] = agentproto.MsgAddIDConstrained
+		req = append(req, 255) //nolint:mnd // SSH_AGENT_CONSTRAIN_EXTENSION
+
+		return rawRequest(t, conn, append(req, ssh.Marshal(struct{ Name, Details string }{name, details})...))
+	}
+
+	if addWithExtension(agentproto.ExtensionRestrictDestination, "hosts") {
+		t.Error("Expected a destination restricted key to be rejected")
+	}
+
+	if addWithExtension(agentproto.ExtensionSKProvider, "/usr/lib/libsk-middleware.so") {
+		t.Error("Expected a security key with an external provider to be rejected")
+	}
+
+	if !addWithExtension(agentproto.ExtensionSKProvider, "internal") {
+		t.Error("Expected a security key with the internal provider to be added")
+	}
+
+	keys, err := client.List()
+	if err != nil || len(keys) != 1 {
+		t.Errorf("Expected only the security key to be added, got %v (%v)", keys, err)
+	}
+}
@@ -0,0 +1,247 @@
+package muxagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dosquad/go-cliversion"
+	"github.com/google/uuid"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ControlService implements the MuxControl gRPC service served on the control socket, the mux
+// control extensions are adapters over the same methods.
+type ControlService struct {
+	api.UnimplementedMuxControlServer
+
+	mux *MuxAgent
+}
+
+// ControlService returns the control API of the mux agent.
+func (m *MuxAgent) ControlService() *ControlService {
+	return m.service
+}
+
+// Ping answers a health check.
+func (s *ControlService) Ping(_ context.Context, msg *api.Ping) (*api.Pong, error) {
+	m := s.mux
+	m.logger.DebugContext(m.ctx, "Ping called", slog.String("msg-id", msg.GetId()))
+
+	pong := api.Pong_builder{
+		Id:        proto.String(msg.GetId()),
+		PingTs:    msg.GetTs(),
+		Ts:        timestamppb.Now(),
+		Pid:       proto.Int64(int64(os.Getpid())),
+		StartTime: m.config.GetStartTime(),
+		Version:   proto.String(cliversion.Get().VersionString()),
+	}.Build()
+
+	return pong, nil
+}
+
+// GetConfig returns the running configuration.
+func (s *ControlService) GetConfig(_ context.Context, msg *api.ConfigRequest) (*api.Config, error) {
+	m := s.mux
+	m.logger.DebugContext(m.ctx, "GetConfig called", slog.String("msg-id", msg.GetId()))
+
+	var cfg *api.Config
+	{
+		m.configMu.RLock()
+		clone, ok := proto.Clone(m.config).(*api.Config)
+		m.configMu.RUnlock()
+		if !ok {
+			return nil, errors.New("failed to clone config")
+		}
+
+		cfg = clone
+	}
+
+	cfg.SetId(uuid.NewString())
+	cfg.SetTs(timestamppb.Now())
+
+	return cfg, nil
+}
+
+// forceCloseDelay is how long a forced shutdown waits before closing connections, giving the
+// connection that carried the shutdown request time to receive its response.
+const forceCloseDelay = 250 * time.Millisecond
+
+// Shutdown stops the mux agent, active connections are drained unless the request forces them
+// to be terminated. A forced shutdown stops reading requests immediately and closes connections
+// that are still open shortly after, including ones stuck in a backend sign or a confirmation.
+func (s *ControlService) Shutdown(_ context.Context, msg *api.ShutdownRequest) (*api.CommandResponse, error) {
+	m := s.mux
+	m.logger.DebugContext(m.ctx, "Shutdown called",
+		slog.String("msg-id", msg.GetId()),
+		slog.Bool("force", msg.GetForce()),
+	)
+
+	defer m.ctx.Cancel()
+
+	if msg.GetForce() {
+		terminated := m.conns.Interrupt()
+		time.AfterFunc(forceCloseDelay, func() { m.conns.CloseAll() })
+
+		return api.CommandResponse_builder{
+			Id:      proto.String(msg.GetId()),
+			Ts:      msg.GetTs(),
+			Success: proto.Bool(true),
+			Message: proto.String(
+				fmt.Sprintf("forced shutdown received for pid %d, terminating %d connections", os.Getpid(), terminated),
+			),
+			ConnectionsTerminated: proto.Int64(int64(terminated)),
+		}.Build(), nil
+	}
+
+	resp := api.CommandResponse_builder{
+		Id:      proto.String(msg.GetId()),
+		Ts:      msg.GetTs(),
+		Success: proto.Bool(true),
+		Message: proto.String(
+			fmt.Sprintf("shutdown received for pid %d, draining %d connections (deadline %s)",
+				os.Getpid(), m.conns.Len(), m.config.GetShutdownTimeout().AsDuration(),
+			),
+		),
+		ConnectionsTerminated: proto.Int64(0),
+	}.Build()
+
+	return resp, nil
+}
+
+// Keygen generates a key inside the mux agent and returns its public key.
+func (s *ControlService) Keygen(_ context.Context, msg *api.KeygenRequest) (*api.KeygenResponse, error) {
+	return s.mux.handleKeygen(msg)
+}
+
+// CertIssue issues a certificate for a local key with the built-in certificate authority.
+func (s *ControlService) CertIssue(_ context.Context, msg *api.CertIssueRequest) (*api.CertIssueResponse, error) {
+	return s.mux.handleCertIssue(msg)
+}
+
+// ListKeys returns the keys known to the mux agent and where each one came from.
+func (s *ControlService) ListKeys(_ context.Context, msg *api.ListKeysRequest) (*api.ListKeysResponse, error) {
+	m := s.mux
+	m.logger.DebugContext(m.ctx, "ListKeys called", slog.String("msg-id", msg.GetId()))
+
+	sourcedKeys := m.listWithSource()
+
+	keys := make([]*api.Key, 0, len(sourcedKeys))
+	for _, sourcedKey := range sourcedKeys {
+		key := api.Key_builder{
+			PublicKey:   proto.String(strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sourcedKey.Key)))),
+			Fingerprint: proto.String(ssh.FingerprintSHA256(sourcedKey.Key)),
+			Comment:     proto.String(sourcedKey.Key.Comment),
+			Type:        proto.String(sourcedKey.Key.Type()),
+			Source:      proto.String(sourcedKey.Source.String()),
+			Tags:        sourcedKey.Tags,
+		}
+		if !sourcedKey.Expires.IsZero() {
+			key.Expires = timestamppb.New(sourcedKey.Expires)
+		}
+
+		keys = append(keys, key.Build())
+	}
+
+	return api.ListKeysResponse_builder{
+		Id:   proto.String(msg.GetId()),
+		Ts:   timestamppb.Now(),
+		Keys: keys,
+	}.Build(), nil
+}
+
+// ManageBackends lists, adds or removes backend agents.
+func (s *ControlService) ManageBackends(
+	_ context.Context, msg *api.ManageBackendsRequest,
+) (*api.ManageBackendsResponse, error) {
+	m := s.mux
+	m.logger.DebugContext(m.ctx, "ManageBackends called",
+		slog.String("msg-id", msg.GetId()),
+		slog.String("backend-action", msg.GetAction().String()),
+		slog.String("socket-path", msg.GetSocketPath()),
+	)
+
+	changed, err := m.manageBackends(msg.GetAction(), msg.GetSocketPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return api.ManageBackendsResponse_builder{
+		Id:                proto.String(msg.GetId()),
+		Ts:                timestamppb.Now(),
+		BackendSocketPath: m.backendSocketPaths(),
+		Changed:           proto.Bool(changed),
+	}.Build(), nil
+}
+
+// GetStatus returns whether signing is locked and the peers with denied or rate limited sign
+// requests.
+func (s *ControlService) GetStatus(_ context.Context, msg *api.StatusRequest) (*api.StatusResponse, error) {
+	m := s.mux
+	m.logger.DebugContext(m.ctx, "GetStatus called", slog.String("msg-id", msg.GetId()))
+
+	status := m.signLimitStatus()
+	status.Id = proto.String(msg.GetId())
+	status.Ts = timestamppb.Now()
+
+	return status.Build(), nil
+}
+
+// Unlock clears the sign lockout.
+func (s *ControlService) Unlock(_ context.Context, msg *api.UnlockRequest) (*api.CommandResponse, error) {
+	m := s.mux
+	m.logger.DebugContext(m.ctx, "Unlock called", slog.String("msg-id", msg.GetId()))
+
+	message := "signing was not locked"
+	if m.UnlockSigning() {
+		message = "signing unlocked"
+	}
+
+	return api.CommandResponse_builder{
+		Id:      proto.String(msg.GetId()),
+		Ts:      msg.GetTs(),
+		Success: proto.Bool(true),
+		Message: proto.String(message),
+	}.Build(), nil
+}
+
+// StreamEvents sends the events of the mux agent until the client goes away or the agent stops.
+func (s *ControlService) StreamEvents(
+	msg *api.StreamEventsRequest, stream grpc.ServerStreamingServer[api.Event],
+) error {
+	m := s.mux
+	m.logger.DebugContext(m.ctx, "StreamEvents called",
+		slog.String("msg-id", msg.GetId()),
+		slog.Any("event-types", msg.GetTypes()),
+	)
+
+	events, cancel := m.SubscribeEvents()
+	defer cancel()
+
+	wanted := eventFilter(msg.GetTypes())
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-m.ctx.Done():
+			return nil
+		case ev := <-events:
+			if !wanted(ev) {
+				continue
+			}
+
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
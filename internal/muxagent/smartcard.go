@@ -0,0 +1,301 @@
+package muxagent
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/internal/keystore"
+	"github.com/na4ma4/ssh-agent-mux/internal/pkcs11provider"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	// ErrProviderNotAllowed indicates that a PKCS#11 module is not in the allowed provider list.
+	ErrProviderNotAllowed = errors.New("PKCS#11 provider not allowed")
+	// ErrProviderLoaded indicates that a PKCS#11 module has already been added.
+	ErrProviderLoaded = errors.New("PKCS#11 provider already loaded")
+	// ErrProviderNotLoaded indicates that a PKCS#11 module to remove has not been added.
+	ErrProviderNotLoaded = errors.New("PKCS#11 provider not loaded")
+)
+
+// DefaultPKCS11Providers are the PKCS#11 module paths that may be loaded by default, the same as
+// the ssh-agent -P default. As in ssh-agent a * also matches path separators, so these allow modules
+// in subdirectories such as /usr/lib/x86_64-linux-gnu.
+//
+//nolint:gochecknoglobals // default list
+var DefaultPKCS11Providers = []string{"/usr/lib*/*", "/usr/local/lib*/*"}
+
+// tokenModule is a loaded PKCS#11 module.
+type tokenModule struct {
+	module  *pkcs11provider.Module
+	expires time.Time
+}
+
+// tokenSet holds the loaded PKCS#11 modules by resolved path.
+type tokenSet struct {
+	mu      sync.RWMutex
+	modules map[string]*tokenModule
+}
+
+// WithPKCS11Providers sets the patterns of the PKCS#11 module paths that may be loaded, replacing
+// DefaultPKCS11Providers. Patterns support * and ? wildcards which, as in ssh-agent, also match
+// path separators. No patterns disables loading modules.
+func WithPKCS11Providers(allowed ...string) Option {
+	return func(m *MuxAgent) {
+		m.pkcs11Allowed = allowed
+	}
+}
+
+// resolveProvider returns the real path of a PKCS#11 module if it is allowed.
+func (m *MuxAgent) resolveProvider(provider string) (string, error) {
+	resolved, err := filepath.Abs(provider)
+	if err == nil {
+		resolved, err = filepath.EvalSymlinks(resolved)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve PKCS#11 provider %s: %w", provider, err)
+	}
+
+	for _, pattern := range m.pkcs11Allowed {
+		if matchProviderPattern(pattern, resolved) {
+			return resolved, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrProviderNotAllowed, resolved)
+}
+
+// matchProviderPattern reports whether path matches pattern with the semantics of the ssh-agent
+// match_pattern, * matches any run of characters including / and ? matches any single character.
+func matchProviderPattern(pattern, path string) bool {
+	// starPattern and starPath record the position after the last * for backtracking, -1 while
+	// no * has been seen.
+	starPattern, starPath := -1, -1
+
+	p, s := 0, 0
+	for s < len(path) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			starPattern, starPath = p+1, s
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == path[s]):
+			p++
+			s++
+		case starPattern >= 0:
+			starPath++
+			p, s = starPattern, starPath
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// AddSmartcardKey loads the keys of a PKCS#11 module.
+func (m *MuxAgent) AddSmartcardKey(provider, pin string, lifetime uint32) error {
+	m.logger.DebugContext(m.ctx, "AddSmartcardKey called", slog.String("provider", provider))
+
+	path, err := m.resolveProvider(provider)
+	if err != nil {
+		return err
+	}
+
+	m.tokens.mu.RLock()
+	_, ok := m.tokens.modules[path]
+	m.tokens.mu.RUnlock()
+	if ok {
+		return fmt.Errorf("%w: %s", ErrProviderLoaded, path)
+	}
+
+	// Opening the module logs in to the token, which can be slow, so it is done without holding
+	// the tokens lock that listing and signing need.
+	module, err := pkcs11provider.Open(path, pin)
+	if err != nil {
+		return err
+	}
+
+	m.tokens.mu.Lock()
+	defer m.tokens.mu.Unlock()
+
+	if _, ok = m.tokens.modules[path]; ok {
+		// Loaded by a concurrent request while this one was logging in.
+		if closeErr := module.Close(); closeErr != nil {
+			m.logger.DebugContext(m.ctx, "Failed to unload duplicate PKCS#11 provider",
+				slog.String("provider", path),
+				slogtool.ErrorAttr(closeErr),
+			)
+		}
+
+		return fmt.Errorf("%w: %s", ErrProviderLoaded, path)
+	}
+
+	loaded := &tokenModule{module: module}
+	if lifetime > 0 {
+		loaded.expires = time.Now().Add(time.Duration(lifetime) * time.Second)
+	}
+	m.tokens.modules[path] = loaded
+
+	m.logger.InfoContext(m.ctx, "Loaded PKCS#11 provider",
+		slog.String("provider", path),
+		slog.Int("key-count", len(module.Keys)),
+	)
+
+	return nil
+}
+
+// RemoveSmartcardKey unloads a PKCS#11 module.
+func (m *MuxAgent) RemoveSmartcardKey(provider string) error {
+	m.logger.DebugContext(m.ctx, "RemoveSmartcardKey called", slog.String("provider", provider))
+
+	// The provider must have been allowed to be loaded, so it always resolves unless it has
+	// since been deleted, in which case the path is matched as given.
+	path, err := m.resolveProvider(provider)
+	if err != nil {
+		path = provider
+	}
+
+	m.tokens.mu.Lock()
+	defer m.tokens.mu.Unlock()
+
+	loaded, ok := m.tokens.modules[path]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProviderNotLoaded, provider)
+	}
+
+	m.unloadToken(path, loaded)
+
+	return nil
+}
+
+// removeAllTokens unloads every PKCS#11 module.
+func (m *MuxAgent) removeAllTokens() {
+	m.tokens.mu.Lock()
+	defer m.tokens.mu.Unlock()
+
+	for path, loaded := range m.tokens.modules {
+		m.unloadToken(path, loaded)
+	}
+}
+
+// removeExpiredTokens unloads the PKCS#11 modules whose lifetime has passed.
+func (m *MuxAgent) removeExpiredTokens(now time.Time) {
+	m.tokens.mu.Lock()
+	defer m.tokens.mu.Unlock()
+
+	for path, loaded := range m.tokens.modules {
+		if !loaded.expires.IsZero() && now.After(loaded.expires) {
+			m.unloadToken(path, loaded)
+		}
+	}
+}
+
+// unloadToken closes a PKCS#11 module, the caller must hold the tokens lock.
+func (m *MuxAgent) unloadToken(path string, loaded *tokenModule) {
+	delete(m.tokens.modules, path)
+
+	if err := loaded.module.Close(); err != nil {
+		m.logger.DebugContext(m.ctx, "Failed to unload PKCS#11 provider",
+			slog.String("provider", path),
+			slogtool.ErrorAttr(err),
+		)
+	}
+
+	m.logger.InfoContext(m.ctx, "Unloaded PKCS#11 provider", slog.String("provider", path))
+}
+
+// listTokenKeys returns the keys on the loaded PKCS#11 tokens.
+func (m *MuxAgent) listTokenKeys() []SourcedKey {
+	m.tokens.mu.RLock()
+	defer m.tokens.mu.RUnlock()
+
+	var keys []SourcedKey
+	for path, loaded := range m.tokens.modules {
+		for _, key := range loaded.module.Keys {
+			keys = append(keys, SourcedKey{
+				Key: &agent.Key{
+					Format:  key.PublicKey.Type(),
+					Blob:    key.PublicKey.Marshal(),
+					Comment: key.Comment,
+				},
+				Source:  KeySource{Token: path},
+				Expires: loaded.expires,
+			})
+		}
+	}
+
+	return keys
+}
+
+// tokenSigners returns signers for the keys on the loaded PKCS#11 tokens.
+func (m *MuxAgent) tokenSigners() []ssh.Signer {
+	m.tokens.mu.RLock()
+	defer m.tokens.mu.RUnlock()
+
+	var signers []ssh.Signer
+	for _, loaded := range m.tokens.modules {
+		for _, key := range loaded.module.Keys {
+			if signer, err := key.Signer(); err == nil {
+				signers = append(signers, signer)
+			}
+		}
+	}
+
+	return signers
+}
+
+// signWithToken signs data with a key on a loaded PKCS#11 token and returns the provider path, it
+// returns keystore.ErrKeyNotFound when no token holds the key. The signature is made without the
+// tokens lock, a slow token must not block listing or other tokens; the module serialises its own
+// calls and refuses to sign once it was unloaded.
+func (m *MuxAgent) signWithToken(
+	key ssh.PublicKey, data []byte, flags agent.SignatureFlags,
+) (*ssh.Signature, string, error) {
+	path, tokenKey := m.findTokenKey(key)
+	if tokenKey == nil {
+		return nil, "", keystore.ErrKeyNotFound
+	}
+
+	signer, err := tokenKey.Signer()
+	if err != nil {
+		return nil, path, err
+	}
+
+	m.logger.DebugContext(m.ctx, "Signing with PKCS#11 token key",
+		slog.String("provider", path),
+		slog.String("key-fingerprint", ssh.FingerprintSHA256(tokenKey.PublicKey)),
+	)
+
+	sig, err := signWithSigner(signer, data, flags)
+
+	return sig, path, err
+}
+
+// findTokenKey returns the provider path and the loaded token key matching key, the key is nil
+// when no token holds it.
+func (m *MuxAgent) findTokenKey(key ssh.PublicKey) (string, *pkcs11provider.Key) {
+	blob := string(key.Marshal())
+
+	m.tokens.mu.RLock()
+	defer m.tokens.mu.RUnlock()
+
+	for path, loaded := range m.tokens.modules {
+		for _, tokenKey := range loaded.module.Keys {
+			if string(tokenKey.PublicKey.Marshal()) == blob {
+				return path, tokenKey
+			}
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,135 @@
+package muxagent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/na4ma4/go-slogtool"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrNamespaceDenied indicates that a sign policy does not allow a key to sign the request.
+	ErrNamespaceDenied = errors.New("sign request denied by namespace policy")
+	// ErrSignKeyUnknown indicates that the sign policies could not be applied because the key was
+	// not found, eg. when a backend agent did not answer.
+	ErrSignKeyUnknown = errors.New("sign request denied, key not found for sign policies")
+)
+
+// sshsigMagic is the preamble of data signed with "ssh-keygen -Y sign" (eg. git commit signing).
+const sshsigMagic = "SSHSIG"
+
+// SSHSig describes an SSHSIG sign request, see PROTOCOL.sshsig in OpenSSH.
+type SSHSig struct {
+	Namespace     string
+	HashAlgorithm string
+}
+
+// ParseSSHSig returns the SSHSIG request contained in data to be signed, ok is false when the
+// data is not an SSHSIG blob (eg. an authentication request).
+func ParseSSHSig(data []byte) (*SSHSig, bool) {
+	rest, found := bytes.CutPrefix(data, []byte(sshsigMagic))
+	if !found {
+		return nil, false
+	}
+
+	var blob struct {
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Hash          []byte
+	}
+	if err := ssh.Unmarshal(rest, &blob); err != nil || blob.Namespace == "" {
+		return nil, false
+	}
+
+	return &SSHSig{Namespace: blob.Namespace, HashAlgorithm: blob.HashAlgorithm}, true
+}
+
+// SignPolicy restricts what the keys matching a key policy may sign.
+type SignPolicy struct {
+	// Keys selects the keys the policy applies to.
+	Keys KeyPolicy `mapstructure:"keys"`
+	// Namespaces restricts the keys to signing SSHSIG data in these namespaces, authentication
+	// requests are refused.
+	Namespaces []string `mapstructure:"namespaces"`
+	// DenyNamespaces lists SSHSIG namespaces the keys must never sign.
+	DenyNamespaces []string `mapstructure:"deny-namespaces"`
+}
+
+// check returns ErrNamespaceDenied if the policy does not allow the request, sig is nil for
+// requests that are not SSHSIG blobs.
+func (p SignPolicy) check(sig *SSHSig) error {
+	switch {
+	case sig == nil && len(p.Namespaces) > 0:
+		return fmt.Errorf("%w: key may only sign namespaces %v", ErrNamespaceDenied, p.Namespaces)
+	case sig == nil:
+		return nil
+	case slices.Contains(p.DenyNamespaces, sig.Namespace):
+		return fmt.Errorf("%w: namespace %q", ErrNamespaceDenied, sig.Namespace)
+	case len(p.Namespaces) > 0 && !slices.Contains(p.Namespaces, sig.Namespace):
+		return fmt.Errorf("%w: namespace %q", ErrNamespaceDenied, sig.Namespace)
+	}
+
+	return nil
+}
+
+// checkSignRequest records the sign request in the audit log and applies the sign policies.
+func (m *MuxAgent) checkSignRequest(key ssh.PublicKey, data []byte) error {
+	attrs := []any{
+		slog.String("key-type", key.Type()),
+		slog.String("key-fingerprint", ssh.FingerprintSHA256(key)),
+	}
+
+	sig, isSSHSig := ParseSSHSig(data)
+	if isSSHSig {
+		attrs = append(attrs,
+			slog.String("sign-type", "sshsig"),
+			slog.String("sshsig-namespace", sig.Namespace),
+			slog.String("sshsig-hash-algorithm", sig.HashAlgorithm),
+		)
+	} else {
+		attrs = append(attrs, slog.String("sign-type", "auth"))
+	}
+
+	if err := m.applySignPolicies(key, sig); err != nil {
+		m.logger.WarnContext(m.ctx, "Sign request denied", append(attrs, slogtool.ErrorAttr(err))...)
+		return err
+	}
+
+	m.logger.InfoContext(m.ctx, "Sign request", attrs...)
+
+	return nil
+}
+
+// applySignPolicies checks the request against every sign policy that matches the key, requests
+// for keys that cannot be found are denied when there are sign policies.
+func (m *MuxAgent) applySignPolicies(key ssh.PublicKey, sig *SSHSig) error {
+	m.configMu.RLock()
+	policies := m.signPolicies
+	m.configMu.RUnlock()
+
+	if len(policies) == 0 {
+		return nil
+	}
+
+	sourcedKey, err := m.lookupSourcedKey(key)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSignKeyUnknown, err)
+	}
+
+	for _, policy := range policies {
+		if !policy.Keys.Allows(sourcedKey) {
+			continue
+		}
+
+		if err = policy.check(sig); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package muxagent_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"golang.org/x/crypto/ssh"
+)
+
+func sshsigBlob(namespace string, message []byte) []byte {
+	hash := sha512.Sum512(message)
+
+	return append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      []byte
+		HashAlgorithm string
+		Hash          []byte
+	}{namespace, nil, "sha512", hash[:]})...)
+}
+
+func TestParseSSHSig(t *testing.T) {
+	sig, ok := muxagent.ParseSSHSig(sshsigBlob("git", []byte("tree 1234")))
+	if !ok {
+		t.Fatal("Expected SSHSIG blob to be recognised")
+	}
+
+	if sig.Namespace != "git" || sig.HashAlgorithm != "sha512" {
+		t.Errorf("Expected namespace git with sha512, got %+v", sig)
+	}
+
+	if _, ok = muxagent.ParseSSHSig(ssh.Marshal(struct{ SessionID []byte }{[]byte("session")})); ok {
+		t.Error("Expected authentication request not to be recognised as SSHSIG")
+	}
+
+	if _, ok = muxagent.ParseSSHSig([]byte("SSHSIG")); ok {
+		t.Error("Expected truncated SSHSIG blob not to be recognised")
+	}
+}
+
+func TestSignPoliciesRestrictNamespaces(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSignPolicies(
+			muxagent.SignPolicy{
+				Keys:           muxagent.KeyPolicy{Comments: []string{"deploy"}},
+				DenyNamespaces: []string{"git"},
+			},
+			muxagent.SignPolicy{
+				Keys:       muxagent.KeyPolicy{Comments: []string{"signing"}},
+				Namespaces: []string{"git"},
+			},
+		),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	deployKey := addTestKey(t, muxAgent, "deploy")
+	signingKey := addTestKey(t, muxAgent, "signing")
+
+	authData := []byte("authentication request")
+	gitData := sshsigBlob("git", []byte("tree 1234"))
+	fileData := sshsigBlob("file", []byte("release.tar.gz"))
+
+	tests := []struct {
+		name    string
+		key     ssh.PublicKey
+		data    []byte
+		allowed bool
+	}{
+		{"deploy key authenticates", deployKey, authData, true},
+		{"deploy key signs files", deployKey, fileData, true},
+		{"deploy key never signs git commits", deployKey, gitData, false},
+		{"signing key signs git commits", signingKey, gitData, true},
+		{"signing key does not sign files", signingKey, fileData, false},
+		{"signing key does not authenticate", signingKey, authData, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, signErr := muxAgent.Sign(tt.key, tt.data)
+			if tt.allowed && signErr != nil {
+				t.Errorf("Expected sign to be allowed, got %v", signErr)
+			}
+			if !tt.allowed && !errors.Is(signErr, muxagent.ErrNamespaceDenied) {
+				t.Errorf("Expected ErrNamespaceDenied, got %v", signErr)
+			}
+		})
+	}
+}
+
+func TestSignPoliciesDenyUnknownKeys(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSignPolicies(muxagent.SignPolicy{
+			Keys:           muxagent.KeyPolicy{Comments: []string{"deploy"}},
+			DenyNamespaces: []string{"git"},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	unknownKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to convert to SSH public key: %v", err)
+	}
+
+	if _, err = muxAgent.Sign(unknownKey, sshsigBlob("git", []byte("tree 1234"))); !errors.Is(
+		err, muxagent.ErrSignKeyUnknown,
+	) {
+		t.Errorf("Expected ErrSignKeyUnknown for a key the policies cannot be applied to, got %v", err)
+	}
+}
@@ -0,0 +1,517 @@
+package muxagent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/keystore"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ErrKeyNotVisible indicates that a key exists but is hidden by the frontend key policy.
+var ErrKeyNotVisible = errors.New("key not visible through this frontend")
+
+// ErrReadOnly indicates that a frontend does not allow keys to be added or removed.
+var ErrReadOnly = errors.New("frontend is read-only")
+
+// ErrExtensionFiltered indicates that a forwarding frontend or one restricting keys does not pass
+// an extension on to the backend agents, as it cannot be checked against its key policy.
+var ErrExtensionFiltered = errors.New("extension not allowed through this frontend")
+
+// ErrRateLimited indicates that a frontend has exceeded its sign request rate.
+var ErrRateLimited = errors.New("sign request rate exceeded")
+
+const (
+	// KeySourceLocal matches keys stored in the mux agent.
+	KeySourceLocal = "local"
+	// KeySourceBackends matches keys from any backend agent.
+	KeySourceBackends = "backends"
+	// KeySourceTokens matches keys on PKCS#11 tokens loaded with ssh-add -s.
+	KeySourceTokens = "tokens"
+)
+
+// KeySource identifies where a key listed by the mux agent came from.
+type KeySource struct {
+	Local   bool
+	Backend string
+	// Token is the path of the PKCS#11 module holding the key.
+	Token string
+}
+
+// String returns the source in the form used by KeyPolicy.Sources.
+func (s KeySource) String() string {
+	if s.Local {
+		return KeySourceLocal
+	}
+
+	if s.Token != "" {
+		return s.Token
+	}
+
+	return s.Backend
+}
+
+// SourcedKey is a listed key along with its source.
+type SourcedKey struct {
+	Key    *agent.Key
+	Source KeySource
+	// Tags are the tags of a local key, backend keys have no tags.
+	Tags []string
+	// Expires is the expiry time of a local key added with a lifetime.
+	Expires time.Time
+}
+
+// KeyPolicy decides which keys are visible through a frontend, empty lists match everything.
+type KeyPolicy struct {
+	// Sources is a list of "local", "backends", "tokens", backend socket paths or PKCS#11
+	// module paths.
+	Sources []string `mapstructure:"sources"`
+	// Comments is a list of glob patterns matched against the key comment.
+	Comments []string `mapstructure:"comments"`
+	// Fingerprints is a list of SHA256 key fingerprints.
+	Fingerprints []string `mapstructure:"fingerprints"`
+	// Tags is a list of local key tags, keys must have at least one of them.
+	Tags []string `mapstructure:"tags"`
+	// Ephemeral only matches local keys that were added with a lifetime.
+	Ephemeral bool `mapstructure:"ephemeral"`
+	// Any is a list of policies of which at least one must also match.
+	Any []KeyPolicy `mapstructure:"any"`
+}
+
+// Allows reports whether a key is visible under the policy.
+func (p KeyPolicy) Allows(key SourcedKey) bool {
+	return p.allowsSource(key.Source) &&
+		p.allowsComment(key.Key.Comment) &&
+		p.allowsFingerprint(key.Key) &&
+		p.allowsTags(key.Tags) &&
+		(!p.Ephemeral || !key.Expires.IsZero()) &&
+		p.allowsAny(key)
+}
+
+// empty reports whether the policy has no rules and so matches every key.
+func (p KeyPolicy) empty() bool {
+	return len(p.Sources) == 0 && len(p.Comments) == 0 && len(p.Fingerprints) == 0 &&
+		len(p.Tags) == 0 && !p.Ephemeral && len(p.Any) == 0
+}
+
+func (p KeyPolicy) allowsSource(source KeySource) bool {
+	if len(p.Sources) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.Sources {
+		switch {
+		case allowed == KeySourceLocal && source.Local:
+			return true
+		case allowed == KeySourceBackends && source.Backend != "":
+			return true
+		case allowed == KeySourceTokens && source.Token != "":
+			return true
+		case !source.Local && allowed == source.String():
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p KeyPolicy) allowsComment(comment string) bool {
+	if len(p.Comments) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Comments {
+		if ok, _ := path.Match(pattern, comment); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p KeyPolicy) allowsFingerprint(key *agent.Key) bool {
+	if len(p.Fingerprints) == 0 {
+		return true
+	}
+
+	// Certificates match the fingerprint of the key they were issued for
+	fingerprint := ssh.FingerprintSHA256(key)
+	if pubKey, err := ssh.ParsePublicKey(key.Blob); err == nil {
+		if cert, ok := pubKey.(*ssh.Certificate); ok {
+			fingerprint = ssh.FingerprintSHA256(cert.Key)
+		}
+	}
+
+	for _, allowed := range p.Fingerprints {
+		if allowed == fingerprint {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p KeyPolicy) allowsTags(tags []string) bool {
+	if len(p.Tags) == 0 {
+		return true
+	}
+
+	for _, tag := range p.Tags {
+		if slices.Contains(tags, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p KeyPolicy) allowsAny(key SourcedKey) bool {
+	if len(p.Any) == 0 {
+		return true
+	}
+
+	for _, policy := range p.Any {
+		if policy.Allows(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ViewOptions configures a View.
+type ViewOptions struct {
+	// Name identifies the frontend in logs.
+	Name string
+	// Keys is the key visibility policy.
+	Keys KeyPolicy
+	// ReadOnly rejects adding, removing and locking keys.
+	ReadOnly bool
+	// AllowControl allows mux control extensions (ping, config, shutdown).
+	AllowControl bool
+	// Tags are applied to keys added through the view.
+	Tags []string
+	// Extensions are the backend extensions passed on by a forwarding view or a view with a key
+	// policy, other extensions are refused. Views without either pass every extension on.
+	Extensions []string
+	// Forwarding restricts the view for use by forwarded agent connections.
+	Forwarding ForwardingOptions
+}
+
+// ForwardingOptions configures a view served to forwarded agent connections (ssh -A).
+//
+// A forwarding view is read-only, never serves control extensions, only shows keys that are
+// marked forwardable and asks for confirmation before every signature. Its sign requests are rate
+// limited with SignLimits.Forwarded.
+type ForwardingOptions struct {
+	Enabled bool
+	// Forwardable marks the keys that may be used through forwarding views, when empty no keys
+	// are forwardable.
+	Forwardable KeyPolicy
+	// Confirmer approves each signature, it defaults to AskpassConfirmer.
+	Confirmer Confirmer
+}
+
+// allows reports whether a key is marked forwardable.
+func (f ForwardingOptions) allows(key SourcedKey) bool {
+	if !f.Enabled {
+		return true
+	}
+
+	return !f.Forwardable.empty() && f.Forwardable.Allows(key)
+}
+
+// View is a filtered view over a MuxAgent, used to serve frontends that should only see some keys.
+type View struct {
+	mux  *MuxAgent
+	opts ViewOptions
+}
+
+// NewView creates a filtered view over the mux agent.
+func (m *MuxAgent) NewView(opts ViewOptions) *View {
+	if opts.Forwarding.Enabled {
+		opts.ReadOnly = true
+		opts.AllowControl = false
+
+		if opts.Forwarding.Confirmer == nil {
+			opts.Forwarding.Confirmer = AskpassConfirmer{}
+		}
+	}
+
+	return &View{mux: m, opts: opts}
+}
+
+// allows reports whether a key is visible under the view policies.
+func (v *View) allows(key SourcedKey) bool {
+	return v.opts.Keys.Allows(key) && v.opts.Forwarding.allows(key)
+}
+
+// List returns the identities visible through the view.
+func (v *View) List() ([]*agent.Key, error) {
+	v.mux.logger.DebugContext(v.mux.ctx, "View List called", slog.String("frontend", v.opts.Name))
+
+	var keys []*agent.Key
+	for _, sourcedKey := range v.mux.listWithSource() {
+		if v.allows(sourcedKey) {
+			keys = append(keys, sourcedKey.Key)
+		}
+	}
+
+	return keys, nil
+}
+
+// visible reports whether the key is visible through the view.
+func (v *View) visible(key ssh.PublicKey) bool {
+	_, ok := v.visibleBlobs()[string(key.Marshal())]
+	return ok
+}
+
+// visibleBlobs returns the public key blobs of all keys visible through the view.
+func (v *View) visibleBlobs() map[string]struct{} {
+	blobs := make(map[string]struct{})
+	for _, sourcedKey := range v.mux.listWithSource() {
+		if v.allows(sourcedKey) {
+			blobs[string(sourcedKey.Key.Blob)] = struct{}{}
+		}
+	}
+
+	return blobs
+}
+
+// Sign signs data with a key visible through the view.
+func (v *View) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return v.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data with a key visible through the view.
+func (v *View) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	return v.signFor(signCaller{}, key, data, flags)
+}
+
+// signFor signs data with a key visible through the view for a caller on the view's frontend.
+func (v *View) signFor(
+	caller signCaller, key ssh.PublicKey, data []byte, flags agent.SignatureFlags,
+) (*ssh.Signature, error) {
+	caller.Frontend = v.opts.Name
+	caller.Forwarded = v.opts.Forwarding.Enabled
+
+	if err := v.mux.checkSignLimits(caller, key); err != nil {
+		v.mux.signDenied(caller, key, err)
+		return nil, err
+	}
+	caller.limitsChecked = true
+
+	if !v.visible(key) {
+		v.mux.logger.DebugContext(v.mux.ctx, "Sign denied for key hidden by frontend policy",
+			slog.String("frontend", v.opts.Name),
+			slog.String("key-fingerprint", ssh.FingerprintSHA256(key)),
+		)
+		v.mux.signDenied(caller, key, ErrKeyNotVisible)
+		return nil, ErrKeyNotVisible
+	}
+
+	if v.opts.Forwarding.Enabled {
+		if err := v.confirmForwardedSign(key); err != nil {
+			v.mux.signDenied(caller, key, err)
+			return nil, err
+		}
+	}
+
+	return v.mux.signFor(caller, key, data, flags)
+}
+
+// confirmForwardedSign asks the user to approve the signature.
+func (v *View) confirmForwardedSign(key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	prompt := fmt.Sprintf("Allow use of key %s through forwarded agent %q?", fingerprint, v.opts.Name)
+	ok, err := v.opts.Forwarding.Confirmer.Confirm(v.mux.ctx, prompt)
+	if err != nil {
+		return fmt.Errorf("failed to confirm key use: %w", err)
+	}
+
+	if !ok {
+		v.mux.logger.WarnContext(v.mux.ctx, "Sign request denied on forwarding frontend",
+			slog.String("frontend", v.opts.Name),
+			slog.String("key-fingerprint", fingerprint),
+		)
+		return ErrConfirmationDenied
+	}
+
+	return nil
+}
+
+// Add adds a private key to the mux agent unless the view is read-only.
+func (v *View) Add(key agent.AddedKey) error {
+	if v.opts.ReadOnly {
+		return fmt.Errorf("adding key: %w", ErrReadOnly)
+	}
+
+	return v.mux.AddWithOptions(key, keystore.WithTags(v.opts.Tags...))
+}
+
+// Remove removes a local key visible through the view unless the view is read-only.
+func (v *View) Remove(key ssh.PublicKey) error {
+	if v.opts.ReadOnly {
+		return fmt.Errorf("removing key: %w", ErrReadOnly)
+	}
+
+	if !v.visible(key) {
+		return fmt.Errorf("removing key: %w", ErrKeyNotVisible)
+	}
+
+	return v.mux.Remove(key)
+}
+
+// AddSmartcardKey loads the keys of a PKCS#11 module unless the view is read-only.
+func (v *View) AddSmartcardKey(provider, pin string, lifetime uint32) error {
+	if v.opts.ReadOnly {
+		return fmt.Errorf("adding smartcard keys: %w", ErrReadOnly)
+	}
+
+	return v.mux.AddSmartcardKey(provider, pin, lifetime)
+}
+
+// RemoveSmartcardKey unloads a PKCS#11 module unless the view is read-only.
+func (v *View) RemoveSmartcardKey(provider string) error {
+	if v.opts.ReadOnly {
+		return fmt.Errorf("removing smartcard keys: %w", ErrReadOnly)
+	}
+
+	return v.mux.RemoveSmartcardKey(provider)
+}
+
+// RemoveAll removes the local keys visible through the view unless the view is read-only, only a
+// view without a key policy also unloads the PKCS#11 modules like ssh-agent.
+func (v *View) RemoveAll() error {
+	if v.opts.ReadOnly {
+		return fmt.Errorf("removing keys: %w", ErrReadOnly)
+	}
+
+	if v.opts.Keys.empty() {
+		return v.mux.RemoveAll()
+	}
+
+	for _, sourcedKey := range v.mux.listWithSource() {
+		if !sourcedKey.Source.Local || !v.allows(sourcedKey) {
+			continue
+		}
+
+		pubKey, err := ssh.ParsePublicKey(sourcedKey.Key.Blob)
+		if err != nil {
+			return err
+		}
+
+		// Certificates are removed with the key they were issued for
+		if cert, ok := pubKey.(*ssh.Certificate); ok {
+			pubKey = cert.Key
+		}
+
+		if err = v.mux.Remove(pubKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lock is passed through to the mux agent unless the view is read-only.
+func (v *View) Lock(passphrase []byte) error {
+	if v.opts.ReadOnly {
+		return fmt.Errorf("locking: %w", ErrReadOnly)
+	}
+
+	return v.mux.Lock(passphrase)
+}
+
+// Unlock is passed through to the mux agent unless the view is read-only.
+func (v *View) Unlock(passphrase []byte) error {
+	if v.opts.ReadOnly {
+		return fmt.Errorf("unlocking: %w", ErrReadOnly)
+	}
+
+	return v.mux.Unlock(passphrase)
+}
+
+// Signers returns signers for the keys visible through the view.
+//
+// Signers of a forwarding view sign through the view so every signature is confirmed.
+func (v *View) Signers() ([]ssh.Signer, error) {
+	signers, err := v.mux.Signers()
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := v.visibleBlobs()
+	visible := make([]ssh.Signer, 0, len(signers))
+	for _, signer := range signers {
+		if _, ok := blobs[string(signer.PublicKey().Marshal())]; !ok {
+			continue
+		}
+
+		if v.opts.Forwarding.Enabled {
+			signer = &viewSigner{view: v, pubKey: signer.PublicKey()}
+		}
+
+		visible = append(visible, signer)
+	}
+
+	return visible, nil
+}
+
+// Extension forwards extension requests, mux control extensions are only served when allowed and
+// otherwise only the backend extensions are listed by the query extension.
+func (v *View) Extension(extensionType string, contents []byte) ([]byte, error) {
+	if v.opts.AllowControl {
+		if v.mux.extensions.resolve(extensionType) == ExtensionKeygen {
+			return v.keygen(contents)
+		}
+
+		return v.mux.Extension(extensionType, contents)
+	}
+
+	if extensionType == ExtensionQuery {
+		return marshalQueryReply(slices.DeleteFunc(v.mux.queryBackends(), func(name string) bool {
+			return !v.allowsExtension(name)
+		})), nil
+	}
+
+	if !v.allowsExtension(extensionType) {
+		// Clients are told the extension is unsupported, like any extension no backend offers.
+		return nil, fmt.Errorf("%w: %s: %w", ErrExtensionFiltered, extensionType, agent.ErrExtensionUnsupported)
+	}
+
+	return v.mux.backendExtension(extensionType, contents)
+}
+
+// allowsExtension reports whether a backend extension may be passed on, forwarding views and views
+// with a key policy only pass on the extensions they allow.
+func (v *View) allowsExtension(extensionType string) bool {
+	if !v.opts.Forwarding.Enabled && v.opts.Keys.empty() {
+		return true
+	}
+
+	return slices.Contains(v.opts.Extensions, extensionType)
+}
+
+// viewSigner is a signer that signs through a view.
+type viewSigner struct {
+	view   *View
+	pubKey ssh.PublicKey
+}
+
+// PublicKey returns the public key of the signer.
+func (s *viewSigner) PublicKey() ssh.PublicKey {
+	return s.pubKey
+}
+
+// Sign signs data through the view.
+func (s *viewSigner) Sign(_ io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.view.Sign(s.pubKey, data)
+}
@@ -0,0 +1,265 @@
+package muxagent_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/na4ma4/go-contextual"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func addTestKey(t *testing.T, a agent.Agent, comment string) ssh.PublicKey {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if err = a.Add(agent.AddedKey{PrivateKey: privateKey, Comment: comment}); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	sshPubKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to convert to SSH public key: %v", err)
+	}
+
+	return sshPubKey
+}
+
+func TestViewFiltersKeysByComment(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	workKey := addTestKey(t, muxAgent, "deploy@work")
+	personalKey := addTestKey(t, muxAgent, "me@home")
+
+	view := muxAgent.NewView(muxagent.ViewOptions{
+		Name: "work",
+		Keys: muxagent.KeyPolicy{
+			Sources:  []string{muxagent.KeySourceLocal},
+			Comments: []string{"*@work"},
+		},
+	})
+
+	keys, err := view.List()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+
+	if len(keys) != 1 || keys[0].Comment != "deploy@work" {
+		t.Fatalf("Expected only the work key, got %v", keys)
+	}
+
+	data := []byte("test data to sign")
+	if _, err = view.Sign(workKey, data); err != nil {
+		t.Errorf("Failed to sign with visible key: %v", err)
+	}
+
+	if _, err = view.Sign(personalKey, data); !errors.Is(err, muxagent.ErrKeyNotVisible) {
+		t.Errorf("Expected ErrKeyNotVisible signing with hidden key, got %v", err)
+	}
+}
+
+func TestViewOnlyRemovesVisibleKeys(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	addTestKey(t, muxAgent, "deploy@work")
+	personalKey := addTestKey(t, muxAgent, "me@home")
+
+	view := muxAgent.NewView(muxagent.ViewOptions{
+		Name: "work",
+		Keys: muxagent.KeyPolicy{Comments: []string{"*@work"}},
+	})
+
+	if err = view.Remove(personalKey); !errors.Is(err, muxagent.ErrKeyNotVisible) {
+		t.Errorf("Expected ErrKeyNotVisible removing hidden key, got %v", err)
+	}
+
+	if err = view.RemoveAll(); err != nil {
+		t.Fatalf("Failed to remove keys: %v", err)
+	}
+
+	keys, err := muxAgent.List()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+
+	if len(keys) != 1 || keys[0].Comment != "me@home" {
+		t.Errorf("Expected only the hidden key to remain, got %v", keys)
+	}
+}
+
+func TestReadOnlyViewRejectsChanges(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	view := muxAgent.NewView(muxagent.ViewOptions{Name: "vm", ReadOnly: true})
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if err = view.Add(agent.AddedKey{PrivateKey: privateKey}); !errors.Is(err, muxagent.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly adding key, got %v", err)
+	}
+
+	if err = view.RemoveAll(); !errors.Is(err, muxagent.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly removing keys, got %v", err)
+	}
+
+	if _, err = view.Extension("shutdown", nil); !errors.Is(err, agent.ErrExtensionUnsupported) {
+		t.Errorf("Expected control extensions to be unsupported, got %v", err)
+	}
+}
+
+func TestViewTagsKeysAddedThroughIt(t *testing.T) {
+	muxAgent, err := muxagent.NewMuxAgent(contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	work := muxAgent.NewView(muxagent.ViewOptions{
+		Name: "work",
+		Tags: []string{"work"},
+		Keys: muxagent.KeyPolicy{
+			Any: []muxagent.KeyPolicy{
+				{Sources: []string{muxagent.KeySourceLocal}, Tags: []string{"work"}},
+				{Comments: []string{"Work *"}},
+			},
+		},
+	})
+	ci := muxAgent.NewView(muxagent.ViewOptions{
+		Name: "ci",
+		Keys: muxagent.KeyPolicy{Ephemeral: true},
+	})
+
+	addTestKey(t, work, "tagged")
+	addTestKey(t, muxAgent, "Work laptop")
+	addTestKey(t, muxAgent, "personal")
+
+	keys, err := work.List()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+
+	if len(keys) != 2 {
+		t.Errorf("Expected tagged and work keys to be visible, got %v", keys)
+	}
+
+	for _, key := range keys {
+		if key.Comment == "personal" {
+			t.Errorf("Expected personal key to be hidden, got %v", keys)
+		}
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if err = muxAgent.Add(agent.AddedKey{PrivateKey: privateKey, Comment: "deploy", LifetimeSecs: 60}); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	if keys, err = ci.List(); err != nil || len(keys) != 1 || keys[0].Comment != "deploy" {
+		t.Errorf("Expected only the ephemeral key to be visible, got %v (%v)", keys, err)
+	}
+}
+
+type stubConfirmer struct {
+	approve bool
+	prompts int
+}
+
+func (c *stubConfirmer) Confirm(context.Context, string) (bool, error) {
+	c.prompts++
+	return c.approve, nil
+}
+
+func TestForwardingViewConfirmsAndLimitsSigns(t *testing.T) {
+	// Every sign request through the forwarding frontend takes from its bucket, including the
+	// ones refused afterwards.
+	muxAgent, err := muxagent.NewMuxAgent(
+		contextual.New(t.Context()), slog.New(slog.DiscardHandler), defaultConfig(),
+		muxagent.WithSignLimits(muxagent.SignLimits{
+			Forwarded: muxagent.SignRateLimit{PerMinute: 0.001, Burst: 3},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create mux agent: %v", err)
+	}
+	defer muxAgent.Close()
+
+	forwardableKey := addTestKey(t, muxAgent, "shared@forward")
+	privateKey := addTestKey(t, muxAgent, "me@home")
+
+	confirmer := &stubConfirmer{approve: true}
+	view := muxAgent.NewView(muxagent.ViewOptions{
+		Name:         "forwarded",
+		AllowControl: true,
+		Forwarding: muxagent.ForwardingOptions{
+			Enabled:     true,
+			Forwardable: muxagent.KeyPolicy{Comments: []string{"*@forward"}},
+			Confirmer:   confirmer,
+		},
+	})
+
+	keys, err := view.List()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+
+	if len(keys) != 1 || keys[0].Comment != "shared@forward" {
+		t.Fatalf("Expected only the forwardable key, got %v", keys)
+	}
+
+	data := []byte("test data to sign")
+	if _, err = view.Sign(privateKey, data); !errors.Is(err, muxagent.ErrKeyNotVisible) {
+		t.Errorf("Expected ErrKeyNotVisible for key not marked forwardable, got %v", err)
+	}
+
+	if _, err = view.Sign(forwardableKey, data); err != nil {
+		t.Errorf("Failed to sign with confirmed key: %v", err)
+	}
+
+	confirmer.approve = false
+	if _, err = view.Sign(forwardableKey, data); !errors.Is(err, muxagent.ErrConfirmationDenied) {
+		t.Errorf("Expected ErrConfirmationDenied, got %v", err)
+	}
+
+	if _, err = view.Sign(forwardableKey, data); !errors.Is(err, muxagent.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+
+	if confirmer.prompts != 2 {
+		t.Errorf("Expected 2 confirmation prompts, got %d", confirmer.prompts)
+	}
+
+	if err = view.RemoveAll(); !errors.Is(err, muxagent.ErrReadOnly) {
+		t.Errorf("Expected forwarding view to be read-only, got %v", err)
+	}
+
+	if _, err = view.Extension("shutdown", nil); !errors.Is(err, agent.ErrExtensionUnsupported) {
+		t.Errorf("Expected control extensions to be unsupported, got %v", err)
+	}
+}
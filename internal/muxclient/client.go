@@ -2,10 +2,17 @@ package muxclient
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net"
+	"os"
+	"strings"
 	"sync"
 
+	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
 	"golang.org/x/crypto/ssh/agent"
 )
 
@@ -19,26 +26,120 @@ type CloseFunc func()
 type MuxClient struct {
 	logger        *slog.Logger
 	socketPath    string
+	controlSocket string
+	tokenFile     string
 	closeFuncOnce sync.Once
 }
 
+// Option configures a MuxClient.
+type Option func(*MuxClient)
+
+// WithTokenFile authenticates connections with the control token read from path, which admin
+// extensions need when the mux agent has a control token. A missing file is ignored.
+func WithTokenFile(path string) Option {
+	return func(c *MuxClient) {
+		c.tokenFile = path
+	}
+}
+
+// WithControlSocket sends control requests over gRPC to the mux agent's dedicated control socket
+// instead of as extensions on the agent socket, which the mux agent needs for admin requests when
+// it has a control socket.
+func WithControlSocket(path string) Option {
+	return func(c *MuxClient) {
+		c.controlSocket = path
+	}
+}
+
 // NewMuxClient creates a new MuxClient connected to the specified socket path.
-func NewMuxClient(logger *slog.Logger, socketPath string) (*MuxClient, error) {
-	return &MuxClient{
+func NewMuxClient(logger *slog.Logger, socketPath string, opts ...Option) (*MuxClient, error) {
+	c := &MuxClient{
 		logger:        logger,
 		socketPath:    socketPath,
 		closeFuncOnce: sync.Once{},
-	}, nil
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
 }
 
-// connect establishes a connection to the mux agent and returns an ExtendedAgent client.
+// connect establishes a connection to the mux agent and returns an ExtendedAgent client. The
+// connection is closed when ctx is done, so a mux agent that accepts the connection but never
+// replies does not hang the request.
 func (c *MuxClient) connect(ctx context.Context) (agent.ExtendedAgent, CloseFunc, error) {
 	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", c.socketPath)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	closeConn := func() {
+		stop()
+		_ = conn.Close()
+	}
+
 	muxClient := agent.NewClient(conn)
 
-	return muxClient, func() { c.closeFuncOnce.Do(func() { _ = conn.Close() }) }, nil
+	if err = c.authenticate(ctx, muxClient); err != nil {
+		closeConn()
+		return nil, nil, err
+	}
+
+	return muxClient, func() { c.closeFuncOnce.Do(closeConn) }, nil
+}
+
+// control returns a client for the control API, over gRPC when there is a control socket and
+// otherwise through the control extensions of the agent socket.
+func (c *MuxClient) control(ctx context.Context) (api.MuxControlClient, CloseFunc, error) {
+	if c.controlSocket != "" {
+		return c.dialControl(ctx)
+	}
+
+	client, cancel, err := c.connect(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return extensionClient{agent: client}, cancel, nil
+}
+
+// readToken returns the control token, empty when there is no token file.
+func (c *MuxClient) readToken(ctx context.Context) (string, error) {
+	if c.tokenFile == "" {
+		return "", nil
+	}
+
+	token, err := os.ReadFile(c.tokenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		c.logger.DebugContext(ctx, "Control token file does not exist", slog.String("token-file", c.tokenFile))
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read control token: %w", err)
+	}
+
+	return strings.TrimSpace(string(token)), nil
+}
+
+// authenticate sends the control token, if there is one, to the mux agent.
+func (c *MuxClient) authenticate(ctx context.Context, client agent.ExtendedAgent) error {
+	token, err := c.readToken(ctx)
+	if err != nil || token == "" {
+		return err
+	}
+
+	_, err = client.Extension(muxagent.ExtensionAuth, []byte(token))
+	if errors.Is(err, agent.ErrExtensionUnsupported) {
+		// The mux agent has no control token.
+		c.logger.DebugContext(ctx, "Mux agent does not use a control token", slogtool.ErrorAttr(err))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("control token rejected: %w", err)
+	}
+
+	return nil
 }
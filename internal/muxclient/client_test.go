@@ -0,0 +1,53 @@
+package muxclient_test
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/muxclient"
+)
+
+func TestPingGivesUpOnUnresponsiveAgent(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := (&net.ListenConfig{}).Listen(t.Context(), "unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	// Accept connections but never answer them.
+	go func() {
+		for {
+			conn, acceptErr := l.Accept()
+			if acceptErr != nil {
+				return
+			}
+
+			go func() {
+				<-t.Context().Done()
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	client, err := muxclient.NewMuxClient(slog.New(slog.DiscardHandler), socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err = client.Ping(ctx); err == nil {
+		t.Fatal("Expected ping to fail against an agent that never replies")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected ping to give up at the context deadline, took %s", elapsed)
+	}
+}
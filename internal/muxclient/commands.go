@@ -0,0 +1,198 @@
+package muxclient
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Ping sends a ping request to the mux agent and returns the pong response.
+func (c *MuxClient) Ping(ctx context.Context) (*api.Pong, error) {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return client.Ping(ctx, api.Ping_builder{
+		Id: proto.String(uuid.NewString()),
+		Ts: timestamppb.Now(),
+	}.Build())
+}
+
+// GetConfig retrieves the current configuration from the mux agent.
+func (c *MuxClient) GetConfig(ctx context.Context) (*api.Config, error) {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return client.GetConfig(ctx, api.ConfigRequest_builder{
+		Id: proto.String(uuid.NewString()),
+		Ts: timestamppb.Now(),
+	}.Build())
+}
+
+// Shutdown sends a shutdown request to the mux agent and returns the response.
+// When force is set the mux agent terminates active connections instead of draining them.
+func (c *MuxClient) Shutdown(ctx context.Context, force bool) (*api.CommandResponse, error) {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return client.Shutdown(ctx, api.ShutdownRequest_builder{
+		Id:    proto.String(uuid.NewString()),
+		Ts:    timestamppb.Now(),
+		Force: proto.Bool(force),
+	}.Build())
+}
+
+// Keygen asks the mux agent to generate a key and returns its public key, the private key
+// never leaves the mux agent.
+func (c *MuxClient) Keygen(ctx context.Context, req *api.KeygenRequest) (*api.KeygenResponse, error) {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	req.SetId(uuid.NewString())
+	req.SetTs(timestamppb.Now())
+
+	return client.Keygen(ctx, req)
+}
+
+// CertIssue asks the mux agent to issue a certificate for a local key with its built-in
+// certificate authority, the certificate is attached to the key in the mux agent.
+func (c *MuxClient) CertIssue(ctx context.Context, req *api.CertIssueRequest) (*api.CertIssueResponse, error) {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	req.SetId(uuid.NewString())
+	req.SetTs(timestamppb.Now())
+
+	return client.CertIssue(ctx, req)
+}
+
+// ListKeys returns the keys known to the mux agent and where each one came from.
+func (c *MuxClient) ListKeys(ctx context.Context) (*api.ListKeysResponse, error) {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return client.ListKeys(ctx, api.ListKeysRequest_builder{
+		Id: proto.String(uuid.NewString()),
+		Ts: timestamppb.Now(),
+	}.Build())
+}
+
+// ManageBackends lists, adds or removes the backend agents of the mux agent and returns the
+// backend agents after the change.
+func (c *MuxClient) ManageBackends(
+	ctx context.Context, action api.BackendAction, socketPath string,
+) (*api.ManageBackendsResponse, error) {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return client.ManageBackends(ctx, api.ManageBackendsRequest_builder{
+		Id:         proto.String(uuid.NewString()),
+		Ts:         timestamppb.Now(),
+		Action:     action.Enum(),
+		SocketPath: proto.String(socketPath),
+	}.Build())
+}
+
+// Status returns whether signing is locked and the peers with denied or rate limited sign
+// requests.
+func (c *MuxClient) Status(ctx context.Context) (*api.StatusResponse, error) {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return client.GetStatus(ctx, api.StatusRequest_builder{
+		Id: proto.String(uuid.NewString()),
+		Ts: timestamppb.Now(),
+	}.Build())
+}
+
+// Unlock clears the sign lockout of the mux agent.
+func (c *MuxClient) Unlock(ctx context.Context) (*api.CommandResponse, error) {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return client.Unlock(ctx, api.UnlockRequest_builder{
+		Id: proto.String(uuid.NewString()),
+		Ts: timestamppb.Now(),
+	}.Build())
+}
+
+// Watch streams the events of the mux agent to fn until the context is done, the mux agent stops or
+// fn returns an error, only events of the given types are sent when types is not empty.
+func (c *MuxClient) Watch(ctx context.Context, types []api.EventType, fn func(*api.Event) error) error {
+	client, cancel, err := c.control(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	stream, err := client.StreamEvents(ctx, api.StreamEventsRequest_builder{
+		Id:    proto.String(uuid.NewString()),
+		Ts:    timestamppb.Now(),
+		Types: types,
+	}.Build())
+	if err != nil {
+		return err
+	}
+
+	for {
+		ev, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			return nil
+		}
+		if recvErr != nil {
+			return recvErr
+		}
+
+		if err = fn(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// Query returns the names of the extensions supported by the mux agent.
+func (c *MuxClient) Query(ctx context.Context) ([]string, error) {
+	client, cancel, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	reply, err := client.Extension(muxagent.ExtensionQuery, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return muxagent.ParseQueryReply(reply)
+}
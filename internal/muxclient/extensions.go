@@ -0,0 +1,105 @@
+package muxclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
+	"golang.org/x/crypto/ssh/agent"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrNeedsControlSocket indicates a control API call that is only served on the control socket.
+var ErrNeedsControlSocket = errors.New("call is only served on the control socket")
+
+// protoMessage is a pointer to a protobuf message type.
+type protoMessage[T any] interface {
+	proto.Message
+	*T
+}
+
+// extensionClient calls the control API through the mux control extensions of the agent socket.
+type extensionClient struct {
+	agent agent.ExtendedAgent
+}
+
+// callExtension sends a protobuf request as an extension and returns the protobuf reply.
+func callExtension[T, P any, TT protoMessage[T], PP protoMessage[P]](
+	client agent.ExtendedAgent, extensionType string, in *T,
+) (*P, error) {
+	return muxagent.HandleExtensionProtoInvert[T, P, TT, PP](in, func(inBytes []byte) ([]byte, error) {
+		return client.Extension(extensionType, inBytes)
+	})
+}
+
+// Ping calls the ping extension.
+func (e extensionClient) Ping(_ context.Context, in *api.Ping, _ ...grpc.CallOption) (*api.Pong, error) {
+	return callExtension[api.Ping, api.Pong](e.agent, muxagent.ExtensionPing, in)
+}
+
+// GetConfig calls the config extension.
+func (e extensionClient) GetConfig(
+	_ context.Context, in *api.ConfigRequest, _ ...grpc.CallOption,
+) (*api.Config, error) {
+	return callExtension[api.ConfigRequest, api.Config](e.agent, muxagent.ExtensionConfig, in)
+}
+
+// Shutdown calls the shutdown extension.
+func (e extensionClient) Shutdown(
+	_ context.Context, in *api.ShutdownRequest, _ ...grpc.CallOption,
+) (*api.CommandResponse, error) {
+	return callExtension[api.ShutdownRequest, api.CommandResponse](e.agent, muxagent.ExtensionShutdown, in)
+}
+
+// Keygen calls the keygen extension.
+func (e extensionClient) Keygen(
+	_ context.Context, in *api.KeygenRequest, _ ...grpc.CallOption,
+) (*api.KeygenResponse, error) {
+	return callExtension[api.KeygenRequest, api.KeygenResponse](e.agent, muxagent.ExtensionKeygen, in)
+}
+
+// CertIssue calls the cert-issue extension.
+func (e extensionClient) CertIssue(
+	_ context.Context, in *api.CertIssueRequest, _ ...grpc.CallOption,
+) (*api.CertIssueResponse, error) {
+	return callExtension[api.CertIssueRequest, api.CertIssueResponse](e.agent, muxagent.ExtensionCertIssue, in)
+}
+
+// ListKeys calls the list-keys extension.
+func (e extensionClient) ListKeys(
+	_ context.Context, in *api.ListKeysRequest, _ ...grpc.CallOption,
+) (*api.ListKeysResponse, error) {
+	return callExtension[api.ListKeysRequest, api.ListKeysResponse](e.agent, muxagent.ExtensionListKeys, in)
+}
+
+// ManageBackends calls the backends extension.
+func (e extensionClient) ManageBackends(
+	_ context.Context, in *api.ManageBackendsRequest, _ ...grpc.CallOption,
+) (*api.ManageBackendsResponse, error) {
+	return callExtension[api.ManageBackendsRequest, api.ManageBackendsResponse](
+		e.agent, muxagent.ExtensionBackends, in,
+	)
+}
+
+// GetStatus calls the status extension.
+func (e extensionClient) GetStatus(
+	_ context.Context, in *api.StatusRequest, _ ...grpc.CallOption,
+) (*api.StatusResponse, error) {
+	return callExtension[api.StatusRequest, api.StatusResponse](e.agent, muxagent.ExtensionStatus, in)
+}
+
+// Unlock calls the unlock extension.
+func (e extensionClient) Unlock(
+	_ context.Context, in *api.UnlockRequest, _ ...grpc.CallOption,
+) (*api.CommandResponse, error) {
+	return callExtension[api.UnlockRequest, api.CommandResponse](e.agent, muxagent.ExtensionUnlock, in)
+}
+
+// StreamEvents fails, events can only be streamed from the control socket.
+func (extensionClient) StreamEvents(
+	context.Context, *api.StreamEventsRequest, ...grpc.CallOption,
+) (grpc.ServerStreamingClient[api.Event], error) {
+	return nil, ErrNeedsControlSocket
+}
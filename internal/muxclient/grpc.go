@@ -2,88 +2,51 @@ package muxclient
 
 import (
 	"context"
+	"fmt"
 
-	"github.com/google/uuid"
 	"github.com/na4ma4/ssh-agent-mux/api"
 	"github.com/na4ma4/ssh-agent-mux/internal/muxagent"
-	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
-// Ping sends a ping request to the mux agent and returns the pong response.
-func (c *MuxClient) Ping(ctx context.Context) (*api.Pong, error) {
-	client, cancel, err := c.connect(ctx)
+// dialControl connects to the control API on the control socket, the control token is attached
+// to every call when there is one.
+func (c *MuxClient) dialControl(ctx context.Context) (api.MuxControlClient, CloseFunc, error) {
+	token, err := c.readToken(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer cancel()
 
-	msg, err := muxagent.HandleExtensionProtoInvert[
-		api.Ping, api.Pong,
-	](
-		api.Ping_builder{
-			Id: proto.String(uuid.NewString()),
-			Ts: timestamppb.Now(),
-		}.Build(),
-		func(inBytes []byte) ([]byte, error) {
-			return client.Extension("ping", inBytes)
-		},
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	return msg, nil
-}
-
-// GetConfig retrieves the current configuration from the mux agent.
-func (c *MuxClient) GetConfig(ctx context.Context) (*api.Config, error) {
-	client, cancel, err := c.connect(ctx)
-	if err != nil {
-		return nil, err
+	opts := []grpc.DialOption{
+		// The control socket is only accessible to the current user and is not encrypted.
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	}
-	defer cancel()
 
-	configMsg, err := muxagent.HandleExtensionProtoInvert[
-		api.ConfigRequest, api.Config,
-	](
-		api.ConfigRequest_builder{
-			Id: proto.String(uuid.NewString()),
-			Ts: timestamppb.Now(),
-		}.Build(),
-		func(inBytes []byte) ([]byte, error) {
-			return client.Extension("config", inBytes)
-		},
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	return configMsg, nil
-}
-
-// Shutdown sends a shutdown request to the mux agent and returns the response.
-func (c *MuxClient) Shutdown(ctx context.Context) (*api.CommandResponse, error) {
-	client, cancel, err := c.connect(ctx)
-	if err != nil {
-		return nil, err
+	if token != "" {
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(func(
+				ctx context.Context, method string, req, reply any,
+				cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption,
+			) error {
+				ctx = metadata.AppendToOutgoingContext(ctx, muxagent.ControlTokenMetadata, token)
+				return invoker(ctx, method, req, reply, cc, callOpts...)
+			}),
+			grpc.WithChainStreamInterceptor(func(
+				ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+				method string, streamer grpc.Streamer, callOpts ...grpc.CallOption,
+			) (grpc.ClientStream, error) {
+				ctx = metadata.AppendToOutgoingContext(ctx, muxagent.ControlTokenMetadata, token)
+				return streamer(ctx, desc, cc, method, callOpts...)
+			}),
+		)
 	}
-	defer cancel()
 
-	msg, err := muxagent.HandleExtensionProtoInvert[
-		api.ShutdownRequest, api.CommandResponse,
-	](
-		api.ShutdownRequest_builder{
-			Id: proto.String(uuid.NewString()),
-			Ts: timestamppb.Now(),
-		}.Build(),
-		func(inBytes []byte) ([]byte, error) {
-			return client.Extension("shutdown", inBytes)
-		},
-	)
+	conn, err := grpc.NewClient("unix:"+c.controlSocket, opts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("failed to connect to control socket: %w", err)
 	}
 
-	return msg, nil
+	return api.NewMuxControlClient(conn), func() { _ = conn.Close() }, nil
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/na4ma4/go-slogtool"
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
 )
 
 func (c *MuxClient) RemoveSocket(ctx context.Context) error {
@@ -21,8 +22,8 @@ func (c *MuxClient) RemoveSocket(ctx context.Context) error {
 }
 
 func (c *MuxClient) SocketExists(ctx context.Context) (bool, error) {
-	// check if socket exists
-	stat, err := os.Stat(c.socketPath)
+	// check if socket exists, without following symlinks
+	stat, err := os.Lstat(c.socketPath)
 	if os.IsNotExist(err) {
 		c.logger.DebugContext(ctx, "Socket does not exist", slog.String("socket-path", c.socketPath))
 		return false, nil
@@ -35,6 +36,15 @@ func (c *MuxClient) SocketExists(ctx context.Context) (bool, error) {
 		return false, err
 	}
 
+	// refuse to touch symlinks or sockets owned by another user
+	if err = paths.CheckOwner(c.socketPath, stat); err != nil {
+		c.logger.DebugContext(ctx, "Socket path is not safe to use",
+			slog.String("socket-path", c.socketPath),
+			slogtool.ErrorAttr(err),
+		)
+		return true, err
+	}
+
 	// check if file is a socket
 	if stat.Mode()&os.ModeSocket == 0 {
 		c.logger.DebugContext(ctx, "File exists and is not a socket", slog.String("socket-path", c.socketPath))
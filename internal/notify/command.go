@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/hookexec"
+)
+
+const defaultCommandTimeout = 5 * time.Second
+
+// CommandConfig configures a command hook notifier.
+type CommandConfig struct {
+	// Command is the program and arguments to run, the summary and body are appended as the last
+	// two arguments so that eg. notify-send can be used directly.
+	Command []string `mapstructure:"command"`
+	// Timeout limits each run, it defaults to 5s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// CommandNotifier shows notifications by running a command.
+//
+// The command receives the notification details in the SSH_AGENT_MUX_KEY_FINGERPRINT,
+// SSH_AGENT_MUX_KEY_SOURCE, SSH_AGENT_MUX_FRONTEND, SSH_AGENT_MUX_PEER_PID and
+// SSH_AGENT_MUX_PEER_PROCESS environment variables.
+type CommandNotifier struct {
+	config CommandConfig
+}
+
+// NewCommandNotifier creates a command hook notifier.
+func NewCommandNotifier(config CommandConfig) (*CommandNotifier, error) {
+	if len(config.Command) == 0 {
+		return nil, fmt.Errorf("%w: command is required", ErrInvalidConfig)
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultCommandTimeout
+	}
+
+	return &CommandNotifier{config: config}, nil
+}
+
+// Notify runs the command for the notification.
+func (c *CommandNotifier) Notify(ctx context.Context, n Notification) error {
+	args := append(slices.Clone(c.config.Command), n.Summary, n.Body)
+
+	if _, err := (hookexec.Command{
+		Args: args,
+		Env: []string{
+			"SSH_AGENT_MUX_KEY_FINGERPRINT=" + n.KeyFingerprint,
+			"SSH_AGENT_MUX_KEY_SOURCE=" + n.KeySource,
+			"SSH_AGENT_MUX_FRONTEND=" + n.Frontend,
+			"SSH_AGENT_MUX_PEER_PID=" + strconv.Itoa(n.PeerPID),
+			"SSH_AGENT_MUX_PEER_PROCESS=" + n.PeerProcess,
+		},
+		Timeout: c.config.Timeout,
+	}).Run(ctx); err != nil {
+		return fmt.Errorf("notification command failed: %w", err)
+	}
+
+	return nil
+}
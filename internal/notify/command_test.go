@@ -0,0 +1,37 @@
+package notify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/notify"
+)
+
+func TestCommandNotifierRunsHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "notification")
+
+	notifier, err := notify.NewCommandNotifier(notify.CommandConfig{
+		Command: []string{"/bin/sh", "-c", `echo "$1|$2|$SSH_AGENT_MUX_PEER_PROCESS|$SSH_AGENT_MUX_KEY_SOURCE" > "$0"`, out},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+
+	if err = notifier.Notify(t.Context(), notify.Notification{
+		Summary:     "SSH key used",
+		Body:        "signed by ssh",
+		KeySource:   "local",
+		PeerProcess: "ssh",
+	}); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	if got, _ := os.ReadFile(out); string(got) != "SSH key used|signed by ssh|ssh|local\n" {
+		t.Errorf("Expected summary, body and details passed to the hook, got %q", got)
+	}
+
+	if _, err = notify.NewCommandNotifier(notify.CommandConfig{}); err == nil {
+		t.Error("Expected an error for a notifier without a command")
+	}
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	defaultDBusTimeout = 5 * time.Second
+	defaultAppName     = "ssh-agent-mux"
+
+	// dbusSessionBusEnv is the environment variable holding the session bus address.
+	dbusSessionBusEnv = "DBUS_SESSION_BUS_ADDRESS"
+
+	notificationsName = "org.freedesktop.Notifications"
+	notificationsPath = "/org/freedesktop/Notifications"
+)
+
+var (
+	// ErrNoSessionBus indicates that there is no D-Bus session bus to send notifications to.
+	ErrNoSessionBus = errors.New("no D-Bus session bus address")
+	// ErrDBusCall indicates that the bus or the notification server returned an error.
+	ErrDBusCall = errors.New("D-Bus call failed")
+)
+
+// DBusConfig configures a freedesktop notifications notifier.
+type DBusConfig struct {
+	// Address is the session bus address, it defaults to $DBUS_SESSION_BUS_ADDRESS when the
+	// notification is sent.
+	Address string `mapstructure:"address"`
+	// AppName is the application name shown with the notifications, it defaults to ssh-agent-mux.
+	AppName string `mapstructure:"app-name"`
+	// Icon is the icon name or file URI shown with the notifications.
+	Icon string `mapstructure:"icon"`
+	// Timeout limits each notification, it defaults to 5s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// DBusNotifier shows notifications with the org.freedesktop.Notifications service on the D-Bus
+// session bus, each notification uses its own bus connection.
+type DBusNotifier struct {
+	config DBusConfig
+}
+
+// NewDBusNotifier creates a freedesktop notifications notifier.
+func NewDBusNotifier(config DBusConfig) *DBusNotifier {
+	if config.AppName == "" {
+		config.AppName = defaultAppName
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultDBusTimeout
+	}
+
+	return &DBusNotifier{config: config}
+}
+
+// Notify sends the notification to the notification server.
+func (d *DBusNotifier) Notify(ctx context.Context, n Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	address := d.config.Address
+	if address == "" {
+		address = os.Getenv(dbusSessionBusEnv)
+	}
+
+	if address == "" {
+		return ErrNoSessionBus
+	}
+
+	conn, err := dbus.Connect(address, dbus.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to connect to D-Bus session bus: %w", err)
+	}
+	defer conn.Close()
+
+	call := conn.Object(notificationsName, notificationsPath).CallWithContext(
+		ctx, notificationsName+".Notify", 0,
+		d.config.AppName, uint32(0), d.config.Icon, n.Summary, n.Body,
+		[]string{}, map[string]dbus.Variant{}, int32(-1),
+	)
+	if call.Err != nil {
+		return fmt.Errorf("%w: %w", ErrDBusCall, call.Err)
+	}
+
+	return nil
+}
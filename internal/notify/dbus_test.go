@@ -0,0 +1,44 @@
+package notify_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/notify"
+)
+
+func TestDBusNotifierSendsNotification(t *testing.T) {
+	address, calls := notify.StartDBusStandIn(t)
+
+	notifier := notify.NewDBusNotifier(notify.DBusConfig{Address: address})
+	err := notifier.Notify(t.Context(), notify.Notification{Summary: "SSH key used", Body: "signed by ssh"})
+	if err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	if call := <-calls; call.Member != "Hello" {
+		t.Fatalf("Expected Hello call first, got %q", call.Member)
+	}
+
+	call := <-calls
+	if call.Member != "Notify" || len(call.Body) != 8 {
+		t.Fatalf("Expected Notify call with 8 arguments, got %q with %v", call.Member, call.Body)
+	}
+
+	if call.Body[0] != "ssh-agent-mux" || call.Body[3] != "SSH key used" || call.Body[4] != "signed by ssh" {
+		t.Errorf("Expected app name, summary and body in Notify call, got %v", call.Body)
+	}
+
+	if call.Body[7] != int32(-1) {
+		t.Errorf("Expected default expiry timeout, got %v", call.Body[7])
+	}
+}
+
+func TestDBusNotifierWithoutSessionBus(t *testing.T) {
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "")
+
+	err := notify.NewDBusNotifier(notify.DBusConfig{}).Notify(t.Context(), notify.Notification{Summary: "SSH key used"})
+	if !errors.Is(err, notify.ErrNoSessionBus) {
+		t.Errorf("Expected ErrNoSessionBus, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// DBusCall is a method call received by the session bus stand-in.
+type DBusCall struct {
+	Member string
+	Body   []any
+}
+
+// StartDBusStandIn serves a session bus stand-in that answers Hello and Notify calls like the bus
+// and a notification server would, it returns the bus address and the calls it received.
+func StartDBusStandIn(t *testing.T) (string, <-chan DBusCall) {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "bus")
+	l, err := (&net.ListenConfig{}).Listen(t.Context(), "unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen on stand-in bus: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	calls := make(chan DBusCall, 10) //nolint:mnd // more calls than any test makes
+
+	go func() {
+		for {
+			conn, acceptErr := l.Accept()
+			if acceptErr != nil {
+				return
+			}
+
+			go serveDBusStandIn(conn, calls)
+		}
+	}()
+
+	return "unix:path=" + socketPath + ",guid=0123456789abcdef0123456789abcdef", calls
+}
+
+// serveDBusStandIn authenticates a client and answers its method calls.
+func serveDBusStandIn(conn net.Conn, calls chan<- DBusCall) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	if nul, err := reader.ReadByte(); err != nil || nul != 0 {
+		return
+	}
+
+	if !authenticateDBusStandIn(conn, reader) {
+		return
+	}
+
+	for {
+		msg, err := dbus.DecodeMessage(reader)
+		if err != nil {
+			return
+		}
+
+		member, _ := msg.Headers[dbus.FieldMember].Value().(string)
+		calls <- DBusCall{Member: member, Body: msg.Body}
+
+		reply := &dbus.Message{
+			Type:    dbus.TypeMethodReply,
+			Headers: map[dbus.HeaderField]dbus.Variant{dbus.FieldReplySerial: dbus.MakeVariant(msg.Serial())},
+		}
+		switch member {
+		case "Hello":
+			reply.Body = []any{":1.1"}
+		case "Notify":
+			reply.Body = []any{uint32(1)}
+		}
+		reply.Headers[dbus.FieldSignature] = dbus.MakeVariant(dbus.SignatureOf(reply.Body...))
+
+		if err = reply.EncodeTo(conn, binary.LittleEndian); err != nil {
+			return
+		}
+	}
+}
+
+// authenticateDBusStandIn accepts the EXTERNAL mechanism and refuses file descriptor passing, it
+// reports whether the client began the message stream.
+func authenticateDBusStandIn(conn net.Conn, reader *bufio.Reader) bool {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return false
+		}
+
+		var reply string
+		switch line = strings.TrimSuffix(line, "\r\n"); {
+		case line == "BEGIN":
+			return true
+		case line == "AUTH":
+			reply = "REJECTED EXTERNAL"
+		case strings.HasPrefix(line, "AUTH EXTERNAL"):
+			reply = "OK 0123456789abcdef0123456789abcdef"
+		case line == "NEGOTIATE_UNIX_FD":
+			reply = "ERROR"
+		default:
+			return false
+		}
+
+		if _, err = conn.Write([]byte(reply + "\r\n")); err != nil {
+			return false
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package notify sends desktop notifications about the use of the mux agent's keys.
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidConfig indicates a notifier configuration that cannot be used.
+var ErrInvalidConfig = errors.New("invalid notifier config")
+
+// Notification describes the use of a key.
+type Notification struct {
+	Summary string
+	Body    string
+
+	KeyFingerprint string
+	// KeySource is "local", the PKCS#11 module path or the backend agent socket path.
+	KeySource string
+	// Frontend is the listener the request came in on, empty for requests without a frontend.
+	Frontend string
+	// PeerPID is the requesting process id, zero when it is unknown.
+	PeerPID int
+	// PeerProcess is the requesting process name, empty when it is unknown.
+	PeerProcess string
+}
+
+// Notifier shows notifications to the user.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
@@ -0,0 +1,61 @@
+// Package paths resolves the per-user file locations used by ssh-agent-mux.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+const appName = "ssh-agent-mux"
+
+// DefaultProfile is the name of the profile used when no profile is specified.
+const DefaultProfile = "default"
+
+// ErrInvalidProfile indicates that a profile name contains unsupported characters.
+var ErrInvalidProfile = errors.New("invalid profile name")
+
+//nolint:gochecknoglobals // compiled once.
+var profileNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ValidateProfile checks that a profile name is safe to use in file names.
+func ValidateProfile(profile string) error {
+	if !profileNameRegexp.MatchString(profile) {
+		return fmt.Errorf("%w: %q", ErrInvalidProfile, profile)
+	}
+
+	return nil
+}
+
+// RuntimeDir returns the per-user runtime directory, $XDG_RUNTIME_DIR/ssh-agent-mux when set,
+// otherwise a per-user directory in the system temporary directory.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, appName)
+	}
+
+	return filepath.Join(os.TempDir(), appName+"-"+strconv.Itoa(os.Getuid()))
+}
+
+// ConfigDir returns the per-user configuration directory.
+func ConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		homeDir, _ := os.UserHomeDir()
+		dir = filepath.Join(homeDir, ".config")
+	}
+
+	return filepath.Join(dir, appName)
+}
+
+// DefaultSocketPath returns the default agent socket path for a profile, inside the runtime directory.
+func DefaultSocketPath(profile string) string {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	return filepath.Join(RuntimeDir(), profile+".sock")
+}
@@ -0,0 +1,82 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/na4ma4/go-permbits"
+)
+
+// ErrInsecurePath indicates that a path could be modified or replaced by another user.
+var ErrInsecurePath = errors.New("insecure path")
+
+// EnsureDir creates dir as a private directory if it does not exist, then verifies that it is
+// safe to hold sockets and runtime files.
+func EnsureDir(dir string) error {
+	if _, err := os.Lstat(dir); errors.Is(err, fs.ErrNotExist) {
+		if err = os.MkdirAll(dir, permbits.MustString("u=rwx,a=")); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	return CheckDir(dir)
+}
+
+// CheckDir verifies that dir is a real directory (not a symlink) that only the current user can
+// modify, directories owned by root are accepted when the sticky bit is set (eg. /tmp).
+func CheckDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat directory %s: %w", dir, err)
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("%w: %s is a symlink", ErrInsecurePath, dir)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrInsecurePath, dir)
+	}
+
+	uid, ok := OwnerUID(info)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case uid == os.Getuid() && info.Mode().Perm()&0o022 == 0:
+		return nil
+	case uid == 0 && info.Mode()&fs.ModeSticky != 0:
+		return nil
+	case uid == os.Getuid():
+		return fmt.Errorf("%w: %s is writable by other users (%s)", ErrInsecurePath, dir, info.Mode().Perm())
+	default:
+		return fmt.Errorf("%w: %s is owned by uid %d", ErrInsecurePath, dir, uid)
+	}
+}
+
+// CheckOwner verifies that info describes a file owned by the current user that is not a symlink.
+func CheckOwner(path string, info fs.FileInfo) error {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("%w: %s is a symlink", ErrInsecurePath, path)
+	}
+
+	if uid, ok := OwnerUID(info); ok && uid != os.Getuid() {
+		return fmt.Errorf("%w: %s is owned by uid %d", ErrInsecurePath, path, uid)
+	}
+
+	return nil
+}
+
+// OwnerUID returns the owning user id of a file, if the platform provides it.
+func OwnerUID(info fs.FileInfo) (int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return int(stat.Uid), true
+}
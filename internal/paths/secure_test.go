@@ -0,0 +1,50 @@
+package paths_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
+)
+
+func TestEnsureDirCreatesPrivateDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "runtime", "ssh-agent-mux")
+
+	if err := paths.EnsureDir(dir); err != nil {
+		t.Fatalf("Failed to ensure directory: %v", err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("Failed to stat directory: %v", err)
+	}
+
+	if info.Mode().Perm() != 0o700 {
+		t.Errorf("Expected directory mode 0700, got %s", info.Mode().Perm())
+	}
+}
+
+func TestCheckDirRejectsInsecurePaths(t *testing.T) {
+	base := t.TempDir()
+
+	writable := filepath.Join(base, "writable")
+	if err := os.Mkdir(writable, 0o700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Chmod(writable, 0o777); err != nil {
+		t.Fatalf("Failed to chmod directory: %v", err)
+	}
+
+	link := filepath.Join(base, "link")
+	if err := os.Symlink(writable, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	for _, dir := range []string{writable, link} {
+		if err := paths.CheckDir(dir); !errors.Is(err, paths.ErrInsecurePath) {
+			t.Errorf("Expected ErrInsecurePath for %s, got %v", dir, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package pkcs11provider loads keys held on smartcards and hardware tokens through PKCS#11 modules.
+package pkcs11provider
+
+import (
+	"crypto"
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// ErrUnsupported indicates that the binary was built without PKCS#11 support (cgo is required).
+	ErrUnsupported = errors.New("PKCS#11 support is not available in this build")
+	// ErrNoKeys indicates that a module has no tokens with signing keys.
+	ErrNoKeys = errors.New("no signing keys found on PKCS#11 tokens")
+	// ErrUnsupportedKey indicates that a token key is not an RSA or ECDSA key.
+	ErrUnsupportedKey = errors.New("unsupported PKCS#11 key type")
+)
+
+// Key is a private key held on a token, it never leaves the token.
+type Key struct {
+	PublicKey ssh.PublicKey
+	// Comment is the key label, or the module path when the key has no label.
+	Comment string
+
+	signer crypto.Signer
+}
+
+// Signer returns an SSH signer that signs on the token.
+func (k *Key) Signer() (ssh.Signer, error) {
+	return ssh.NewSignerFromSigner(k.signer)
+}
+
+// Module is a loaded PKCS#11 module and the signing keys found on its tokens.
+type Module struct {
+	Path string
+	Keys []*Key
+
+	close func() error
+}
+
+// Close logs out of the tokens and unloads the module, its keys can no longer sign.
+func (m *Module) Close() error {
+	if m.close == nil {
+		return nil
+	}
+
+	return m.close()
+}
@@ -0,0 +1,355 @@
+//go:build cgo
+
+package pkcs11provider
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxObjects limits the number of keys read from a single token.
+const maxObjects = 64
+
+// rsaDigestInfo holds the DER DigestInfo prefixes for the hashes used by SSH RSA signatures,
+// CKM_RSA_PKCS only pads the data so the prefix has to be added before signing.
+//
+//nolint:gochecknoglobals // lookup table
+var rsaDigestInfo = map[crypto.Hash][]byte{
+	crypto.SHA1: {
+		0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14,
+	},
+	crypto.SHA256: {
+		0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20,
+	},
+	crypto.SHA384: {
+		0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30,
+	},
+	crypto.SHA512: {
+		0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40,
+	},
+}
+
+// ecCurves maps the named curve OIDs in CKA_EC_PARAMS to curves.
+//
+//nolint:gochecknoglobals // lookup table
+var ecCurves = map[string]elliptic.Curve{
+	asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}.String(): elliptic.P256(),
+	asn1.ObjectIdentifier{1, 3, 132, 0, 34}.String():          elliptic.P384(),
+	asn1.ObjectIdentifier{1, 3, 132, 0, 35}.String():          elliptic.P521(),
+}
+
+// token is a loaded module, PKCS#11 sessions are not safe for concurrent use so every call is
+// made while holding the lock.
+type token struct {
+	mu       sync.Mutex
+	ctx      *pkcs11.Ctx
+	sessions []pkcs11.SessionHandle
+	closed   bool
+}
+
+// Open loads a PKCS#11 module, logs in to every token present with the PIN (if any) and finds
+// the signing keys on them.
+func Open(path, pin string) (*Module, error) {
+	ctx := pkcs11.New(path)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", path)
+	}
+
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("failed to initialise PKCS#11 module %s: %w", path, err)
+	}
+
+	t := &token{ctx: ctx}
+	mod := &Module{Path: path, close: t.close}
+
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("failed to list PKCS#11 slots: %w", err)
+	}
+
+	for _, slot := range slots {
+		keys, slotErr := t.openSlot(slot, pin, path)
+		if slotErr != nil {
+			_ = t.close()
+			return nil, slotErr
+		}
+
+		mod.Keys = append(mod.Keys, keys...)
+	}
+
+	if len(mod.Keys) == 0 {
+		_ = t.close()
+		return nil, ErrNoKeys
+	}
+
+	return mod, nil
+}
+
+// openSlot opens a session on the token in slot and returns its signing keys.
+func (t *token) openSlot(slot uint, pin, path string) ([]*Key, error) {
+	session, err := t.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session on slot %d: %w", slot, err)
+	}
+	t.sessions = append(t.sessions, session)
+
+	if pin != "" {
+		if err = t.ctx.Login(session, pkcs11.CKU_USER, pin); err != nil &&
+			!errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+			return nil, fmt.Errorf("failed to log in to token in slot %d: %w", slot, err)
+		}
+	}
+
+	privateKeys, err := t.findObjects(session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(privateKeys))
+	for _, handle := range privateKeys {
+		key, keyErr := t.loadKey(session, handle, path)
+		if errors.Is(keyErr, ErrUnsupportedKey) {
+			continue
+		}
+		if keyErr != nil {
+			return nil, keyErr
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// loadKey reads the public half of the private key object handle.
+func (t *token) loadKey(session pkcs11.SessionHandle, handle pkcs11.ObjectHandle, path string) (*Key, error) {
+	attrs, err := t.ctx.GetAttributeValue(session, handle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_ID, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key attributes: %w", err)
+	}
+
+	id, label, keyType := attrs[0].Value, string(attrs[1].Value), attrUint(attrs[2].Value)
+
+	// The public key attributes are read from the matching public key object, some tokens
+	// also expose them on the private key object.
+	pubHandle := handle
+	if len(id) > 0 {
+		if pubKeys, findErr := t.findObjects(session, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+		}); findErr == nil && len(pubKeys) > 0 {
+			pubHandle = pubKeys[0]
+		}
+	}
+
+	var pub crypto.PublicKey
+	switch keyType {
+	case pkcs11.CKK_RSA:
+		pub, err = t.rsaPublicKey(session, pubHandle)
+	case pkcs11.CKK_EC:
+		pub, err = t.ecPublicKey(session, pubHandle)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedKey, keyType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedKey, err)
+	}
+
+	if label == "" {
+		label = path
+	}
+
+	return &Key{
+		PublicKey: sshPub,
+		Comment:   label,
+		signer:    &tokenSigner{token: t, session: session, handle: handle, pub: pub},
+	}, nil
+}
+
+func (t *token) rsaPublicKey(session pkcs11.SessionHandle, handle pkcs11.ObjectHandle) (*rsa.PublicKey, error) {
+	attrs, err := t.ctx.GetAttributeValue(session, handle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RSA public key: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(attrs[1].Value)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%w: RSA exponent out of range", ErrUnsupportedKey)
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(attrs[0].Value), E: int(exponent.Int64())}, nil
+}
+
+func (t *token) ecPublicKey(session pkcs11.SessionHandle, handle pkcs11.ObjectHandle) (*ecdsa.PublicKey, error) {
+	attrs, err := t.ctx.GetAttributeValue(session, handle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read EC public key: %w", err)
+	}
+
+	var oid asn1.ObjectIdentifier
+	if _, err = asn1.Unmarshal(attrs[0].Value, &oid); err != nil {
+		return nil, fmt.Errorf("%w: only named curves are supported", ErrUnsupportedKey)
+	}
+
+	curve, ok := ecCurves[oid.String()]
+	if !ok {
+		return nil, fmt.Errorf("%w: curve %s", ErrUnsupportedKey, oid)
+	}
+
+	// CKA_EC_POINT is a DER octet string, some older tokens return the raw point instead.
+	point := attrs[1].Value
+	var wrapped []byte
+	if rest, unwrapErr := asn1.Unmarshal(point, &wrapped); unwrapErr == nil && len(rest) == 0 {
+		point = wrapped
+	}
+
+	x, y := elliptic.Unmarshal(curve, point) //nolint:staticcheck // the point comes from the token, not ECDH
+	if x == nil {
+		return nil, fmt.Errorf("%w: invalid EC point", ErrUnsupportedKey)
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func (t *token) findObjects(session pkcs11.SessionHandle, template []*pkcs11.Attribute) ([]pkcs11.ObjectHandle, error) {
+	if err := t.ctx.FindObjectsInit(session, template); err != nil {
+		return nil, fmt.Errorf("failed to search token: %w", err)
+	}
+
+	handles, _, err := t.ctx.FindObjects(session, maxObjects)
+	if finalErr := t.ctx.FindObjectsFinal(session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search token: %w", err)
+	}
+
+	return handles, nil
+}
+
+// sign signs data with the private key object handle using mechanism.
+func (t *token) sign(
+	session pkcs11.SessionHandle, handle pkcs11.ObjectHandle, mechanism uint, data []byte,
+) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, errors.New("PKCS#11 module has been unloaded")
+	}
+
+	if err := t.ctx.SignInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, handle); err != nil {
+		return nil, fmt.Errorf("failed to start token signature: %w", err)
+	}
+
+	sig, err := t.ctx.Sign(session, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign on token: %w", err)
+	}
+
+	return sig, nil
+}
+
+func (t *token) close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+
+	for _, session := range t.sessions {
+		_ = t.ctx.Logout(session)
+		_ = t.ctx.CloseSession(session)
+	}
+
+	err := t.ctx.Finalize()
+	t.ctx.Destroy()
+
+	return err
+}
+
+// tokenSigner is a crypto.Signer for a private key on a token.
+type tokenSigner struct {
+	token   *token
+	session pkcs11.SessionHandle
+	handle  pkcs11.ObjectHandle
+	pub     crypto.PublicKey
+}
+
+// Public returns the public key.
+func (s *tokenSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// Sign signs digest on the token, returning a PKCS #1 v1.5 or ASN.1 ECDSA signature.
+func (s *tokenSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	switch s.pub.(type) {
+	case *rsa.PublicKey:
+		prefix, ok := rsaDigestInfo[opts.HashFunc()]
+		if !ok {
+			return nil, fmt.Errorf("%w: hash %s", ErrUnsupportedKey, opts.HashFunc())
+		}
+
+		return s.token.sign(s.session, s.handle, pkcs11.CKM_RSA_PKCS, append(append([]byte{}, prefix...), digest...))
+	case *ecdsa.PublicKey:
+		sig, err := s.token.sign(s.session, s.handle, pkcs11.CKM_ECDSA, digest)
+		if err != nil {
+			return nil, err
+		}
+
+		// CKM_ECDSA returns r and s concatenated, crypto.Signer returns them ASN.1 encoded.
+		half := len(sig) / 2
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			R: new(big.Int).SetBytes(sig[:half]),
+			S: new(big.Int).SetBytes(sig[half:]),
+		})
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// attrUint decodes a CK_ULONG attribute value, which is in native byte order.
+func attrUint(value []byte) uint {
+	switch len(value) {
+	case 4: //nolint:mnd // 32-bit CK_ULONG
+		return uint(binary.NativeEndian.Uint32(value))
+	case 8: //nolint:mnd // 64-bit CK_ULONG
+		return uint(binary.NativeEndian.Uint64(value))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package pkcs11provider
+
+// Open is unavailable without cgo, it always returns ErrUnsupported.
+func Open(_, _ string) (*Module, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,206 @@
+//go:build cgo
+
+package pkcs11provider_test
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+	"github.com/na4ma4/ssh-agent-mux/internal/pkcs11provider"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	testUserPIN = "1234"
+	testSOPIN   = "5678"
+)
+
+// softHSMModule returns the SoftHSM module path, set SOFTHSM2_MODULE when it is installed elsewhere.
+func softHSMModule(t *testing.T) string {
+	t.Helper()
+
+	candidates := []string{
+		os.Getenv("SOFTHSM2_MODULE"),
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/lib/aarch64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+		"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+	}
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	t.Skip("SoftHSM is not installed")
+	return ""
+}
+
+// newSoftHSMToken initialises a SoftHSM token in a temporary directory with an ECDSA and an RSA key.
+func newSoftHSMToken(t *testing.T) string {
+	t.Helper()
+
+	module := softHSMModule(t)
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "tokens"), 0o700); err != nil {
+		t.Fatalf("Failed to create token directory: %v", err)
+	}
+
+	confPath := filepath.Join(dir, "softhsm2.conf")
+	conf := "directories.tokendir = " + filepath.Join(dir, "tokens") + "\nobjectstore.backend = file\n"
+	if err := os.WriteFile(confPath, []byte(conf), 0o600); err != nil {
+		t.Fatalf("Failed to write SoftHSM config: %v", err)
+	}
+	t.Setenv("SOFTHSM2_CONF", confPath)
+
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		t.Fatalf("Failed to load SoftHSM module %s", module)
+	}
+	defer ctx.Destroy()
+
+	if err := ctx.Initialize(); err != nil {
+		t.Fatalf("Failed to initialise SoftHSM: %v", err)
+	}
+	defer func() { _ = ctx.Finalize() }()
+
+	slots, err := ctx.GetSlotList(false)
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("Failed to list SoftHSM slots: %v", err)
+	}
+
+	if err = ctx.InitToken(slots[0], testSOPIN, "test"); err != nil {
+		t.Fatalf("Failed to initialise token: %v", err)
+	}
+
+	// SoftHSM moves an initialised token to a new slot.
+	if slots, err = ctx.GetSlotList(true); err != nil || len(slots) == 0 {
+		t.Fatalf("Failed to list initialised token: %v", err)
+	}
+
+	session, err := ctx.OpenSession(slots[0], pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		t.Fatalf("Failed to open session: %v", err)
+	}
+	defer func() { _ = ctx.CloseSession(session) }()
+
+	if err = ctx.Login(session, pkcs11.CKU_SO, testSOPIN); err != nil {
+		t.Fatalf("Failed to log in as SO: %v", err)
+	}
+	if err = ctx.InitPIN(session, testUserPIN); err != nil {
+		t.Fatalf("Failed to set user PIN: %v", err)
+	}
+	_ = ctx.Logout(session)
+
+	if err = ctx.Login(session, pkcs11.CKU_USER, testUserPIN); err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	defer func() { _ = ctx.Logout(session) }()
+
+	generate := func(id, label string, mechanism uint, public []*pkcs11.Attribute) {
+		public = append(public,
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(id)),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		)
+		private := []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(id)),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		}
+
+		if _, _, genErr := ctx.GenerateKeyPair(
+			session, []*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, public, private,
+		); genErr != nil {
+			t.Fatalf("Failed to generate %s key: %v", label, genErr)
+		}
+	}
+
+	// DER encoding of the P-256 curve OID.
+	p256 := []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}
+	generate("1", "ecdsa-key", pkcs11.CKM_EC_KEY_PAIR_GEN, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, p256),
+	})
+	generate("2", "rsa-key", pkcs11.CKM_RSA_PKCS_KEY_PAIR_GEN, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS_BITS, 2048),
+		pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, []byte{0x01, 0x00, 0x01}),
+	})
+
+	return module
+}
+
+func TestOpenSignsOnToken(t *testing.T) {
+	module := newSoftHSMToken(t)
+
+	mod, err := pkcs11provider.Open(module, testUserPIN)
+	if err != nil {
+		t.Fatalf("Failed to open module: %v", err)
+	}
+
+	if len(mod.Keys) != 2 {
+		t.Fatalf("Expected two keys on the token, got %d", len(mod.Keys))
+	}
+
+	data := []byte("data to sign")
+	for _, key := range mod.Keys {
+		signer, signerErr := key.Signer()
+		if signerErr != nil {
+			t.Fatalf("Failed to create signer for %s: %v", key.Comment, signerErr)
+		}
+
+		algorithm := key.PublicKey.Type()
+		if algorithm == ssh.KeyAlgoRSA {
+			algorithm = ssh.KeyAlgoRSASHA256
+		}
+
+		algoSigner, ok := signer.(ssh.AlgorithmSigner)
+		if !ok {
+			t.Fatalf("Expected an algorithm signer for %s", key.Comment)
+		}
+
+		sig, signErr := algoSigner.SignWithAlgorithm(rand.Reader, data, algorithm)
+		if signErr != nil {
+			t.Fatalf("Failed to sign with %s: %v", key.Comment, signErr)
+		}
+
+		if err = key.PublicKey.Verify(data, sig); err != nil {
+			t.Errorf("Signature from %s did not verify: %v", key.Comment, err)
+		}
+	}
+
+	signer, err := mod.Keys[0].Signer()
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	if err = mod.Close(); err != nil {
+		t.Fatalf("Failed to close module: %v", err)
+	}
+
+	if _, err = signer.Sign(rand.Reader, data); err == nil {
+		t.Error("Expected signing to fail once the module is unloaded")
+	}
+}
+
+func TestOpenRejectsMissingModule(t *testing.T) {
+	if _, err := pkcs11provider.Open(filepath.Join(t.TempDir(), "missing.so"), ""); err == nil {
+		t.Error("Expected an error for a missing module")
+	}
+
+	if _, err := pkcs11provider.Open("/dev/null", ""); err == nil || errors.Is(err, pkcs11provider.ErrNoKeys) {
+		t.Errorf("Expected a load error for a non-module file, got %v", err)
+	}
+}
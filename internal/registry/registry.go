@@ -0,0 +1,136 @@
+// Package registry records running ssh-agent-mux instances in the per-user runtime directory.
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/na4ma4/go-permbits"
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const entryExtension = ".json"
+
+// Registry is a directory of instance entries, one file per profile.
+type Registry struct {
+	dir string
+}
+
+// New creates a registry stored in dir.
+func New(dir string) *Registry {
+	return &Registry{dir: dir}
+}
+
+// Default returns the registry in the per-user runtime directory.
+func Default() *Registry {
+	return New(filepath.Join(paths.RuntimeDir(), "instances"))
+}
+
+// Dir returns the directory holding the registry entries.
+func (r *Registry) Dir() string {
+	return r.dir
+}
+
+// Register records a running instance and returns a function that removes the entry.
+func (r *Registry) Register(cfg *api.Config) (func(), error) {
+	profile := cfg.GetProfile()
+	if err := paths.ValidateProfile(profile); err != nil {
+		return nil, err
+	}
+
+	if err := paths.EnsureDir(r.dir); err != nil {
+		return nil, fmt.Errorf("failed to create registry directory: %w", err)
+	}
+
+	data, err := protojson.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal registry entry: %w", err)
+	}
+
+	entryPath := r.entryPath(profile)
+	tmpPath := entryPath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, permbits.MustString("u=rw,a=")); err != nil {
+		return nil, fmt.Errorf("failed to write registry entry: %w", err)
+	}
+
+	if err = os.Rename(tmpPath, entryPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to write registry entry: %w", err)
+	}
+
+	return func() { _ = r.removeIfOwned(profile, cfg.GetPid()) }, nil
+}
+
+// List returns the registered instances sorted by profile name.
+func (r *Registry) List() ([]*api.Config, error) {
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read registry directory: %w", err)
+	}
+
+	instances := make([]*api.Config, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), entryExtension) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(r.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		cfg := &api.Config{}
+		if err = protojson.Unmarshal(data, cfg); err != nil {
+			continue
+		}
+
+		instances = append(instances, cfg)
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].GetProfile() < instances[j].GetProfile()
+	})
+
+	return instances, nil
+}
+
+// Remove deletes the entry for a profile.
+func (r *Registry) Remove(profile string) error {
+	if err := paths.ValidateProfile(profile); err != nil {
+		return err
+	}
+
+	if err := os.Remove(r.entryPath(profile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove registry entry: %w", err)
+	}
+
+	return nil
+}
+
+// removeIfOwned deletes the entry for a profile only if it still belongs to pid,
+// so a replacement instance is not deregistered by its predecessor.
+func (r *Registry) removeIfOwned(profile string, pid int64) error {
+	data, err := os.ReadFile(r.entryPath(profile))
+	if err != nil {
+		return err
+	}
+
+	cfg := &api.Config{}
+	if err = protojson.Unmarshal(data, cfg); err != nil || cfg.GetPid() != pid {
+		return err
+	}
+
+	return r.Remove(profile)
+}
+
+func (r *Registry) entryPath(profile string) string {
+	return filepath.Join(r.dir, profile+entryExtension)
+}
@@ -0,0 +1,58 @@
+package registry_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/api"
+	"github.com/na4ma4/ssh-agent-mux/internal/paths"
+	"github.com/na4ma4/ssh-agent-mux/internal/registry"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRegisterAndList(t *testing.T) {
+	reg := registry.New(t.TempDir())
+
+	var deregister []func()
+	for i, profile := range []string{"work", "default"} {
+		remove, err := reg.Register(api.Config_builder{
+			Profile:    proto.String(profile),
+			SocketPath: proto.String("/tmp/" + profile + ".sock"),
+			Pid:        proto.Int64(int64(100 + i)),
+		}.Build())
+		if err != nil {
+			t.Fatalf("Failed to register %s: %v", profile, err)
+		}
+		deregister = append(deregister, remove)
+	}
+
+	instances, err := reg.List()
+	if err != nil {
+		t.Fatalf("Failed to list instances: %v", err)
+	}
+
+	if len(instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(instances))
+	}
+
+	if instances[0].GetProfile() != "default" || instances[1].GetSocketPath() != "/tmp/work.sock" {
+		t.Errorf("Unexpected instances: %v", instances)
+	}
+
+	for _, remove := range deregister {
+		remove()
+	}
+
+	if instances, err = reg.List(); err != nil || len(instances) != 0 {
+		t.Errorf("Expected no instances after deregistering, got %d (%v)", len(instances), err)
+	}
+}
+
+func TestRegisterRejectsInvalidProfile(t *testing.T) {
+	reg := registry.New(t.TempDir())
+
+	_, err := reg.Register(api.Config_builder{Profile: proto.String("../escape")}.Build())
+	if !errors.Is(err, paths.ErrInvalidProfile) {
+		t.Errorf("Expected ErrInvalidProfile, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package securitykey
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultFido2Assert  = "fido2-assert"
+	defaultFido2Token   = "fido2-token"
+	defaultFido2Timeout = 30 * time.Second
+
+	// authDataSize is the size of the authenticator data without extensions: the relying party
+	// ID hash, flags and signature counter.
+	authDataSize = 37
+)
+
+// ErrNoDevice indicates that no FIDO device was found.
+var ErrNoDevice = errors.New("no FIDO device found")
+
+// Fido2Config configures the libfido2 command line tools authenticator.
+type Fido2Config struct {
+	// Device is the FIDO device, eg. /dev/hidraw0, the first device listed by fido2-token -L
+	// is used by default.
+	Device string `mapstructure:"device"`
+	// Assert and Token are the fido2-assert and fido2-token programs.
+	Assert string `mapstructure:"fido2-assert"`
+	Token  string `mapstructure:"fido2-token"`
+	// Timeout limits each signature including waiting for the user to touch the key, it
+	// defaults to 30s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// Fido2Authenticator signs with security keys through libfido2's fido2-assert tool.
+type Fido2Authenticator struct {
+	config Fido2Config
+}
+
+// NewFido2Authenticator creates an authenticator using the libfido2 command line tools.
+func NewFido2Authenticator(config Fido2Config) *Fido2Authenticator {
+	if config.Assert == "" {
+		config.Assert = defaultFido2Assert
+	}
+
+	if config.Token == "" {
+		config.Token = defaultFido2Token
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultFido2Timeout
+	}
+
+	return &Fido2Authenticator{config: config}
+}
+
+// Sign gets an assertion from the security key with fido2-assert -G.
+func (a *Fido2Authenticator) Sign(ctx context.Context, req SignRequest) (*Assertion, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.config.Timeout)
+	defer cancel()
+
+	device, err := a.device(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"-G"}
+	if req.Flags&FlagUserPresenceRequired != 0 {
+		args = append(args, "-p")
+	}
+	if req.Flags&FlagUserVerificationRequired != 0 {
+		args = append(args, "-v")
+	}
+	args = append(args, device)
+	if req.Algorithm == ssh.KeyAlgoSKED25519 {
+		args = append(args, "eddsa")
+	}
+
+	input := strings.Join([]string{
+		base64.StdEncoding.EncodeToString(req.DataHash),
+		req.Application,
+		base64.StdEncoding.EncodeToString(req.KeyHandle),
+	}, "\n") + "\n"
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, a.config.Assert, args...) //nolint:gosec // configured program
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", a.config.Assert, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseFido2Assertion(stdout.Bytes())
+}
+
+// device returns the configured device or the first device found by fido2-token -L.
+func (a *Fido2Authenticator) device(ctx context.Context) (string, error) {
+	if a.config.Device != "" {
+		return a.config.Device, nil
+	}
+
+	out, err := exec.CommandContext(ctx, a.config.Token, "-L").Output() //nolint:gosec // configured program
+	if err != nil {
+		return "", fmt.Errorf("%s -L failed: %w", a.config.Token, err)
+	}
+
+	// Each line is "<device>: vendor=..., product=... (<name>)".
+	if device, _, ok := strings.Cut(strings.TrimSpace(string(out)), ": "); ok && device != "" {
+		return device, nil
+	}
+
+	return "", ErrNoDevice
+}
+
+// parseFido2Assertion parses the output of fido2-assert -G: the client data hash, relying party
+// ID, CBOR encoded authenticator data and signature, one per line.
+func parseFido2Assertion(out []byte) (*Assertion, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+
+	if len(lines) < 4 { //nolint:mnd // hash, relying party, authenticator data and signature
+		return nil, fmt.Errorf("unexpected fido2-assert output: %d lines", len(lines))
+	}
+
+	authData, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid authenticator data: %w", err)
+	}
+
+	if authData, err = cborBytes(authData); err != nil {
+		return nil, err
+	}
+
+	if len(authData) < authDataSize {
+		return nil, fmt.Errorf("authenticator data too short: %d bytes", len(authData))
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid assertion signature: %w", err)
+	}
+
+	return &Assertion{
+		Flags:     authData[32],
+		Counter:   binary.BigEndian.Uint32(authData[33:37]),
+		Signature: sig,
+	}, nil
+}
+
+// cborBytes decodes a CBOR byte string.
+func cborBytes(data []byte) ([]byte, error) {
+	const majorBytes = 0x40
+
+	if len(data) == 0 || data[0]&0xe0 != majorBytes {
+		return nil, errors.New("authenticator data is not a CBOR byte string")
+	}
+
+	size, rest := uint64(data[0]&0x1f), data[1:]
+	switch {
+	case size < 24: //nolint:mnd // length in the initial byte
+	case size == 24 && len(rest) >= 1: //nolint:mnd // 1 byte length
+		size, rest = uint64(rest[0]), rest[1:]
+	case size == 25 && len(rest) >= 2: //nolint:mnd // 2 byte length
+		size, rest = uint64(binary.BigEndian.Uint16(rest)), rest[2:]
+	default:
+		return nil, errors.New("unsupported CBOR byte string length")
+	}
+
+	if uint64(len(rest)) != size {
+		return nil, errors.New("truncated CBOR byte string")
+	}
+
+	return rest, nil
+}
@@ -0,0 +1,79 @@
+package securitykey_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/securitykey"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestFido2AuthenticatorParsesAssertion(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte("data to sign")
+	dataHash := sha256.Sum256(data)
+
+	soft := securitykey.NewSoftAuthenticator()
+	key, err := soft.Generate(ssh.KeyAlgoSKED25519, "ssh:", securitykey.FlagUserPresenceRequired)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	assertion, err := soft.Sign(t.Context(), securitykey.SignRequest{
+		Application: key.Application,
+		KeyHandle:   key.KeyHandle,
+		DataHash:    dataHash[:],
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	// fido2-assert prints the authenticator data as a CBOR byte string.
+	appHash := sha256.Sum256([]byte(key.Application))
+	authData := append([]byte{0x58, 37}, appHash[:]...)
+	authData = append(authData, assertion.Flags)
+	authData = binary.BigEndian.AppendUint32(authData, assertion.Counter)
+
+	output := strings.Join([]string{
+		base64.StdEncoding.EncodeToString(dataHash[:]),
+		key.Application,
+		base64.StdEncoding.EncodeToString(authData),
+		base64.StdEncoding.EncodeToString(assertion.Signature),
+	}, "\n") + "\n"
+	if err = os.WriteFile(filepath.Join(dir, "output"), []byte(output), 0o600); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+
+	script := filepath.Join(dir, "fido2-assert")
+	if err = os.WriteFile(script, []byte(
+		"#!/bin/sh\necho \"$@\" > \""+dir+"/args\"\ncat > \""+dir+"/input\"\ncat \""+dir+"/output\"\n",
+	), 0o700); err != nil { //nolint:gosec // test script must be executable
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	auth := securitykey.NewFido2Authenticator(securitykey.Fido2Config{Device: "/dev/hidraw9", Assert: script})
+
+	sig, err := securitykey.Sign(t.Context(), auth, key, data)
+	if err != nil {
+		t.Fatalf("Failed to sign through fido2-assert: %v", err)
+	}
+
+	if err = key.PublicKey.Verify(data, sig); err != nil {
+		t.Errorf("Signature did not verify: %v", err)
+	}
+
+	if args, _ := os.ReadFile(filepath.Join(dir, "args")); string(args) != "-G -p /dev/hidraw9 eddsa\n" {
+		t.Errorf("Unexpected fido2-assert arguments %q", args)
+	}
+
+	input, _ := os.ReadFile(filepath.Join(dir, "input"))
+	if lines := strings.Split(string(input), "\n"); len(lines) < 3 ||
+		lines[2] != base64.StdEncoding.EncodeToString(key.KeyHandle) {
+		t.Errorf("Expected key handle as the credential ID, got %q", input)
+	}
+}
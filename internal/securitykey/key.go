@@ -0,0 +1,144 @@
+// Package securitykey signs with FIDO security key backed SSH keys (sk-ssh-ed25519@openssh.com and
+// sk-ecdsa-sha2-nistp256@openssh.com) through a pluggable authenticator.
+package securitykey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key flags stored with security keys, from OpenSSH's sk-api.h.
+const (
+	FlagUserPresenceRequired     byte = 0x01
+	FlagUserVerificationRequired byte = 0x04
+	FlagResidentKey              byte = 0x20
+)
+
+var (
+	// ErrNoAuthenticator indicates that no authenticator is configured to sign with security keys.
+	ErrNoAuthenticator = errors.New("no security key authenticator configured")
+	// ErrUnsupportedKey indicates that a key is not a supported security key type.
+	ErrUnsupportedKey = errors.New("unsupported security key type")
+	// ErrUnknownKeyHandle indicates that an authenticator does not hold the requested key.
+	ErrUnknownKeyHandle = errors.New("unknown security key handle")
+)
+
+// PrivateKey is the agent's half of a security key: the key handle that the authenticator
+// needs to sign, the private key itself never leaves the security key.
+type PrivateKey struct {
+	PublicKey   ssh.PublicKey
+	Application string
+	Flags       byte
+	KeyHandle   []byte
+}
+
+// SSHPublicKey returns the public key, security keys have no crypto.PublicKey form.
+func (k *PrivateKey) SSHPublicKey() ssh.PublicKey {
+	return k.PublicKey
+}
+
+// Clone returns a copy of the key that is not affected by Wipe.
+func (k *PrivateKey) Clone() *PrivateKey {
+	clone := *k
+	clone.KeyHandle = append([]byte(nil), k.KeyHandle...)
+
+	return &clone
+}
+
+// Wipe overwrites the key handle.
+func (k *PrivateKey) Wipe() {
+	clear(k.KeyHandle)
+}
+
+// SignRequest asks an authenticator for an assertion, like libfido2's fido_dev_get_assert with
+// the SHA-256 of the signed data as the client data hash.
+type SignRequest struct {
+	// Algorithm is the SSH public key type of the key.
+	Algorithm   string
+	Application string
+	KeyHandle   []byte
+	// Flags are the key flags, they say whether user presence or verification is required.
+	Flags    byte
+	DataHash []byte
+}
+
+// Assertion is the result of a signature by a security key.
+type Assertion struct {
+	// Flags are the authenticator data flags, eg. whether the user was present.
+	Flags   byte
+	Counter uint32
+	// Signature is the raw Ed25519 signature or the ASN.1 encoded ECDSA signature over the
+	// authenticator data and the data hash.
+	Signature []byte
+}
+
+// Authenticator signs with security keys.
+type Authenticator interface {
+	Sign(ctx context.Context, req SignRequest) (*Assertion, error)
+}
+
+// Sign signs data with a security key through an authenticator, the signature is in the
+// format used by OpenSSH for the key type.
+func Sign(ctx context.Context, auth Authenticator, key *PrivateKey, data []byte) (*ssh.Signature, error) {
+	if auth == nil {
+		return nil, ErrNoAuthenticator
+	}
+
+	dataHash := sha256.Sum256(data)
+	assertion, err := auth.Sign(ctx, SignRequest{
+		Algorithm:   key.PublicKey.Type(),
+		Application: key.Application,
+		KeyHandle:   key.KeyHandle,
+		Flags:       key.Flags,
+		DataHash:    dataHash[:],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("security key signature failed: %w", err)
+	}
+
+	sig := &ssh.Signature{
+		Format: key.PublicKey.Type(),
+		Rest: ssh.Marshal(struct {
+			Flags   byte
+			Counter uint32
+		}{assertion.Flags, assertion.Counter}),
+	}
+
+	switch key.PublicKey.Type() {
+	case ssh.KeyAlgoSKED25519:
+		sig.Blob = assertion.Signature
+	case ssh.KeyAlgoSKECDSA256:
+		var ecSig struct{ R, S *big.Int }
+		if _, err = asn1.Unmarshal(assertion.Signature, &ecSig); err != nil {
+			return nil, fmt.Errorf("invalid security key signature: %w", err)
+		}
+		sig.Blob = ssh.Marshal(ecSig)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, key.PublicKey.Type())
+	}
+
+	if err = key.PublicKey.Verify(data, sig); err != nil {
+		return nil, fmt.Errorf("security key returned an invalid signature: %w", err)
+	}
+
+	return sig, nil
+}
+
+// signedMessage returns the message signed by a security key: the authenticator data followed
+// by the client data hash.
+func signedMessage(application string, flags byte, counter uint32, dataHash []byte) []byte {
+	appHash := sha256.Sum256([]byte(application))
+
+	return ssh.Marshal(struct {
+		ApplicationHash []byte `ssh:"rest"`
+		Flags           byte
+		Counter         uint32
+		DataHash        []byte `ssh:"rest"`
+	}{appHash[:], flags, counter, dataHash})
+}
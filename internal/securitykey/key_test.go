@@ -0,0 +1,77 @@
+package securitykey_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/na4ma4/ssh-agent-mux/internal/securitykey"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSignWithSoftAuthenticator(t *testing.T) {
+	auth := securitykey.NewSoftAuthenticator()
+	data := []byte("data to sign")
+
+	for _, algorithm := range []string{ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256} {
+		key, err := auth.Generate(algorithm, "ssh:", securitykey.FlagUserPresenceRequired)
+		if err != nil {
+			t.Fatalf("Failed to generate %s key: %v", algorithm, err)
+		}
+
+		if key.PublicKey.Type() != algorithm {
+			t.Errorf("Expected %s public key, got %s", algorithm, key.PublicKey.Type())
+		}
+
+		sig, err := securitykey.Sign(t.Context(), auth, key, data)
+		if err != nil {
+			t.Fatalf("Failed to sign with %s key: %v", algorithm, err)
+		}
+
+		if err = key.PublicKey.Verify(data, sig); err != nil {
+			t.Errorf("Signature from %s key did not verify: %v", algorithm, err)
+		}
+
+		// Every signature increments the authenticator counter.
+		next, err := securitykey.Sign(t.Context(), auth, key, data)
+		if err != nil {
+			t.Fatalf("Failed to sign with %s key: %v", algorithm, err)
+		}
+
+		if string(next.Rest) == string(sig.Rest) {
+			t.Errorf("Expected the signature counter to change for %s key", algorithm)
+		}
+	}
+}
+
+func TestSignRequiresAuthenticatorHoldingKey(t *testing.T) {
+	key, err := securitykey.NewSoftAuthenticator().Generate(ssh.KeyAlgoSKED25519, "ssh:", 0)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if _, err = securitykey.Sign(context.Background(), nil, key, []byte("data")); !errors.Is(
+		err, securitykey.ErrNoAuthenticator,
+	) {
+		t.Errorf("Expected ErrNoAuthenticator, got %v", err)
+	}
+
+	other := securitykey.NewSoftAuthenticator()
+	if _, err = securitykey.Sign(t.Context(), other, key, []byte("data")); !errors.Is(
+		err, securitykey.ErrUnknownKeyHandle,
+	) {
+		t.Errorf("Expected ErrUnknownKeyHandle, got %v", err)
+	}
+
+	clone := key.Clone()
+	key.Wipe()
+	for _, b := range key.KeyHandle {
+		if b != 0 {
+			t.Fatal("Expected key handle to be wiped")
+		}
+	}
+
+	if string(clone.KeyHandle) == string(key.KeyHandle) {
+		t.Error("Expected clone to keep its key handle after wipe")
+	}
+}
@@ -0,0 +1,116 @@
+package securitykey
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// keyHandleSize is the size of the random key handles issued by the software authenticator.
+	keyHandleSize = 32
+	// authDataUserPresent is the authenticator data flag set when the user touched the key.
+	authDataUserPresent byte = 0x01
+)
+
+// SoftAuthenticator is an authenticator that keeps its keys in memory, for tests.
+type SoftAuthenticator struct {
+	mu      sync.Mutex
+	keys    map[string]crypto.Signer
+	counter uint32
+}
+
+// NewSoftAuthenticator creates a software authenticator without keys.
+func NewSoftAuthenticator() *SoftAuthenticator {
+	return &SoftAuthenticator{keys: make(map[string]crypto.Signer)}
+}
+
+// Generate creates a key for application, algorithm is ssh.KeyAlgoSKED25519 or ssh.KeyAlgoSKECDSA256.
+func (a *SoftAuthenticator) Generate(algorithm, application string, flags byte) (*PrivateKey, error) {
+	var (
+		signer crypto.Signer
+		pubKey []byte
+		err    error
+	)
+
+	switch algorithm {
+	case ssh.KeyAlgoSKED25519:
+		var pub ed25519.PublicKey
+		pub, signer, err = ed25519.GenerateKey(rand.Reader)
+		pubKey = ssh.Marshal(struct {
+			Type        string
+			PublicKey   []byte
+			Application string
+		}{algorithm, pub, application})
+	case ssh.KeyAlgoSKECDSA256:
+		var ecKey *ecdsa.PrivateKey
+		ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err == nil {
+			signer = ecKey
+			point := elliptic.Marshal(elliptic.P256(), ecKey.X, ecKey.Y) //nolint:staticcheck // SSH wire format
+			pubKey = ssh.Marshal(struct {
+				Type        string
+				Curve       string
+				Point       []byte
+				Application string
+			}{algorithm, "nistp256", point, application})
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	sshPubKey, err := ssh.ParsePublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	keyHandle := make([]byte, keyHandleSize)
+	_, _ = rand.Read(keyHandle)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.keys[string(keyHandle)] = signer
+
+	return &PrivateKey{PublicKey: sshPubKey, Application: application, Flags: flags, KeyHandle: keyHandle}, nil
+}
+
+// Sign signs as a security key would, the user is always present.
+func (a *SoftAuthenticator) Sign(_ context.Context, req SignRequest) (*Assertion, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	signer, ok := a.keys[string(req.KeyHandle)]
+	if !ok {
+		return nil, ErrUnknownKeyHandle
+	}
+
+	a.counter++
+	assertion := &Assertion{Flags: authDataUserPresent, Counter: a.counter}
+	message := signedMessage(req.Application, assertion.Flags, assertion.Counter, req.DataHash)
+
+	var err error
+	switch key := signer.(type) {
+	case ed25519.PrivateKey:
+		assertion.Signature = ed25519.Sign(key, message)
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(message)
+		assertion.Signature, err = ecdsa.SignASN1(rand.Reader, key, digest[:])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return assertion, nil
+}